@@ -0,0 +1,349 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/events"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func SetupAccount(t *testing.T) {
+	SetupClassroom(t)
+	eventsDS = events.NewDataSource(events.BuildSQLLiteConfig(test.DB_NAME))
+
+	users := []auth.JWTUser{
+		{Username: "maria", FirstName: "Maria", Email: "maria@robolucha.com"},
+		{Username: "teacher", Roles: []string{"dashboard_user"}},
+		{Username: "other-teacher", Roles: []string{"dashboard_user"}},
+	}
+	provider, err := auth.NewDevProvider(users)
+	assert.Nil(t, err)
+	auth.EnableDevProvider(provider)
+
+	router = createRouter(test.API_KEY, "true", auth.SessionIsValid, auth.SessionIsValid)
+}
+
+// logs in and creates a luchador with code, score, media and page events for the user
+func setupAccountData(t *testing.T, username string) (string, *model.User, *model.GameComponent) {
+	token := devLoginToken(t, username)
+	w := test.PerformRequest(router, "GET", "/private/get-user", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	user := ds.FindUserByUsername(username)
+
+	luchador := ds.CreateLuchador(&model.GameComponent{
+		UserID:  user.ID,
+		Name:    "luchador-" + username,
+		Codes:   []model.Code{{Event: "onStart", Script: "move(10)"}},
+		Configs: []model.Config{{Key: "mask", Value: "red"}},
+	})
+	ds.DB.Create(&model.MatchScore{LuchadorID: luchador.ID, MatchID: 1, Score: 10})
+	ds.DB.Create(&model.Media{UserID: user.ID, URL: "http://media/avatar.png"})
+	eventsDS.CreateEvent(model.PageEvent{UserID: user.ID, RemoteAddr: "10.0.0.1", UserAgent: "browser", Page: "home"})
+
+	return token, user, luchador
+}
+
+func requestAccountDeletion(t *testing.T, token string, mode string) model.AccountDeletionRequest {
+	body, _ := json.Marshal(model.AccountDeletionRequestBody{Mode: mode})
+	w := test.PerformRequest(router, "POST", "/private/account/delete", string(body), token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result model.AccountDeletionRequest
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestAccountExport(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, luchador := setupAccountData(t, "maria")
+
+	w := test.PerformRequest(router, "GET", "/private/account/export", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var export model.AccountExport
+	json.Unmarshal(w.Body.Bytes(), &export)
+	assert.Equal(t, user.ID, export.User.ID)
+	assert.Equal(t, "maria@robolucha.com", export.User.Email)
+	assert.Equal(t, 1, len(export.Luchadors))
+	assert.Equal(t, luchador.Name, export.Luchadors[0].Name)
+	assert.Equal(t, 1, len(export.CodeHistory))
+	assert.Equal(t, 1, len(export.MatchScores))
+	assert.Equal(t, 1, len(export.Media))
+	assert.Equal(t, 1, len(export.Sessions))
+	assert.Equal(t, 1, len(export.PageEvents))
+	assert.Equal(t, "10.0.0.1", export.PageEvents[0].RemoteAddr)
+
+	w = test.PerformRequest(router, "GET", "/private/account/export?format=zip", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "account.json")
+	assert.Contains(t, names, fmt.Sprintf("luchador-%v/gamedefinition-0/onStart.lua", luchador.ID))
+
+	w = test.PerformRequest(router, "GET", "/private/account/export?format=xml", "", token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAccountDeleteImmediately(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, luchador := setupAccountData(t, "maria")
+
+	body, _ := json.Marshal(model.AccountDeletionRequestBody{Mode: "forget"})
+	w := test.PerformRequest(router, "POST", "/private/account/delete", string(body), token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_DELETE)
+	assert.Equal(t, model.ACCOUNT_DELETION_COMPLETED, request.Status)
+	assert.Equal(t, "", request.Username)
+
+	assert.Nil(t, ds.FindUserByID(user.ID))
+
+	var count int
+	ds.DB.Model(&model.GameComponent{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Model(&model.CodeHistory{}).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Model(&model.MatchScore{}).Where("luchador_id = ?", luchador.ID).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Model(&model.Media{}).Count(&count)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 0, len(eventsDS.FindEventsByUser(user.ID)))
+
+	// the token used before the deletion is rejected
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAccountDeletionApprovedByTeacher(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, luchador := setupAccountData(t, "maria")
+	teacher := devLoginToken(t, "teacher")
+	otherTeacher := devLoginToken(t, "other-teacher")
+	test.PerformRequest(router, "GET", "/dashboard/get-user", "", teacher)
+	test.PerformRequest(router, "GET", "/dashboard/get-user", "", otherTeacher)
+
+	classroom := ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: ds.FindUserByUsername("teacher").ID})
+	ds.JoinClassroom(user, classroom.AccessCode)
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_ANONYMIZE)
+	assert.Equal(t, model.ACCOUNT_DELETION_PENDING, request.Status)
+
+	body, _ := json.Marshal(model.AccountDeletionRequestBody{Mode: model.ACCOUNT_DELETION_DELETE})
+	w := test.PerformRequest(router, "POST", "/private/account/delete", string(body), token)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/account/deletion-request", "", teacher)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pending []model.AccountDeletionRequest
+	json.Unmarshal(w.Body.Bytes(), &pending)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, "maria", pending[0].Username)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/account/deletion-request", "", otherTeacher)
+	assert.Equal(t, "[]", w.Body.String())
+
+	url := fmt.Sprintf("/dashboard/account/deletion-request/%v/approve", request.ID)
+	w = test.PerformRequest(router, "POST", url, "", otherTeacher)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequest(router, "POST", url, "", teacher)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	anonymized := ds.FindUserByID(user.ID)
+	assert.Equal(t, fmt.Sprintf("deleted-%v", user.ID), anonymized.Username)
+	assert.Equal(t, "", anonymized.Email)
+	assert.Equal(t, "", anonymized.DisplayName)
+
+	var renamed model.GameComponent
+	ds.DB.First(&renamed, luchador.ID)
+	assert.Equal(t, fmt.Sprintf("deleted-%v", luchador.ID), renamed.Name)
+
+	var count int
+	ds.DB.Model(&model.CodeHistory{}).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Model(&model.MatchScore{}).Where("luchador_id = ?", luchador.ID).Count(&count)
+	assert.Equal(t, 1, count)
+
+	assert.Equal(t, 0, len(eventsDS.FindEventsByUser(user.ID)))
+	assert.Equal(t, 1, len(eventsDS.FindEventsByUser(0)))
+	assert.Equal(t, "", eventsDS.FindEventsByUser(0)[0].RemoteAddr)
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "POST", url, "", teacher)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAccountDeletionRejectedByTeacher(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, _ := setupAccountData(t, "maria")
+	teacher := devLoginToken(t, "teacher")
+	test.PerformRequest(router, "GET", "/dashboard/get-user", "", teacher)
+
+	classroom := ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: ds.FindUserByUsername("teacher").ID})
+	ds.JoinClassroom(user, classroom.AccessCode)
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_DELETE)
+
+	url := fmt.Sprintf("/dashboard/account/deletion-request/%v/reject", request.ID)
+	w := test.PerformRequest(router, "POST", url, "", teacher)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/account/delete", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var requests []model.AccountDeletionRequest
+	json.Unmarshal(w.Body.Bytes(), &requests)
+	assert.Equal(t, model.ACCOUNT_DELETION_REJECTED, requests[0].Status)
+	assert.NotNil(t, ds.FindUserByID(user.ID))
+}
+
+// uploads an image with its renditions and adds moderation records of the user
+func setupAccountModeration(t *testing.T, token string, user *model.User) (model.Media, *model.User) {
+	body := fmt.Sprintf(`{"fileName":"avatar.png","base64Data":"%v"}`, testImage)
+	w := test.PerformRequest(router, "POST", "/private/media", body, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var media model.Media
+	json.Unmarshal(w.Body.Bytes(), &media)
+	w = test.PerformRequest(router, "GET", media.Thumbnail, "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	other := ds.CreateUser("reporter")
+	ds.DB.Create(&model.ModerationFlag{UserID: user.ID, Field: "name", Text: "bad name"})
+	ds.DB.Create(&model.ModerationReport{ReporterUserID: other.ID, ReportedUserID: user.ID, Reason: "offensive"})
+	ds.DB.Create(&model.ModerationReport{ReporterUserID: user.ID, ReportedUserID: other.ID, Reason: "rude"})
+
+	return media, other
+}
+
+func TestAccountModerationExportAndDelete(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, _ := setupAccountData(t, "maria")
+	media, other := setupAccountModeration(t, token, user)
+
+	w := test.PerformRequest(router, "GET", "/private/account/export", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var export model.AccountExport
+	json.Unmarshal(w.Body.Bytes(), &export)
+	assert.Equal(t, 1, len(export.ModerationFlags))
+	assert.Equal(t, "bad name", export.ModerationFlags[0].Text)
+	assert.Equal(t, 2, len(export.ModerationReports))
+
+	// the users reporting the account are not exported
+	assert.Equal(t, uint(0), export.ModerationReports[0].ReporterUserID)
+	assert.Equal(t, user.ID, export.ModerationReports[1].ReporterUserID)
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_DELETE)
+	assert.Equal(t, model.ACCOUNT_DELETION_COMPLETED, request.Status)
+
+	// the files of the media are removed from the store
+	for _, url := range []string{media.URL, media.Thumbnail} {
+		w = test.PerformRequestNoAuth(router, "GET", url, "")
+		assert.Equal(t, http.StatusNotFound, w.Code, url)
+	}
+
+	var count int
+	ds.DB.Unscoped().Model(&model.MediaRendition{}).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Unscoped().Model(&model.ModerationFlag{}).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Unscoped().Model(&model.ModerationReport{}).Count(&count)
+	assert.Equal(t, 0, count)
+	assert.NotNil(t, ds.FindUserByID(other.ID))
+}
+
+func TestAccountModerationAnonymize(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, _ := setupAccountData(t, "maria")
+	media, other := setupAccountModeration(t, token, user)
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_ANONYMIZE)
+	assert.Equal(t, model.ACCOUNT_DELETION_COMPLETED, request.Status)
+
+	w := test.PerformRequestNoAuth(router, "GET", media.URL, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var flag model.ModerationFlag
+	ds.DB.Where(&model.ModerationFlag{UserID: user.ID}).First(&flag)
+	assert.Equal(t, "", flag.Text)
+
+	var sent model.ModerationReport
+	ds.DB.Where(&model.ModerationReport{ReportedUserID: other.ID}).First(&sent)
+	assert.Equal(t, uint(0), sent.ReporterUserID)
+	assert.Equal(t, "", sent.Reason)
+}
+
+func TestAccountDeletedLuchador(t *testing.T) {
+	SetupAccount(t)
+	defer ds.DB.Close()
+	defer eventsDS.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	token, user, _ := setupAccountData(t, "maria")
+	deleted := ds.CreateLuchador(&model.GameComponent{
+		UserID: user.ID,
+		Name:   "deleted luchador",
+		Codes:  []model.Code{{Event: "onStart", Script: "fire(1)"}},
+	})
+	ds.DB.Create(&model.MatchScore{LuchadorID: deleted.ID, MatchID: 2, Score: 5})
+	assert.Nil(t, ds.DeleteLuchador(deleted))
+
+	w := test.PerformRequest(router, "GET", "/private/account/export", "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var export model.AccountExport
+	json.Unmarshal(w.Body.Bytes(), &export)
+	assert.Equal(t, 2, len(export.Luchadors))
+	assert.Equal(t, 2, len(export.CodeHistory))
+	assert.Equal(t, 2, len(export.MatchScores))
+
+	request := requestAccountDeletion(t, token, model.ACCOUNT_DELETION_DELETE)
+	assert.Equal(t, model.ACCOUNT_DELETION_COMPLETED, request.Status)
+
+	var count int
+	ds.DB.Unscoped().Model(&model.GameComponent{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Model(&model.MatchScore{}).Where("luchador_id = ?", deleted.ID).Count(&count)
+	assert.Equal(t, 0, count)
+	ds.DB.Unscoped().Model(&model.Code{}).Count(&count)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func issueAPIKey(t *testing.T, name string, scopes ...string) model.APIKeyResponse {
+	body, _ := json.Marshal(model.APIKeyRequest{Name: name, Scopes: scopes})
+	w := test.PerformRequestNoAuth(router, "POST", "/dashboard/api-key", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.APIKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	runner := issueAPIKey(t, "runner", model.APIKEY_SCOPE_RUNNER)
+	metadata := issueAPIKey(t, "metadata", model.APIKEY_SCOPE_METADATA, model.APIKEY_SCOPE_METADATA)
+
+	assert.NotEqual(t, "", runner.Key)
+	assert.Equal(t, model.APIKEY_SCOPE_METADATA, metadata.APIKey.Scopes)
+	assert.Equal(t, auth.HashAPIKey(runner.Key), ds.FindAPIKey(runner.APIKey.ID).KeyHash)
+
+	w := test.PerformRequest(router, "GET", "/internal/game-definition/unknown", "", metadata.Key)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "POST", "/internal/match-participant", "{}", metadata.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "PUT", "/internal/game-definition", "{}", runner.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/internal/game-definition/unknown", "", "Bearer "+runner.Key)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, ds.FindAPIKey(runner.APIKey.ID).LastUsedAt)
+
+	// INTERNAL_API_KEY keeps working with all the scopes
+	w = test.PerformRequest(router, "PUT", "/internal/game-definition", "{}", test.API_KEY)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/internal/ready", "", "wrong")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/internal/ready", "", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAPIKeyValidation(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	issueAPIKey(t, "runner", model.APIKEY_SCOPE_RUNNER)
+	past := time.Now().Add(-time.Hour)
+
+	requests := map[string]model.APIKeyRequest{
+		"no name":       {Scopes: []string{model.APIKEY_SCOPE_RUNNER}},
+		"no scope":      {Name: "empty"},
+		"unknown scope": {Name: "admin", Scopes: []string{"admin"}},
+		"expired":       {Name: "old", Scopes: []string{model.APIKEY_SCOPE_RUNNER}, ExpiresAt: &past},
+	}
+	for name, request := range requests {
+		body, _ := json.Marshal(request)
+		w := test.PerformRequestNoAuth(router, "POST", "/dashboard/api-key", string(body))
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	body, _ := json.Marshal(model.APIKeyRequest{Name: "runner", Scopes: []string{model.APIKEY_SCOPE_RUNNER}})
+	w := test.PerformRequestNoAuth(router, "POST", "/dashboard/api-key", string(body))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "GET", "/dashboard/api-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Hash")
+}
+
+func TestAPIKeyRotateAndRevoke(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	first := issueAPIKey(t, "runner", model.APIKEY_SCOPE_RUNNER)
+
+	url := fmt.Sprintf("/dashboard/api-key/%v/rotate", first.APIKey.ID)
+	w := test.PerformRequestNoAuth(router, "POST", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var second model.APIKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &second)
+	assert.NotEqual(t, first.Key, second.Key)
+
+	// both keys are valid during the grace period
+	w = test.PerformRequest(router, "GET", "/internal/match-single?matchID=1", "", first.Key)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+	w = test.PerformRequest(router, "GET", "/internal/match-single?matchID=1", "", second.Key)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", url+"?graceMinutes=0", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var third model.APIKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &third)
+
+	w = test.PerformRequest(router, "GET", "/internal/match-single?matchID=1", "", second.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "DELETE", fmt.Sprintf("/dashboard/api-key/%v", first.APIKey.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/internal/match-single?matchID=1", "", third.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", url, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "DELETE", "/dashboard/api-key/999", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	raw, prefix, hash, err := auth.GenerateAPIKey()
+	assert.Nil(t, err)
+	expiresAt := time.Now().Add(time.Minute)
+	ds.CreateAPIKey(&model.APIKey{Name: "expiring", Prefix: prefix, KeyHash: hash,
+		Scopes: model.APIKEY_SCOPE_ANALYTICS, ExpiresAt: &expiresAt})
+
+	assert.NotNil(t, ds.FindValidAPIKey(hash, time.Now()))
+	assert.Nil(t, ds.FindValidAPIKey(hash, expiresAt))
+
+	w := test.PerformRequest(router, "POST", "/internal/match-participant", "{}", raw)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+const apiKeyMarker = "rlk_"
+const apiKeyContextName = "apiKey"
+
+// name used in the logs when the request uses INTERNAL_API_KEY
+const legacyAPIKeyName = "INTERNAL_API_KEY"
+
+// GenerateAPIKey creates a random key, returns the key, the prefix used to identify it and its hash
+func GenerateAPIKey() (string, string, string, error) {
+	bytes := make([]byte, 24)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	raw := apiKeyMarker + hex.EncodeToString(bytes)
+	return raw, raw[:len(apiKeyMarker)+8], HashAPIKey(raw), nil
+}
+
+// HashAPIKey returns the hash stored for the key
+func HashAPIKey(raw string) string {
+	return hashToken(raw)
+}
+
+// APIKeyIsValid checks the Authorization header against the managed keys,
+// legacyKey from INTERNAL_API_KEY is accepted with all the scopes when defined
+func APIKeyIsValid(ds *datasource.DataSource, legacyKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorization := strings.TrimPrefix(c.Request.Header.Get("Authorization"), bearerPrefix)
+		if authorization == "" {
+			log.Debug("No Authorization header")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		if legacyKey != "" && subtle.ConstantTimeCompare([]byte(authorization), []byte(legacyKey)) == 1 {
+			c.Set(apiKeyContextName, &model.APIKey{
+				Name:   legacyAPIKeyName,
+				Scopes: strings.Join(model.APIKeyScopes, ","),
+			})
+			return
+		}
+
+		now := time.Now()
+		key := ds.FindValidAPIKey(HashAPIKey(authorization), now)
+		if key == nil {
+			log.Info("INVALID Authorization key")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		ds.TouchAPIKey(key, now)
+		c.Set(apiKeyContextName, key)
+	}
+}
+
+// RequireScope rejects the request when the API key dont have any of the scopes
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(apiKeyContextName)
+		key, ok := value.(*model.APIKey)
+		if ok {
+			for _, scope := range scopes {
+				if key.HasScope(scope) {
+					return
+				}
+			}
+		}
+
+		fields := log.Fields{
+			"scopes": scopes,
+			"path":   c.FullPath(),
+		}
+		if ok {
+			fields["name"] = key.Name
+			fields["prefix"] = key.Prefix
+		}
+		log.WithFields(fields).Info("API key DONT have scope")
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
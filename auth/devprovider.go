@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	devKeyID          = "robolucha-dev"
+	devIssuer         = "robolucha-dev"
+	devTokenLifetime  = 8 * time.Hour
+	devProviderEnv    = "AUTH_DEV_PROVIDER"
+	devUsersFileEnv   = "AUTH_DEV_USERS_FILE"
+	devProviderEnable = "true"
+)
+
+// users available when AUTH_DEV_USERS_FILE is not defined
+var defaultDevUsers = []JWTUser{
+	{Username: "student", FirstName: "Student", Name: "Student"},
+	{Username: "teacher", FirstName: "Teacher", Name: "Teacher", Roles: []string{dashboardRole}},
+	{Username: "editor", FirstName: "Editor", Name: "Editor", Roles: []string{SystemEditorRole}},
+}
+
+// DevProvider issues signed tokens for configured fake users, for local development only
+type DevProvider struct {
+	key   *rsa.PrivateKey
+	users map[string]JWTUser
+}
+
+// the enabled dev provider, the session verifiers accept only its tokens
+var devProvider *DevProvider
+
+// NewDevProvider creates a dev provider with a new signing key, tokens use the
+// robolucha-dev issuer so they are never valid for the production verifier
+func NewDevProvider(users []JWTUser) (*DevProvider, error) {
+	if len(users) == 0 {
+		return nil, errors.New("Dev provider without users")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating dev provider key: %v", err)
+	}
+
+	provider := DevProvider{
+		key:   key,
+		users: make(map[string]JWTUser),
+	}
+	for _, user := range users {
+		if user.Username == "" {
+			return nil, errors.New("Dev provider user without username")
+		}
+		provider.users[user.Username] = user
+	}
+
+	return &provider, nil
+}
+
+// EnableDevProviderFromEnv enables the dev provider when AUTH_DEV_PROVIDER is true,
+// users are read from AUTH_DEV_USERS_FILE as a JSON list or the default users are used.
+// It is refused when AUTH_JWKS_FILE or AUTH_JWKS_URL is defined, a JWKS source means a real
+// identity provider. Must be called before the session validators are created
+func EnableDevProviderFromEnv() error {
+	if os.Getenv(devProviderEnv) != devProviderEnable {
+		return nil
+	}
+
+	if os.Getenv(jwksFileEnv) != "" || os.Getenv(jwksURLEnv) != "" {
+		return fmt.Errorf("Dev provider cant be enabled when %v or %v is defined", jwksFileEnv, jwksURLEnv)
+	}
+
+	users := defaultDevUsers
+	if fileName := os.Getenv(devUsersFileEnv); fileName != "" {
+		bytes, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return fmt.Errorf("Error reading dev users file: %v", err)
+		}
+		err = json.Unmarshal(bytes, &users)
+		if err != nil {
+			return fmt.Errorf("Error parsing dev users file: %v", err)
+		}
+	}
+
+	provider, err := NewDevProvider(users)
+	if err != nil {
+		return err
+	}
+
+	EnableDevProvider(provider)
+	return nil
+}
+
+// EnableDevProvider makes the session validators accept the tokens of the provider
+func EnableDevProvider(provider *DevProvider) {
+	devProvider = provider
+
+	if provider != nil {
+		log.WithFields(log.Fields{
+			"users": provider.Usernames(),
+		}).Warn("Dev auth provider ENABLED, dont use it in production")
+	}
+}
+
+// GetDevProvider returns the enabled dev provider or nil
+func GetDevProvider() *DevProvider {
+	return devProvider
+}
+
+// Users returns the configured users sorted by username
+func (p *DevProvider) Users() []JWTUser {
+	result := make([]JWTUser, 0, len(p.users))
+	for _, username := range p.Usernames() {
+		result = append(result, p.users[username])
+	}
+	return result
+}
+
+// Usernames returns the sorted list of usernames
+func (p *DevProvider) Usernames() []string {
+	result := make([]string, 0, len(p.users))
+	for username := range p.users {
+		result = append(result, username)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Login issues a token for the configured user with the same claims as keycloak
+func (p *DevProvider) Login(username string) (string, time.Time, error) {
+	user, found := p.users[username]
+	if !found {
+		return "", time.Time{}, fmt.Errorf("unknown dev user '%v'", username)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(devTokenLifetime)
+
+	roles := user.Roles
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := jwt.MapClaims{
+		"jti":                hex.EncodeToString(jti),
+		"iat":                now.Unix(),
+		"exp":                expiresAt.Unix(),
+		"iss":                devIssuer,
+		"sub":                user.Username,
+		"preferred_username": user.Username,
+		"name":               user.Name,
+		"given_name":         user.FirstName,
+		"family_name":        user.LastName,
+		"email":              user.Email,
+		"email_verified":     user.EmailVerified,
+		"realm_access": map[string]interface{}{
+			"roles": roles,
+		},
+	}
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = devKeyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
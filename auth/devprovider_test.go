@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevProviderLogin(t *testing.T) {
+	provider, err := NewDevProvider(defaultDevUsers)
+	assert.Nil(t, err)
+	EnableDevProvider(provider)
+	defer EnableDevProvider(nil)
+
+	verifier := newSessionVerifier()
+	assert.NotNil(t, verifier)
+
+	token, _, err := provider.Login("teacher")
+	assert.Nil(t, err)
+
+	user, err := verifier.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "teacher", user.Username)
+	assert.Equal(t, []string{dashboardRole}, user.Roles)
+
+	_, _, err = provider.Login("unknown")
+	assert.NotNil(t, err)
+
+	// tokens from another provider instance are rejected
+	other, _ := NewDevProvider(defaultDevUsers)
+	token, _, _ = other.Login("teacher")
+	_, err = verifier.Verify(token)
+	assert.NotNil(t, err)
+}
+
+func TestDevProviderUsers(t *testing.T) {
+	_, err := NewDevProvider(nil)
+	assert.NotNil(t, err)
+
+	_, err = NewDevProvider([]JWTUser{{Name: "no username"}})
+	assert.NotNil(t, err)
+
+	provider, _ := NewDevProvider(defaultDevUsers)
+	assert.Equal(t, []string{"editor", "student", "teacher"}, provider.Usernames())
+}
+
+func TestAddedKeysSurviveRefresh(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buildJWKS(map[string]*rsa.PrivateKey{"main": key}))
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifierFromURL(server.URL, testIssuer, testAudience)
+	assert.Nil(t, err)
+
+	added, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier.AddKey("added", &added.PublicKey)
+
+	assert.Nil(t, verifier.refresh())
+
+	_, err = verifier.Verify(signToken(t, added, "added", validClaims()))
+	assert.Nil(t, err)
+}
+
+func TestDevProviderTokensRejectedByProductionVerifier(t *testing.T) {
+	verifier, _ := setupVerifier(t)
+	provider, _ := NewDevProvider(defaultDevUsers)
+
+	// even with the same key the dev issuer is rejected
+	verifier.AddKey(devKeyID, &provider.key.PublicKey)
+	token, _, _ := provider.Login("teacher")
+	_, err := verifier.Verify(token)
+	assert.NotNil(t, err)
+}
+
+func TestDevProviderRefusedWithJWKS(t *testing.T) {
+	os.Setenv(devProviderEnv, devProviderEnable)
+	defer os.Unsetenv(devProviderEnv)
+	defer EnableDevProvider(nil)
+
+	os.Setenv(jwksURLEnv, "http://localhost/jwks")
+	assert.NotNil(t, EnableDevProviderFromEnv())
+	assert.Nil(t, GetDevProvider())
+	os.Unsetenv(jwksURLEnv)
+
+	assert.Nil(t, EnableDevProviderFromEnv())
+	assert.NotNil(t, GetDevProvider())
+}
@@ -4,21 +4,25 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	log "github.com/sirupsen/logrus"
 )
 
 type JWTUser struct {
-	Name          string   `json:"name"`
-	Username      string   `json:"username"`
-	EmailVerified bool     `json:"emailVerified"`
-	FirstName     string   `json:"firstName"`
-	LastName      string   `json:"lastName"`
-	Email         string   `json:"email"`
-	Roles         []string `json:"roles"`
+	Name          string    `json:"name"`
+	Username      string    `json:"username"`
+	EmailVerified bool      `json:"emailVerified"`
+	FirstName     string    `json:"firstName"`
+	LastName      string    `json:"lastName"`
+	Email         string    `json:"email"`
+	Roles         []string  `json:"roles"`
+	Locale        string    `json:"locale"`
+	ExpiresAt     time.Time `json:"-"`
 }
 
 // from https://github.com/keycloak/keycloak-gatekeeper/blob/d87453446b6dbe6aea36d069dac7aef7b42e6c5e/doc.go
@@ -29,6 +33,8 @@ const (
 	claimGroups         = "groups"
 )
 
+// GetUser reads the user from the gatekeeper cookie WITHOUT verifying the token,
+// sessions should use Verifier.Verify
 func GetUser(encrypted, key string) (JWTUser, error) {
 	result := JWTUser{}
 	decrypted, err := decodeText(encrypted, key)
@@ -44,22 +50,67 @@ func GetUser(encrypted, key string) (JWTUser, error) {
 		return result, err
 	}
 
-	result.Name = token.Claims.(jwt.MapClaims)["name"].(string)
-	result.Username = token.Claims.(jwt.MapClaims)["preferred_username"].(string)
-	result.EmailVerified = token.Claims.(jwt.MapClaims)["email_verified"].(bool)
-	result.FirstName = token.Claims.(jwt.MapClaims)["given_name"].(string)
-	result.LastName = token.Claims.(jwt.MapClaims)["family_name"].(string)
-	result.Email = token.Claims.(jwt.MapClaims)["email"].(string)
+	return userFromClaims(token.Claims.(jwt.MapClaims)), nil
+}
 
+// maps the keycloak claims to the user, missing claims are left empty
+func userFromClaims(claims jwt.MapClaims) JWTUser {
 	log.Debug("Claim list")
 
-	for key, val := range token.Claims.(jwt.MapClaims) {
+	for key, val := range claims {
 		log.Debug(fmt.Sprintf("Claim Key: %v, value: %v", key, val))
 	}
 
-	result.Roles = getRoles(token.Claims.(jwt.MapClaims))
+	expiresAt, _ := claimTime(claims, "exp")
+
+	return JWTUser{
+		Name:          claimString(claims, "name"),
+		Username:      claimString(claims, "preferred_username"),
+		EmailVerified: claimBool(claims, "email_verified"),
+		FirstName:     claimString(claims, "given_name"),
+		LastName:      claimString(claims, "family_name"),
+		Email:         claimString(claims, "email"),
+		Roles:         getRoles(claims),
+		Locale:        claimString(claims, "locale"),
+		ExpiresAt:     expiresAt,
+	}
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func claimBool(claims jwt.MapClaims, name string) bool {
+	value, _ := claims[name].(bool)
+	return value
+}
+
+// numeric dates are seconds since the epoch
+func claimTime(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch value := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(value), 0), true
+	case json.Number:
+		seconds, err := value.Int64()
+		return time.Unix(seconds, 0), err == nil
+	}
+	return time.Time{}, false
+}
 
-	return result, nil
+// claims as aud can be a single string or a list
+func claimContains(claims jwt.MapClaims, name string, search string) bool {
+	switch value := claims[name].(type) {
+	case string:
+		return value == search
+	case []interface{}:
+		for _, item := range value {
+			if item == search {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // from https://github.com/keycloak/keycloak-gatekeeper/blob/1b7ee69ed9ef1b471be86a18b37db82bc950a4f6/user_context.go
@@ -67,8 +118,8 @@ func getRoles(claims jwt.MapClaims) []string {
 	var roleList []string
 
 	if realmRoles, found := claims[claimRealmAccess].(map[string]interface{}); found {
-		if roles, found := realmRoles[claimResourceRoles]; found {
-			for _, r := range roles.([]interface{}) {
+		if roles, found := realmRoles[claimResourceRoles].([]interface{}); found {
+			for _, r := range roles {
 				roleList = append(roleList, fmt.Sprintf("%s", r))
 			}
 		}
@@ -76,9 +127,12 @@ func getRoles(claims jwt.MapClaims) []string {
 
 	if accesses, found := claims[claimResourceAccess].(map[string]interface{}); found {
 		for name, list := range accesses {
-			scopes := list.(map[string]interface{})
-			if roles, found := scopes[claimResourceRoles]; found {
-				for _, r := range roles.([]interface{}) {
+			scopes, found := list.(map[string]interface{})
+			if !found {
+				continue
+			}
+			if roles, found := scopes[claimResourceRoles].([]interface{}); found {
+				for _, r := range roles {
 					roleList = append(roleList, fmt.Sprintf("%s:%s", name, r))
 				}
 			}
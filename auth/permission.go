@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// Permission names checked by the routes
+const (
+	PermissionDashboardAccess  = "dashboard.access"
+	PermissionClassroomManage  = "classroom.manage"
+	PermissionAssignmentGrade  = "assignment.grade"
+	PermissionLearningEdit     = "learning.edit"
+	PermissionLearningEditAny  = "learning.edit.any"
+	PermissionMapEditAny       = "map.edit.any"
+	PermissionMatchAdmin       = "match.admin"
+	PermissionAPIKeyAdmin      = "apikey.admin"
+	PermissionSessionAdmin     = "session.admin"
+	PermissionModerationReview = "moderation.review"
+)
+
+// realm roles are used as is, resource roles are prefixed by the client name as "client:role"
+var defaultRolePermissions = map[string][]string{
+	dashboardRole: {
+		PermissionDashboardAccess,
+		PermissionClassroomManage,
+		PermissionAssignmentGrade,
+		PermissionLearningEdit,
+	},
+	SystemEditorRole: {
+		PermissionDashboardAccess,
+		PermissionLearningEdit,
+		PermissionLearningEditAny,
+		PermissionMapEditAny,
+		PermissionMatchAdmin,
+		PermissionAPIKeyAdmin,
+		PermissionSessionAdmin,
+		PermissionModerationReview,
+	},
+}
+
+var rolePermissions = defaultRolePermissions
+
+// LoadRolePermissions replaces the default role to permission mapping with a JSON file
+// as {"role": ["permission"]}
+func LoadRolePermissions(fileName string) error {
+	bytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("Error reading role permissions file: %v", err)
+	}
+
+	var mapping map[string][]string
+	err = json.Unmarshal(bytes, &mapping)
+	if err != nil {
+		return fmt.Errorf("Error parsing role permissions file: %v", err)
+	}
+
+	rolePermissions = mapping
+
+	log.WithFields(log.Fields{
+		"fileName": fileName,
+		"roles":    len(mapping),
+	}).Info("Role permissions loaded")
+
+	return nil
+}
+
+// PermissionsForRoles returns the sorted list of permissions granted by the roles
+func PermissionsForRoles(roles []string) []string {
+	found := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range rolePermissions[role] {
+			found[permission] = true
+		}
+	}
+
+	result := make([]string, 0, len(found))
+	for permission := range found {
+		result = append(result, permission)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// UserHasPermission checks if the user roles grant the permission
+func UserHasPermission(userDetails *model.UserDetails, permission string) bool {
+	return contains(PermissionsForRoles(userDetails.Roles), permission)
+}
+
+// RequirePermission rejects the request when the current user dont have all the permissions
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userDetails := httphelper.UserDetailsFromContext(c)
+		granted := PermissionsForRoles(userDetails.Roles)
+
+		for _, permission := range permissions {
+			if !contains(granted, permission) {
+				log.WithFields(log.Fields{
+					"user":       userDetails.User,
+					"roles":      userDetails.Roles,
+					"permission": permission,
+					"path":       c.FullPath(),
+				}).Info("User DONT have permission")
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+	}
+}
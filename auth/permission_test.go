@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+func TestPermissionsForRoles(t *testing.T) {
+	permissions := PermissionsForRoles([]string{dashboardRole, SystemEditorRole, "unknown"})
+	assert.Equal(t, []string{
+		PermissionAPIKeyAdmin,
+		PermissionAssignmentGrade,
+		PermissionClassroomManage,
+		PermissionDashboardAccess,
+		PermissionLearningEdit,
+		PermissionLearningEditAny,
+		PermissionMapEditAny,
+		PermissionMatchAdmin,
+		PermissionModerationReview,
+		PermissionSessionAdmin,
+	}, permissions)
+
+	assert.Equal(t, 0, len(PermissionsForRoles(nil)))
+}
+
+func TestUserHasPermission(t *testing.T) {
+	teacher := model.UserDetails{Roles: []string{dashboardRole}}
+	assert.True(t, UserHasPermission(&teacher, PermissionClassroomManage))
+	assert.False(t, UserHasPermission(&teacher, PermissionMapEditAny))
+}
+
+func TestLoadRolePermissions(t *testing.T) {
+	defer func() { rolePermissions = defaultRolePermissions }()
+
+	file, err := ioutil.TempFile("", "permissions*.json")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`{"teacher": ["dashboard.access", "classroom.manage"]}`)
+	file.Close()
+
+	err = LoadRolePermissions(file.Name())
+	assert.Nil(t, err)
+
+	teacher := model.UserDetails{Roles: []string{"teacher"}}
+	assert.True(t, UserHasPermission(&teacher, PermissionClassroomManage))
+
+	editor := model.UserDetails{Roles: []string{SystemEditorRole}}
+	assert.False(t, UserHasPermission(&editor, PermissionMapEditAny))
+
+	err = LoadRolePermissions("/not/found.json")
+	assert.NotNil(t, err)
+}
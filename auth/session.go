@@ -1,8 +1,14 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -12,6 +18,7 @@ import (
 
 const (
 	cookieName             = "kc-access"
+	bearerPrefix           = "Bearer "
 	getkeeperEncryptionKey = "GATEKEEPER_ENCRYPTION_KEY"
 	dashboardRole          = "dashboard_user"
 	SystemEditorRole       = "SYSTEM_EDITOR"
@@ -20,112 +27,255 @@ const (
 // SessionValidatorFactory definition
 type SessionValidatorFactory func(ds *datasource.DataSource) gin.HandlerFunc
 
-// SessionIsValid check if Authorization header is valid
+// SessionIsValid check if the session token is valid, the token is read from
+// the Authorization Bearer header or from the gatekeeper cookie
 func SessionIsValid(ds *datasource.DataSource) gin.HandlerFunc {
-	return func(c *gin.Context) {
+	verifier := &sessionVerifier{}
+	verifier.get()
+	key := os.Getenv(getkeeperEncryptionKey)
 
-		authorization, err := c.Request.Cookie(cookieName)
-		if err != nil {
-			log.Debug("Error reading authorization cookie")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
-		if authorization.Value == "" {
-			log.Debug("No Authorization cookie")
+	return func(c *gin.Context) {
+		session, ok := sessionFromRequest(c, ds, verifier.get(), key)
+		if !ok {
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		key := os.Getenv(getkeeperEncryptionKey)
-		sessionUser, err := GetUser(authorization.Value, key)
-		if err != nil {
-			log.Debug("Error reading user from authorization cookie")
+		details, ok := sessionUserDetails(ds, session)
+		if !ok {
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		if sessionUser.Username == "" {
-			log.WithFields(log.Fields{
-				"authorization": authorization,
-				"cookie-name":   cookieName,
-				"sessionUser":   sessionUser,
-			}).Info("Invalid Session")
-			c.AbortWithStatus(http.StatusForbidden)
+		if rejectSuspended(c, details) {
 			return
-		} else {
-			log.WithFields(log.Fields{
-				"sessionUser": sessionUser,
-			}).Info("User Authorized")
 		}
 
-		user := ds.CreateUser(sessionUser.Username)
-		level := ds.FindUserLevelByUserID(user.ID)
-
-		userDetails := model.UserDetails{
-			User:  user,
-			Roles: sessionUser.Roles,
-			Level: *level,
-		}
-		c.Set("userDetails", userDetails)
+		c.Set("session", session)
+		c.Set("userDetails", details)
 	}
 }
 
-// SessionIsValidAndDashBoardUser check if Authorization header is valid
+// SessionIsValidAndDashBoardUser check if the session token is valid and the user has the dashboard role
 func SessionIsValidAndDashBoardUser(ds *datasource.DataSource) gin.HandlerFunc {
-	return func(c *gin.Context) {
+	verifier := &sessionVerifier{}
+	verifier.get()
+	key := os.Getenv(getkeeperEncryptionKey)
 
-		authorization, err := c.Request.Cookie(cookieName)
-		if err != nil {
-			log.Debug("Error reading authorization cookie")
+	return func(c *gin.Context) {
+		session, ok := sessionFromRequest(c, ds, verifier.get(), key)
+		if !ok {
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
-		if authorization.Value == "" {
-			log.Debug("No Authorization cookie")
+
+		if !contains(session.RoleList(), dashboardRole) {
+			log.WithFields(log.Fields{
+				"session":       session.UUID,
+				"dashboardRole": dashboardRole,
+			}).Info("User DONT have dashboard role")
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		key := os.Getenv(getkeeperEncryptionKey)
-		sessionUser, err := GetUser(authorization.Value, key)
-		if err != nil {
-			log.Debug("Error reading user from authorization cookie")
+		details, ok := sessionUserDetails(ds, session)
+		if !ok {
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		if sessionUser.Username == "" {
-			log.WithFields(log.Fields{
-				"authorization": authorization,
-				"cookie-name":   cookieName,
-				"sessionUser":   sessionUser,
-			}).Info("Invalid Session")
-			c.AbortWithStatus(http.StatusForbidden)
+		if rejectSuspended(c, details) {
 			return
-		} else {
-			log.WithFields(log.Fields{
-				"sessionUser": sessionUser,
-			}).Info("User Authorized")
 		}
 
-		if !contains(sessionUser.Roles, dashboardRole) {
+		c.Set("session", session)
+		c.Set("userDetails", details)
+	}
+}
+
+// sessionVerifier creates the verifier of the sessions when it is first needed, when the
+// creation fails it is tried again after the JWKS refresh interval
+type sessionVerifier struct {
+	mutex    sync.Mutex
+	verifier *Verifier
+	tried    time.Time
+}
+
+func (s *sessionVerifier) get() *Verifier {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.verifier == nil && time.Since(s.tried) > jwksRefreshInterval {
+		s.tried = time.Now()
+		s.verifier = newSessionVerifier()
+	}
+	return s.verifier
+}
+
+// without a verifier all the sessions are rejected, when the dev provider is enabled
+// only its tokens are accepted and the JWKS sources are not used
+func newSessionVerifier() *Verifier {
+	if devProvider != nil {
+		verifier := &Verifier{Issuer: devIssuer}
+		verifier.AddKey(devKeyID, &devProvider.key.PublicKey)
+		return verifier
+	}
+
+	verifier, err := NewVerifierFromEnv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Session verifier not configured, all sessions will be rejected until it is created")
+		return nil
+	}
+
+	return verifier
+}
+
+// reads the token from the Authorization Bearer header or from the gatekeeper cookie
+func tokenFromRequest(c *gin.Context, key string) (string, error) {
+	authorization := c.GetHeader("Authorization")
+	if strings.HasPrefix(authorization, bearerPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix)), nil
+	}
+
+	cookie, err := c.Request.Cookie(cookieName)
+	if err != nil || cookie.Value == "" {
+		return "", errors.New("No Authorization header or cookie")
+	}
+
+	return decodeText(cookie.Value, key)
+}
+
+func verifyToken(verifier *Verifier, token string) (JWTUser, bool) {
+	sessionUser, err := verifier.Verify(token)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Info("Invalid Session")
+		return JWTUser{}, false
+	}
+
+	if sessionUser.Username == "" {
+		log.WithFields(log.Fields{
+			"sessionUser": sessionUser,
+		}).Info("Invalid Session, token without preferred_username")
+		return JWTUser{}, false
+	}
+
+	log.WithFields(log.Fields{
+		"sessionUser": sessionUser,
+	}).Info("User Authorized")
+
+	return sessionUser, true
+}
+
+// finds the session of the token, the token is verified and the session
+// created on the first request, revoked and expired sessions are rejected
+func sessionFromRequest(c *gin.Context, ds *datasource.DataSource, verifier *Verifier, key string) (*model.Session, bool) {
+	if verifier == nil {
+		return nil, false
+	}
+
+	token, err := tokenFromRequest(c, key)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Debug("Error reading session token")
+		return nil, false
+	}
+
+	now := time.Now()
+	hash := hashToken(token)
+
+	session := ds.FindSessionByTokenHash(hash)
+	if session != nil {
+		if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
 			log.WithFields(log.Fields{
-				"sessionUser":   sessionUser,
-				"dashboardRole": dashboardRole,
-			}).Info("User DONT have dashboard role")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
+				"UUID":      session.UUID,
+				"revokedAt": session.RevokedAt,
+				"expiresAt": session.ExpiresAt,
+			}).Info("Session revoked or expired")
+			return nil, false
 		}
 
-		user := ds.CreateUser(sessionUser.Username)
-		level := ds.FindUserLevelByUserID(user.ID)
+		ds.TouchSession(session, now)
+		return session, true
+	}
 
-		userDetails := model.UserDetails{
-			User:  user,
-			Roles: sessionUser.Roles,
-			Level: *level,
-		}
-		c.Set("userDetails", userDetails)
+	sessionUser, ok := verifyToken(verifier, token)
+	if !ok {
+		return nil, false
+	}
+
+	user := ds.CreateUser(sessionUser.Username)
+	ds.SyncUserClaims(user, model.User{
+		Name:          sessionUser.Name,
+		FirstName:     sessionUser.FirstName,
+		LastName:      sessionUser.LastName,
+		Email:         sessionUser.Email,
+		EmailVerified: sessionUser.EmailVerified,
+		Locale:        sessionUser.Locale,
+	})
+
+	session = ds.CreateSession(&model.Session{
+		UserID:     user.ID,
+		TokenHash:  hash,
+		ExpiresAt:  sessionUser.ExpiresAt,
+		UserAgent:  c.Request.UserAgent(),
+		RemoteAddr: c.ClientIP(),
+	}, sessionUser.Roles)
+
+	return session, session != nil
+}
+
+// suspended users are rejected even when the session is valid
+func rejectSuspended(c *gin.Context, details model.UserDetails) bool {
+	if details.User.SuspendedAt == nil {
+		return false
+	}
+
+	log.WithFields(log.Fields{
+		"userID":      details.User.ID,
+		"suspendedAt": details.User.SuspendedAt,
+	}).Info("User suspended")
+	c.AbortWithStatusJSON(http.StatusForbidden, "User is suspended")
+	return true
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// loads the user level and permissions for the cached user and roles, sessions
+// of users that no longer exist are invalid
+func sessionUserDetails(ds *datasource.DataSource, session *model.Session) (model.UserDetails, bool) {
+	user := ds.FindUserByID(session.UserID)
+	if user == nil {
+		log.WithFields(log.Fields{
+			"session": session.UUID,
+			"userID":  session.UserID,
+		}).Info("Session user not found")
+		return model.UserDetails{}, false
+	}
+
+	return newUserDetails(ds, user, session.RoleList()), true
+}
+
+// creates the user when needed and loads the level and permissions
+func buildUserDetails(ds *datasource.DataSource, username string, roles []string) model.UserDetails {
+	return newUserDetails(ds, ds.CreateUser(username), roles)
+}
+
+func newUserDetails(ds *datasource.DataSource, user *model.User, roles []string) model.UserDetails {
+	level := ds.FindUserLevelByUserID(user.ID)
+
+	return model.UserDetails{
+		User:        user,
+		Roles:       roles,
+		Permissions: PermissionsForRoles(roles),
+		Level:       *level,
 	}
 }
 
@@ -143,46 +293,24 @@ func SessionAllwaysValid(ds *datasource.DataSource) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		log.Info("SessionAllwaysValid")
-		var user *model.User
 		testUserName := c.GetHeader("Authorization")
 
 		if testUserName == "" {
 			log.Info("no test user in the Authorization, will use default 'test'")
-			user = ds.CreateUser("test")
+			testUserName = "test"
 		} else {
 			log.WithFields(log.Fields{
 				"testUserName": testUserName,
 			}).Info("Will create user for test")
-			user = ds.CreateUser(testUserName)
-		}
-
-		// load user details
-		level := ds.FindUserLevelByUserID(user.ID)
-		userDetails := model.UserDetails{
-			User:  user,
-			Roles: []string{dashboardRole, SystemEditorRole},
-			Level: *level,
 		}
-		c.Set("userDetails", userDetails)
-
-	}
-}
 
-// KeyIsValid check if Authoraization header is valid
-func KeyIsValid(key string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authorization := c.Request.Header.Get("Authorization")
-		if authorization == "" {
-			log.Debug("No Authorization header")
-			c.AbortWithStatus(http.StatusForbidden)
+		details := buildUserDetails(ds, testUserName, []string{dashboardRole, SystemEditorRole})
+		if rejectSuspended(c, details) {
+			return
 		}
 
-		if authorization != key {
-			log.Info("INVALID Authorization key")
-			c.AbortWithStatus(http.StatusForbidden)
-		}
+		c.Set("userDetails", details)
 
-		log.Info("VALID Authorization key")
 	}
 }
 
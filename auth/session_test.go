@@ -1,7 +1,13 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,3 +23,29 @@ func TestContainsTrue(t *testing.T) {
 	c := contains(data, "two")
 	assert.Equal(t, true, c)
 }
+
+func TestSessionVerifierRetry(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	available := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(buildJWKS(map[string]*rsa.PrivateKey{"main": key}))
+	}))
+	defer server.Close()
+
+	os.Setenv(jwksURLEnv, server.URL)
+	defer os.Unsetenv(jwksURLEnv)
+
+	verifier := &sessionVerifier{}
+	assert.Nil(t, verifier.get())
+
+	// the creation is not tried again before the refresh interval
+	available = true
+	assert.Nil(t, verifier.get())
+
+	verifier.tried = time.Time{}
+	assert.NotNil(t, verifier.get())
+}
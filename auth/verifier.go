@@ -0,0 +1,372 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	jwksFileEnv = "AUTH_JWKS_FILE"
+	jwksURLEnv  = "AUTH_JWKS_URL"
+	issuerEnv   = "AUTH_ISSUER"
+	audienceEnv = "AUTH_AUDIENCE"
+)
+
+// tolerance between the clocks of the identity provider and the API when checking exp and nbf
+const clockSkew = 30 * time.Second
+
+// minimum time between JWKS downloads when a token uses an unknown key id
+const jwksRefreshInterval = time.Minute
+
+// Verifier validates the token signature with the keys of a JWKS document
+// and the exp, nbf, iss and aud claims
+type Verifier struct {
+	Issuer   string
+	Audience string
+	jwksURL  string
+	client   *http.Client
+	mutex    sync.RWMutex
+	keys     map[string]interface{}
+	added    map[string]interface{}
+	fetched  time.Time
+	// one download at a time, the requests waiting use the keys downloaded
+	refreshMutex sync.Mutex
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// NewVerifier creates a verifier with the keys of the JWKS document
+func NewVerifier(jwks []byte, issuer, audience string) (*Verifier, error) {
+	keys, err := parseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		Issuer:   issuer,
+		Audience: audience,
+		keys:     keys,
+	}, nil
+}
+
+// NewVerifierFromURL creates a verifier downloading the JWKS document,
+// the keys are downloaded again when a token uses an unknown key id
+func NewVerifierFromURL(url, issuer, audience string) (*Verifier, error) {
+	verifier := Verifier{
+		Issuer:   issuer,
+		Audience: audience,
+		jwksURL:  url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]interface{}),
+	}
+
+	err := verifier.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifier, nil
+}
+
+// NewVerifierFromEnv creates a verifier from AUTH_JWKS_FILE or AUTH_JWKS_URL,
+// AUTH_ISSUER and AUTH_AUDIENCE are checked when present
+func NewVerifierFromEnv() (*Verifier, error) {
+	issuer := os.Getenv(issuerEnv)
+	audience := os.Getenv(audienceEnv)
+
+	if fileName := os.Getenv(jwksFileEnv); fileName != "" {
+		bytes, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading JWKS file: %v", err)
+		}
+		return NewVerifier(bytes, issuer, audience)
+	}
+
+	if url := os.Getenv(jwksURLEnv); url != "" {
+		return NewVerifierFromURL(url, issuer, audience)
+	}
+
+	return nil, fmt.Errorf("%v or %v should be defined", jwksFileEnv, jwksURLEnv)
+}
+
+// AddKey adds a public key to verify tokens signed with the key id,
+// the key is kept when the JWKS is downloaded again
+func (v *Verifier) AddKey(kid string, key interface{}) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.keys == nil {
+		v.keys = make(map[string]interface{})
+	}
+	if v.added == nil {
+		v.added = make(map[string]interface{})
+	}
+	v.keys[kid] = key
+	v.added[kid] = key
+}
+
+// Verify checks the token signature and claims, returns the user from the claims
+func (v *Verifier) Verify(raw string) (JWTUser, error) {
+	parser := jwt.Parser{
+		ValidMethods:         []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		SkipClaimsValidation: true,
+	}
+
+	token, err := parser.Parse(raw, v.keyFunc)
+	if err != nil {
+		return JWTUser{}, fmt.Errorf("JWT Token verification failed: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return JWTUser{}, errors.New("JWT Token without claims")
+	}
+
+	err = v.validateClaims(claims, time.Now())
+	if err != nil {
+		return JWTUser{}, err
+	}
+
+	return userFromClaims(claims), nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key := v.findKey(kid)
+	if key == nil && v.jwksURL != "" {
+		key = v.refreshForKey(kid)
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id '%v'", kid)
+	}
+
+	// the key type must match the algorithm to avoid algorithm confusion
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("algorithm %v not valid for RSA key", token.Method.Alg())
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("algorithm %v not valid for EC key", token.Method.Alg())
+		}
+	}
+
+	return key, nil
+}
+
+// tokens without key id are accepted when there is a single key
+func (v *Verifier) findKey(kid string) interface{} {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+
+	return v.keys[kid]
+}
+
+// downloads the keys again when the refresh interval has passed since the last attempt,
+// concurrent requests wait for the download in progress instead of starting another one
+func (v *Verifier) refreshForKey(kid string) interface{} {
+	v.refreshMutex.Lock()
+	defer v.refreshMutex.Unlock()
+
+	// the keys may have been downloaded while waiting
+	if key := v.findKey(kid); key != nil || !v.canRefresh() {
+		return key
+	}
+
+	err := v.refresh()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("Error refreshing JWKS")
+	}
+	return v.findKey(kid)
+}
+
+func (v *Verifier) canRefresh() bool {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return time.Since(v.fetched) > jwksRefreshInterval
+}
+
+// the attempt is recorded before the download, a failing endpoint is tried again
+// only after the refresh interval
+func (v *Verifier) refresh() error {
+	v.mutex.Lock()
+	v.fetched = time.Now()
+	v.mutex.Unlock()
+
+	response, err := v.client.Get(v.jwksURL)
+	if err != nil {
+		return fmt.Errorf("Error downloading JWKS: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error downloading JWKS: status %v", response.StatusCode)
+	}
+
+	bytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("Error downloading JWKS: %v", err)
+	}
+
+	keys, err := parseJWKS(bytes)
+	if err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for kid, key := range v.added {
+		keys[kid] = key
+	}
+	v.keys = keys
+
+	log.WithFields(log.Fields{
+		"url":  v.jwksURL,
+		"keys": len(keys),
+	}).Info("JWKS loaded")
+
+	return nil
+}
+
+func (v *Verifier) validateClaims(claims jwt.MapClaims, now time.Time) error {
+	expiresAt, found := claimTime(claims, "exp")
+	if !found {
+		return errors.New("JWT Token without exp claim")
+	}
+	if now.After(expiresAt.Add(clockSkew)) {
+		return errors.New("JWT Token is expired")
+	}
+
+	notBefore, found := claimTime(claims, "nbf")
+	if found && now.Add(clockSkew).Before(notBefore) {
+		return errors.New("JWT Token is not valid yet")
+	}
+
+	if v.Issuer != "" && claimString(claims, "iss") != v.Issuer {
+		return fmt.Errorf("JWT Token issuer '%v' is not valid", claimString(claims, "iss"))
+	}
+
+	// keycloak access tokens have the client in azp when aud lists other services
+	if v.Audience != "" && !claimContains(claims, "aud", v.Audience) && claimString(claims, "azp") != v.Audience {
+		return errors.New("JWT Token audience is not valid")
+	}
+
+	return nil
+}
+
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set jsonWebKeySet
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"kid":   jwk.Kid,
+				"error": err,
+			}).Warn("Ignoring JWKS key")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS without signature keys")
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %v not supported", jwk.Crv)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("key type %v not supported", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testIssuer = "http://local.robolucha.com/auth/realms/robolucha"
+const testAudience = "robolucha-api"
+
+func buildJWKS(keys map[string]*rsa.PrivateKey) []byte {
+	set := jsonWebKeySet{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kid: kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	result, _ := json.Marshal(set)
+	return result
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iss":                testIssuer,
+		"aud":                []string{"account", testAudience},
+		"preferred_username": "maria",
+		"name":               "Maria Silva",
+		"email_verified":     true,
+		"locale":             "pt-BR",
+		"realm_access": map[string]interface{}{
+			"roles": []string{dashboardRole},
+		},
+	}
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	result, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return result
+}
+
+func setupVerifier(t *testing.T) (*Verifier, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	verifier, err := NewVerifier(buildJWKS(map[string]*rsa.PrivateKey{"main": key}), testIssuer, testAudience)
+	assert.Nil(t, err)
+	return verifier, key
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	verifier, key := setupVerifier(t)
+
+	user, err := verifier.Verify(signToken(t, key, "main", validClaims()))
+	assert.Nil(t, err)
+	assert.Equal(t, "maria", user.Username)
+	assert.Equal(t, "Maria Silva", user.Name)
+	assert.Equal(t, true, user.EmailVerified)
+	assert.Equal(t, "pt-BR", user.Locale)
+	assert.Equal(t, []string{dashboardRole}, user.Roles)
+}
+
+func TestVerifyRejectsInvalidClaims(t *testing.T) {
+	verifier, key := setupVerifier(t)
+
+	changes := map[string]func(jwt.MapClaims){
+		"expired":   func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no exp":    func(c jwt.MapClaims) { delete(c, "exp") },
+		"nbf":       func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"issuer":    func(c jwt.MapClaims) { c["iss"] = "http://evil.com" },
+		"audience":  func(c jwt.MapClaims) { c["aud"] = "other-api" },
+		"no issuer": func(c jwt.MapClaims) { delete(c, "iss") },
+	}
+
+	for name, change := range changes {
+		claims := validClaims()
+		change(claims)
+		_, err := verifier.Verify(signToken(t, key, "main", claims))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestVerifyAcceptsAuthorizedParty(t *testing.T) {
+	verifier, key := setupVerifier(t)
+
+	claims := validClaims()
+	claims["aud"] = "account"
+	claims["azp"] = testAudience
+
+	_, err := verifier.Verify(signToken(t, key, "main", claims))
+	assert.Nil(t, err)
+}
+
+func TestVerifyRejectsInvalidSignature(t *testing.T) {
+	verifier, _ := setupVerifier(t)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	_, err := verifier.Verify(signToken(t, other, "main", validClaims()))
+	assert.NotNil(t, err)
+
+	_, err = verifier.Verify(signToken(t, other, "unknown", validClaims()))
+	assert.NotNil(t, err)
+
+	// symmetric tokens signed with the public key are not accepted
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	signed, _ := hmac.SignedString([]byte("secret"))
+	_, err = verifier.Verify(signed)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyMissingClaims(t *testing.T) {
+	verifier, key := setupVerifier(t)
+
+	claims := jwt.MapClaims{
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"preferred_username": "maria",
+		"given_name":         42,
+		"resource_access":    map[string]interface{}{"broken": "roles"},
+	}
+
+	user, err := verifier.Verify(signToken(t, key, "main", claims))
+	assert.Nil(t, err)
+	assert.Equal(t, "maria", user.Username)
+	assert.Equal(t, "", user.FirstName)
+	assert.Equal(t, 0, len(user.Roles))
+}
+
+func TestVerifierFromURLRefreshesKeys(t *testing.T) {
+	first, _ := rsa.GenerateKey(rand.Reader, 2048)
+	second, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := map[string]*rsa.PrivateKey{"first": first}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buildJWKS(keys))
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifierFromURL(server.URL, testIssuer, testAudience)
+	assert.Nil(t, err)
+
+	_, err = verifier.Verify(signToken(t, first, "first", validClaims()))
+	assert.Nil(t, err)
+
+	// rotated keys are downloaded again when the refresh interval has passed
+	keys["second"] = second
+	_, err = verifier.Verify(signToken(t, second, "second", validClaims()))
+	assert.NotNil(t, err)
+
+	verifier.fetched = time.Time{}
+	_, err = verifier.Verify(signToken(t, second, "second", validClaims()))
+	assert.Nil(t, err)
+}
+
+func TestVerifierRefreshWhileEndpointFails(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&downloads, 1) > 1 {
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(buildJWKS(map[string]*rsa.PrivateKey{"main": key}))
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifierFromURL(server.URL, testIssuer, testAudience)
+	assert.Nil(t, err)
+
+	// concurrent tokens with unknown key ids start a single download
+	verifier.fetched = time.Time{}
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			_, err := verifier.Verify(signToken(t, key, fmt.Sprintf("unknown%v", i), validClaims()))
+			assert.NotNil(t, err)
+		}(i)
+	}
+	wait.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+
+	// the failed download is not tried again before the refresh interval
+	_, err = verifier.Verify(signToken(t, key, "other", validClaims()))
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+
+	_, err = verifier.Verify(signToken(t, key, "main", validClaims()))
+	assert.Nil(t, err)
+}
+
+func encryptCookie(t *testing.T, plain string, key string) string {
+	block, _ := aes.NewCipher([]byte(key))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return base64.RawStdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil))
+}
+
+func TestTokenFromBearerAndCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier, key := setupVerifier(t)
+	token := signToken(t, key, "main", validClaims())
+	cookieKey := "1gjrlcjQ8RyKANngp9607txr5fF5fhf1"
+
+	requests := map[string]func(r *http.Request){
+		"bearer": func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) },
+		"cookie": func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: cookieName, Value: encryptCookie(t, token, cookieKey)})
+		},
+	}
+
+	for name, prepare := range requests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/private/get-user", nil)
+		prepare(c.Request)
+
+		token, err := tokenFromRequest(c, cookieKey)
+		assert.Nil(t, err, name)
+		user, ok := verifyToken(verifier, token)
+		assert.True(t, ok, name)
+		assert.Equal(t, "maria", user.Username, name)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/private/get-user", nil)
+	_, err := tokenFromRequest(c, cookieKey)
+	assert.NotNil(t, err)
+
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	_, ok := sessionFromRequest(c, nil, nil, cookieKey)
+	assert.False(t, ok)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 	"gitlab.com/robolucha/robolucha-api/utility"
 )
 
@@ -69,3 +70,46 @@ func TestValidNamesFromPublicTest(t *testing.T) {
 		}
 	}
 }
+
+func assertBadWords(t *testing.T, locale string, expected bool, sentences []string) {
+	for _, sentence := range sentences {
+		if moderation.ContainsBadWord(sentence, locale) != expected {
+			log.WithFields(log.Fields{
+				"locale":   locale,
+				"sentence": sentence,
+				"expected": expected,
+			}).Info("assertBadWords")
+			t.Fail()
+		}
+	}
+}
+
+func TestBadWordsEnglish(t *testing.T) {
+	assertBadWords(t, "en", true, []string{"shit", "5hit", "fuuuuck", "ASSHOLE", "ａｓｓ", "bitch!!"})
+	assertBadWords(t, "en", false, []string{"Class 5A", "assignment", "Grass field", "hello world", "thebest"})
+}
+
+func TestBadWordsPortuguese(t *testing.T) {
+	assertBadWords(t, "pt", true, []string{"bunda", "pênis", "v4i t0m4r no cu", "CARALHOOO", "filho da puta", "рutа"})
+	assertBadWords(t, "pt-BR", false, []string{"Cuidado com o curso", "melhor de todos", "caduzinho", "Turma da manhã"})
+
+	// the english lists are included
+	assertBadWords(t, "pt", true, []string{"shit"})
+}
+
+func TestBadWordsSpanish(t *testing.T) {
+	assertBadWords(t, "es", true, []string{"cabrón", "p3nd3j0", "hijo de puta", "MIERDAAAA", "ｍｉｅｒｄａ", "gilipollas"})
+	assertBadWords(t, "es-MX", false, []string{"pollo asado", "la computadora", "buenos días", "clase de robótica"})
+
+	// the english lists are included
+	assertBadWords(t, "es", true, []string{"shit"})
+}
+
+func TestBadWordsLocaleSelection(t *testing.T) {
+	// spanish words are not checked for english texts
+	assertBadWords(t, "en", false, []string{"gilipollas", "pendejo"})
+
+	// all the lists are used without a locale
+	assertBadWords(t, "", true, []string{"gilipollas", "bunda", "shit"})
+	assertBadWords(t, "fr", true, []string{"gilipollas", "bunda", "shit"})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/events"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func SetupClassroomReport(t *testing.T) model.Classroom {
+	SetupClassroom(t)
+	eventsDS = events.NewDataSource(events.BuildSQLLiteConfig(test.DB_NAME))
+
+	AddTestClassroom(t, "A")
+	w := test.PerformRequestNoAuth(router, "GET", "/private/classroom", "")
+	var classrooms []model.Classroom
+	json.Unmarshal(w.Body.Bytes(), &classrooms)
+
+	// student joins the classroom, creates the luchador and visits a page
+	url := fmt.Sprintf("/private/join-classroom/%v", classrooms[0].AccessCode)
+	w = test.PerformRequest(router, "POST", url, "", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/luchador", "", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "POST", "/private/page-events", `{"page":"home"}`, "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return classrooms[0]
+}
+
+func TestClassroomReport(t *testing.T) {
+	classroom := SetupClassroomReport(t)
+	defer ds.DB.Close()
+
+	// finished tutorial match with a score for the student luchador
+	luchador := ds.FindLuchador(ds.CreateUser("alice"))
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestClassroomReport"
+	created := ds.CreateGameDefinition(&gd)
+	match := createMatch(0, created.ID, model.MatchStatusFinished)
+	ds.AddMatchParticipant(&model.MatchParticipant{MatchID: match.ID, LuchadorID: luchador.ID})
+	ds.AddMatchScores(&model.ScoreList{Scores: []model.MatchScore{
+		{MatchID: match.ID, LuchadorID: luchador.ID, Kills: 2, Score: 42},
+	}})
+
+	url := fmt.Sprintf("/dashboard/classroom/report/%v", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report model.ClassroomReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	assert.Equal(t, classroom.ID, report.ClassroomID)
+	assert.Equal(t, 1, len(report.Students))
+
+	student := report.Students[0]
+	assert.Equal(t, "alice", student.Username)
+	assert.Equal(t, uint(1), student.MatchesPlayed)
+	assert.Equal(t, uint(1), student.TutorialsCompleted)
+	assert.Equal(t, 1, len(student.RecentMatches))
+	assert.Equal(t, 42, student.RecentMatches[0].Score)
+	assert.Equal(t, "TestClassroomReport", student.RecentMatches[0].GameDefinitionName)
+	assert.NotNil(t, student.LastSeen)
+}
+
+func TestClassroomReportAllLuchadors(t *testing.T) {
+	classroom := SetupClassroomReport(t)
+	defer ds.DB.Close()
+
+	// the match is played by the second luchador, the default is the first one
+	user := ds.CreateUser("alice")
+	second := ds.CreateLuchador(&model.GameComponent{Name: "TestClassroomReportAllLuchadors", UserID: user.ID})
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestClassroomReportAllLuchadors"
+	created := ds.CreateGameDefinition(&gd)
+	match := createMatch(0, created.ID, model.MatchStatusFinished)
+	ds.AddMatchParticipant(&model.MatchParticipant{MatchID: match.ID, LuchadorID: second.ID})
+	ds.AddMatchScores(&model.ScoreList{Scores: []model.MatchScore{
+		{MatchID: match.ID, LuchadorID: second.ID, Score: 7},
+	}})
+
+	url := fmt.Sprintf("/dashboard/classroom/report/%v", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report model.ClassroomReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	student := report.Students[0]
+	assert.Equal(t, uint(1), student.MatchesPlayed)
+	assert.Equal(t, uint(1), student.TutorialsCompleted)
+	assert.Equal(t, 1, len(student.RecentMatches))
+	assert.Equal(t, 7, student.RecentMatches[0].Score)
+}
+
+func TestClassroomReportCSV(t *testing.T) {
+	classroom := SetupClassroomReport(t)
+	defer ds.DB.Close()
+
+	url := fmt.Sprintf("/dashboard/classroom/report/%v?format=csv", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "username", rows[0][2])
+	assert.Equal(t, "alice", rows[1][2])
+}
+
+func TestClassroomReportNotOwner(t *testing.T) {
+	classroom := SetupClassroomReport(t)
+	defer ds.DB.Close()
+
+	url := fmt.Sprintf("/dashboard/classroom/report/%v", classroom.ID)
+	w := test.PerformRequest(router, "GET", url, "", "alice")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
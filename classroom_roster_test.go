@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func SetupClassroomRoster(t *testing.T) model.Classroom {
+	SetupClassroom(t)
+
+	AddTestClassroom(t, "A")
+	w := test.PerformRequestNoAuth(router, "GET", "/private/classroom", "")
+	var classrooms []model.Classroom
+	json.Unmarshal(w.Body.Bytes(), &classrooms)
+
+	url := fmt.Sprintf("/private/join-classroom/%v", classrooms[0].AccessCode)
+	w = test.PerformRequest(router, "POST", url, "", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return classrooms[0]
+}
+
+func importRoster(t *testing.T, classroomID uint, roster string, dryRun bool) model.RosterImportResponse {
+	url := fmt.Sprintf("/dashboard/classroom/%v/roster?dryRun=%v", classroomID, dryRun)
+	w := test.PerformRequestNoAuth(router, "POST", url, roster)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result model.RosterImportResponse
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestClassroomRosterImport(t *testing.T) {
+	classroom := SetupClassroomRoster(t)
+	defer ds.DB.Close()
+
+	roster := "username\nBob\nalice\n\nno\ncarol,extra column\nbob\n"
+
+	result := importRoster(t, classroom.ID, roster, true)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"bob", "carol"}, result.Enrolled)
+	assert.Equal(t, []string{"alice"}, result.AlreadyEnrolled)
+	assert.Equal(t, 2, len(result.Invalid))
+	assert.Nil(t, ds.FindUserByUsername("bob"))
+
+	result = importRoster(t, classroom.ID, roster, false)
+	assert.False(t, result.DryRun)
+	assert.Equal(t, []string{"bob", "carol"}, result.Enrolled)
+	assert.NotNil(t, ds.FindUserByUsername("bob"))
+
+	students := ds.FindClassroomByID(classroom.ID).Students
+	assert.Equal(t, 3, len(students))
+
+	result = importRoster(t, classroom.ID, roster, false)
+	assert.Equal(t, 0, len(result.Enrolled))
+	assert.Equal(t, []string{"bob", "alice", "carol"}, result.AlreadyEnrolled)
+}
+
+func TestClassroomRosterImportNotOwner(t *testing.T) {
+	classroom := SetupClassroomRoster(t)
+	defer ds.DB.Close()
+
+	url := fmt.Sprintf("/dashboard/classroom/%v/roster", classroom.ID)
+	w := test.PerformRequest(router, "POST", url, "mallory", "alice")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, ds.FindUserByUsername("mallory"))
+}
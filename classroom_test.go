@@ -13,6 +13,7 @@ import (
 	"gitlab.com/robolucha/robolucha-api/auth"
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/storage"
 	"gitlab.com/robolucha/robolucha-api/test"
 )
 
@@ -33,6 +34,9 @@ func SetupClassroom(t *testing.T) {
 	mockPublisher = &test.MockPublisher{}
 	publisher = mockPublisher
 
+	os.RemoveAll(test.MEDIA_FOLDER)
+	mediaStore = storage.NewLocalStore(test.MEDIA_FOLDER, "/public"+storage.LocalRoute)
+
 	router = createRouter(test.API_KEY, "true", auth.SessionAllwaysValid, auth.SessionAllwaysValid)
 }
 
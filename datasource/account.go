@@ -0,0 +1,332 @@
+package datasource
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// BuildAccountExport definition, collects the data of the user stored in the main database,
+// page events are stored in the events database and must be added by the caller
+func (ds *DataSource) BuildAccountExport(user *model.User) *model.AccountExport {
+	result := model.AccountExport{
+		ExportedAt:            time.Now(),
+		User:                  *user,
+		Settings:              *ds.FindUserSettingByUser(user),
+		Level:                 *ds.FindUserLevelByUserID(user.ID),
+		LevelChanges:          ds.FindUserLevelChanges(user.ID),
+		Classrooms:            ds.FindAllClassroomByStudent(user.ID),
+		OwnedClassrooms:       *ds.FindAllClassroom(user),
+		Sessions:              make([]model.Session, 0),
+		AssignmentEvaluations: make([]model.AssignmentEvaluation, 0),
+		Luchadors:             make([]model.GameComponent, 0),
+		CodeHistory:           make([]model.CodeHistory, 0),
+		MatchScores:           make([]model.MatchScore, 0),
+		MatchEvents:           make([]model.MatchEvent, 0),
+		Media:                 make([]model.Media, 0),
+		ModerationFlags:       make([]model.ModerationFlag, 0),
+		ModerationReports:     make([]model.ModerationReport, 0),
+		PageEvents:            make([]model.PageEvent, 0),
+	}
+
+	ds.DB.Where(&model.Session{UserID: user.ID}).Order("id").Find(&result.Sessions)
+	ds.DB.Where(&model.Media{UserID: user.ID}).Order("id").Find(&result.Media)
+	result.DeletionRequests = ds.FindAccountDeletionRequestsByUser(user.ID)
+
+	ds.DB.Where(&model.ModerationFlag{UserID: user.ID}).Order("id").Find(&result.ModerationFlags)
+	ds.DB.Where("reporter_user_id = ? or reported_user_id = ?", user.ID, user.ID).Order("id").Find(&result.ModerationReports)
+	for n := range result.ModerationReports {
+		// the users reporting the content of the user are not exported
+		if result.ModerationReports[n].ReporterUserID != user.ID {
+			result.ModerationReports[n].ReporterUserID = 0
+		}
+	}
+
+	student := ds.FindStudentByUserID(user.ID)
+	if student != nil {
+		ds.DB.Preload("AssignmentGrades").
+			Where(&model.AssignmentEvaluation{StudentID: student.ID}).
+			Order("id").
+			Find(&result.AssignmentEvaluations)
+	}
+
+	// the deleted luchadors keep the match history of the user
+	ds.DB.Unscoped().Preload("Codes").Preload("Configs").
+		Where(&model.GameComponent{UserID: user.ID}).
+		Order("id").
+		Find(&result.Luchadors)
+
+	luchadorIDs := ds.findLuchadorIDs(user.ID)
+	if len(luchadorIDs) > 0 {
+		ds.DB.Where("code_id in (?)", ds.findLuchadorCodeIDs(luchadorIDs)).Order("id").Find(&result.CodeHistory)
+		ds.DB.Where("luchador_id in (?)", luchadorIDs).Order("id").Find(&result.MatchScores)
+		ds.DB.Where("luchador_id in (?)", luchadorIDs).Order("id").Find(&result.MatchEvents)
+	}
+
+	return &result
+}
+
+// the deleted luchadors are included
+func (ds *DataSource) findLuchadorIDs(userID uint) []uint {
+	result := make([]uint, 0)
+	ds.DB.Unscoped().Model(&model.GameComponent{}).Where("user_id = ?", userID).Pluck("id", &result)
+	return result
+}
+
+func (ds *DataSource) findLuchadorCodeIDs(luchadorIDs []uint) []uint {
+	result := make([]uint, 0)
+	ds.DB.Table("gamecomponent_codes").Where("game_component_id in (?)", luchadorIDs).Pluck("code_id", &result)
+	return result
+}
+
+// AddAccountDeletionRequest definition
+func (ds *DataSource) AddAccountDeletionRequest(request *model.AccountDeletionRequest) *model.AccountDeletionRequest {
+	ds.DB.Create(request)
+
+	log.WithFields(log.Fields{
+		"request": request,
+	}).Info("AddAccountDeletionRequest")
+
+	return request
+}
+
+// UpdateAccountDeletionRequest definition
+func (ds *DataSource) UpdateAccountDeletionRequest(request *model.AccountDeletionRequest) *model.AccountDeletionRequest {
+	ds.DB.Save(request)
+	return request
+}
+
+// FindAccountDeletionRequest definition
+func (ds *DataSource) FindAccountDeletionRequest(id uint) *model.AccountDeletionRequest {
+	var result model.AccountDeletionRequest
+	if ds.DB.First(&result, id).RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// FindAccountDeletionRequestsByUser definition
+func (ds *DataSource) FindAccountDeletionRequestsByUser(userID uint) []model.AccountDeletionRequest {
+	result := make([]model.AccountDeletionRequest, 0)
+	ds.DB.Where(&model.AccountDeletionRequest{UserID: userID}).Order("id").Find(&result)
+	return result
+}
+
+// FindPendingAccountDeletionRequest definition
+func (ds *DataSource) FindPendingAccountDeletionRequest(userID uint) *model.AccountDeletionRequest {
+	var result model.AccountDeletionRequest
+	filter := model.AccountDeletionRequest{UserID: userID, Status: model.ACCOUNT_DELETION_PENDING}
+	if ds.DB.Where(&filter).First(&result).RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// FindPendingAccountDeletionRequestsByOwner definition, pending requests of the students
+// enrolled in the classrooms of the owner
+func (ds *DataSource) FindPendingAccountDeletionRequestsByOwner(ownerID uint) []model.AccountDeletionRequest {
+	result := make([]model.AccountDeletionRequest, 0)
+
+	ds.DB.
+		Where("status = ?", model.ACCOUNT_DELETION_PENDING).
+		Where("user_id in (?)", ds.studentUserIDsByOwner(ownerID)).
+		Order("id").
+		Find(&result)
+
+	return result
+}
+
+// IsStudentOfOwner definition, the user is enrolled in a classroom of the owner
+func (ds *DataSource) IsStudentOfOwner(userID uint, ownerID uint) bool {
+	var count int
+	ds.studentsByOwner(ownerID).Where("students.user_id = ?", userID).Count(&count)
+	return count > 0
+}
+
+func (ds *DataSource) studentUserIDsByOwner(ownerID uint) []uint {
+	result := make([]uint, 0)
+	ds.studentsByOwner(ownerID).Pluck("students.user_id", &result)
+	return result
+}
+
+func (ds *DataSource) studentsByOwner(ownerID uint) *gorm.DB {
+	return ds.DB.Table("students").
+		Joins("join classroom_students on classroom_students.student_id = students.id").
+		Joins("join classrooms on classrooms.id = classroom_students.classroom_id").
+		Where("classrooms.owner_id = ? and classrooms.deleted_at is null", ownerID)
+}
+
+// IsEnrolledStudent definition, the user is enrolled in at least one classroom
+func (ds *DataSource) IsEnrolledStudent(userID uint) bool {
+	student := ds.FindStudentByUserID(userID)
+	if student == nil {
+		return false
+	}
+
+	var count int
+	ds.DB.Table("classroom_students").Where("student_id = ?", student.ID).Count(&count)
+	return count > 0
+}
+
+// FindAccountMediaKeys definition, the keys of the files of the media removed with the account,
+// the files must be deleted from the store after the account is deleted or anonymized
+func (ds *DataSource) FindAccountMediaKeys(userID uint) []string {
+	media := make([]model.Media, 0)
+	ds.DB.Preload("Renditions").Where(deletedMediaFilter, userID).Find(&media)
+
+	result := make([]string, 0)
+	for _, current := range media {
+		result = append(result, current.Key)
+		for _, rendition := range current.Renditions {
+			result = append(result, rendition.Key)
+		}
+	}
+	return result
+}
+
+// AnonymizeAccount definition, removes the personal data of the user keeping the gameplay
+// records used by the classroom reports, luchadors are renamed and code history is removed
+func (ds *DataSource) AnonymizeAccount(userID uint) error {
+	codeIDs := make([]uint, 0)
+	luchadorIDs := ds.findLuchadorIDs(userID)
+	if len(luchadorIDs) > 0 {
+		codeIDs = ds.findLuchadorCodeIDs(luchadorIDs)
+	}
+
+	tx := ds.DB.Begin()
+	steps := []*gorm.DB{
+		tx.Model(&model.User{ID: userID}).Updates(map[string]interface{}{
+			"username":           fmt.Sprintf("deleted-%v", userID),
+			"name":               "",
+			"first_name":         "",
+			"last_name":          "",
+			"email":              "",
+			"email_verified":     false,
+			"display_name":       "",
+			"avatar_media_id":    0,
+			"avatar_url":         "",
+			"profile_visibility": model.PROFILE_VISIBILITY_PRIVATE,
+		}),
+		tx.Model(&model.Session{}).Where("user_id = ? and revoked_at is null", userID).
+			UpdateColumn("revoked_at", time.Now()),
+		tx.Model(&model.Session{}).Where("user_id = ?", userID).
+			UpdateColumns(map[string]interface{}{"user_agent": "", "remote_addr": ""}),
+		tx.Exec("update game_components set name = "+concatID(tx)+" where user_id = ?", userID),
+		tx.Unscoped().Where("code_id in (?)", codeIDs).Delete(&model.CodeHistory{}),
+
+		// the moderation decisions are kept without the text written by the user
+		tx.Model(&model.ModerationFlag{}).Where("user_id = ?", userID).
+			UpdateColumns(map[string]interface{}{"text": "", "matches": ""}),
+		tx.Model(&model.ModerationReport{}).Where("reporter_user_id = ?", userID).
+			UpdateColumns(map[string]interface{}{"reporter_user_id": 0, "reason": ""}),
+	}
+
+	steps = append(steps, deleteMedia(tx, userID)...)
+	return finishAccountTransaction(tx, steps, "AnonymizeAccount", userID)
+}
+
+// DeleteAccount definition, removes the user and all the records linked to the user,
+// game definitions, classrooms and catalogue records created by the user are kept
+// and the sessions are kept revoked to reject the tokens already issued
+func (ds *DataSource) DeleteAccount(userID uint) error {
+	codeIDs := make([]uint, 0)
+	configIDs := make([]uint, 0)
+	luchadorIDs := ds.findLuchadorIDs(userID)
+	if len(luchadorIDs) > 0 {
+		codeIDs = ds.findLuchadorCodeIDs(luchadorIDs)
+		ds.DB.Table("gamecomponent_configs").Where("game_component_id in (?)", luchadorIDs).Pluck("config_id", &configIDs)
+	}
+
+	var studentID uint
+	evaluationIDs := make([]uint, 0)
+	student := ds.FindStudentByUserID(userID)
+	if student != nil {
+		studentID = student.ID
+		ds.DB.Model(&model.AssignmentEvaluation{}).Where("student_id = ?", studentID).Pluck("id", &evaluationIDs)
+	}
+
+	tx := ds.DB.Begin()
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("code_id in (?)", codeIDs).Delete(&model.CodeHistory{}),
+		tx.Unscoped().Where("id in (?)", codeIDs).Delete(&model.Code{}),
+		tx.Unscoped().Where("id in (?)", configIDs).Delete(&model.Config{}),
+		tx.Exec("delete from gamecomponent_codes where game_component_id in (?)", luchadorIDs),
+		tx.Exec("delete from gamecomponent_configs where game_component_id in (?)", luchadorIDs),
+		tx.Exec("delete from match_participants where game_component_id in (?)", luchadorIDs),
+		tx.Unscoped().Where("luchador_id in (?)", luchadorIDs).Delete(&model.TeamParticipant{}),
+		tx.Unscoped().Where("luchador_id in (?)", luchadorIDs).Delete(&model.MatchScore{}),
+		tx.Unscoped().Where("luchador_id in (?)", luchadorIDs).Delete(&model.MatchEvent{}),
+		tx.Unscoped().Where("id in (?)", luchadorIDs).Delete(&model.GameComponent{}),
+
+		tx.Unscoped().Where("assignment_evaluation_id in (?)", evaluationIDs).Delete(&model.AssignmentGrade{}),
+		tx.Unscoped().Where("id in (?)", evaluationIDs).Delete(&model.AssignmentEvaluation{}),
+		tx.Exec("delete from classroom_students where student_id = ?", studentID),
+		tx.Exec("delete from assignment_student where student_id = ?", studentID),
+		tx.Unscoped().Where("id = ?", studentID).Delete(&model.Student{}),
+	}
+
+	steps = append(steps, deleteMedia(tx, userID)...)
+	steps = append(steps,
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&model.UserSetting{}),
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&model.UserLevel{}),
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&model.UserLevelChange{}),
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&model.ModerationFlag{}),
+		tx.Unscoped().Where("reporter_user_id = ? or reported_user_id = ?", userID, userID).
+			Delete(&model.ModerationReport{}),
+		tx.Model(&model.Session{}).Where("user_id = ?", userID).UpdateColumns(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"user_agent":  "",
+			"remote_addr": "",
+		}),
+		tx.Model(&model.AccountDeletionRequest{}).Where("user_id = ?", userID).UpdateColumn("username", ""),
+		tx.Unscoped().Where("id = ?", userID).Delete(&model.User{}),
+	)
+
+	return finishAccountTransaction(tx, steps, "DeleteAccount", userID)
+}
+
+// the media removed with the account, media used by game definitions is kept without the owner
+const deletedMediaFilter = "user_id = ? and game_definition_id = 0 and narrative_definition_id = 0"
+
+func deleteMedia(tx *gorm.DB, userID uint) []*gorm.DB {
+	mediaIDs := make([]uint, 0)
+	tx.Model(&model.Media{}).Where(deletedMediaFilter, userID).Pluck("id", &mediaIDs)
+
+	return []*gorm.DB{
+		tx.Unscoped().Where("media_id in (?)", mediaIDs).Delete(&model.MediaRendition{}),
+		tx.Unscoped().Where(deletedMediaFilter, userID).Delete(&model.Media{}),
+		tx.Model(&model.Media{}).Where("user_id = ?", userID).UpdateColumn("user_id", 0),
+	}
+}
+
+// the sql concat syntax is different in sqlite and mysql
+func concatID(tx *gorm.DB) string {
+	if tx.Dialect().GetName() == "sqlite3" {
+		return "'deleted-' || id"
+	}
+	return "concat('deleted-', id)"
+}
+
+func finishAccountTransaction(tx *gorm.DB, steps []*gorm.DB, context string, userID uint) error {
+	for _, step := range steps {
+		if step.Error != nil {
+			tx.Rollback()
+			log.WithFields(log.Fields{
+				"userID": userID,
+				"error":  step.Error,
+			}).Error(context + " failed")
+			return step.Error
+		}
+	}
+
+	err := tx.Commit().Error
+	log.WithFields(log.Fields{
+		"userID": userID,
+		"error":  err,
+	}).Info(context)
+
+	return err
+}
@@ -0,0 +1,28 @@
+package datasource
+
+import (
+	"testing"
+
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gotest.tools/assert"
+)
+
+func TestFindPendingAccountDeletionRequestsByOwner(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	teacher := ds.CreateUser("teacher")
+	classroom := ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: teacher.ID})
+
+	for _, name := range []string{"maria", "joao", "ana"} {
+		user := ds.CreateUser(name)
+		ds.JoinClassroom(user, classroom.AccessCode)
+		ds.AddAccountDeletionRequest(&model.AccountDeletionRequest{
+			UserID: user.ID,
+			Status: model.ACCOUNT_DELETION_PENDING,
+		})
+	}
+
+	assert.Equal(t, 3, len(ds.FindPendingAccountDeletionRequestsByOwner(teacher.ID)))
+	assert.Equal(t, 0, len(ds.FindPendingAccountDeletionRequestsByOwner(99)))
+}
@@ -0,0 +1,118 @@
+package datasource
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// minimum time between updates of the key last used date
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey definition
+func (ds *DataSource) CreateAPIKey(key *model.APIKey) *model.APIKey {
+	ds.DB.Create(key)
+
+	log.WithFields(log.Fields{
+		"name":   key.Name,
+		"prefix": key.Prefix,
+		"scopes": key.Scopes,
+	}).Info("CreateAPIKey")
+
+	return key
+}
+
+// FindAllAPIKeys definition, revoked keys are included
+func (ds *DataSource) FindAllAPIKeys() []model.APIKey {
+	result := make([]model.APIKey, 0)
+	ds.DB.Order("name").Find(&result)
+	return result
+}
+
+// FindAPIKey definition
+func (ds *DataSource) FindAPIKey(id uint) *model.APIKey {
+	var key model.APIKey
+	if ds.DB.First(&key, id).RecordNotFound() {
+		return nil
+	}
+	return &key
+}
+
+// FindAPIKeyByName definition
+func (ds *DataSource) FindAPIKeyByName(name string) *model.APIKey {
+	var key model.APIKey
+	if ds.DB.Where(&model.APIKey{Name: name}).First(&key).RecordNotFound() {
+		return nil
+	}
+	return &key
+}
+
+// FindValidAPIKey definition, finds a not revoked and not expired key by the hash,
+// the previous hash of a rotated key is accepted until the grace period ends
+func (ds *DataSource) FindValidAPIKey(hash string, now time.Time) *model.APIKey {
+	if hash == "" {
+		return nil
+	}
+
+	var key model.APIKey
+	found := !ds.DB.Where("key_hash = ?", hash).First(&key).RecordNotFound()
+	if !found {
+		found = !ds.DB.
+			Where("previous_key_hash = ? and previous_expires_at > ?", hash, now).
+			First(&key).RecordNotFound()
+	}
+
+	if !found || key.RevokedAt != nil {
+		return nil
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil
+	}
+
+	return &key
+}
+
+// TouchAPIKey definition, records when the key was used
+func (ds *DataSource) TouchAPIKey(key *model.APIKey, now time.Time) {
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return
+	}
+
+	key.LastUsedAt = &now
+	ds.DB.Model(key).UpdateColumn("last_used_at", now)
+}
+
+// RotateAPIKey definition, replaces the key hash keeping the previous one valid during the grace period
+func (ds *DataSource) RotateAPIKey(key *model.APIKey, hash string, prefix string, grace time.Duration) *model.APIKey {
+	previousExpiresAt := time.Now().Add(grace)
+
+	key.PreviousKeyHash = key.KeyHash
+	key.PreviousExpiresAt = &previousExpiresAt
+	key.KeyHash = hash
+	key.Prefix = prefix
+	ds.DB.Save(key)
+
+	log.WithFields(log.Fields{
+		"name":              key.Name,
+		"prefix":            key.Prefix,
+		"previousExpiresAt": previousExpiresAt,
+	}).Info("RotateAPIKey")
+
+	return key
+}
+
+// RevokeAPIKey definition, the key is kept to show when it was revoked
+func (ds *DataSource) RevokeAPIKey(key *model.APIKey) *model.APIKey {
+	now := time.Now()
+	key.RevokedAt = &now
+	key.PreviousKeyHash = ""
+	ds.DB.Save(key)
+
+	log.WithFields(log.Fields{
+		"name":   key.Name,
+		"prefix": key.Prefix,
+	}).Info("RevokeAPIKey")
+
+	return key
+}
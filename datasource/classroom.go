@@ -2,6 +2,8 @@ package datasource
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -14,6 +16,8 @@ import (
 // TODO: remove this
 var accessCodeCounter int64
 
+var rosterUsername = regexp.MustCompile(`^[a-z0-9._@-]{3,40}$`)
+
 func (ds *DataSource) AddClassroom(c *model.Classroom) *model.Classroom {
 
 	now := fmt.Sprintf("%X", time.Now().Unix()+accessCodeCounter)
@@ -23,6 +27,7 @@ func (ds *DataSource) AddClassroom(c *model.Classroom) *model.Classroom {
 		Name:       c.Name,
 		OwnerID:    c.OwnerID,
 		AccessCode: now,
+		Locale:     c.Locale,
 	}
 
 	log.WithFields(log.Fields{
@@ -116,7 +121,6 @@ func (ds *DataSource) FindAllClassroomByStudent(studentUserID uint) []model.Clas
 // JoinClassroom definition
 func (ds *DataSource) JoinClassroom(user *model.User, accessCode string) *model.Classroom {
 	var result model.Classroom
-	student := model.Student{UserID: user.ID}
 
 	if ds.DB.Preload("Students").
 		Where(&model.Classroom{AccessCode: accessCode}).
@@ -130,25 +134,128 @@ func (ds *DataSource) JoinClassroom(user *model.User, accessCode string) *model.
 		return nil
 	}
 
+	student := ds.findOrCreateStudent(user.ID)
+
+	result.Students = append(result.Students, *student)
+	ds.DB.Save(&result)
+
+	log.WithFields(log.Fields{
+		"classroom": result,
+		"student":   student,
+	}).Info("joinClassroom")
+
+	return &result
+}
+
+// FindStudentByID definition
+func (ds *DataSource) FindStudentByID(id uint) *model.Student {
+	var student model.Student
+	if ds.DB.Where(&model.Student{ID: id}).First(&student).RecordNotFound() {
+		return nil
+	}
+	return &student
+}
+
+// FindStudentByUserID definition
+func (ds *DataSource) FindStudentByUserID(userID uint) *model.Student {
+	var student model.Student
+	if ds.DB.Where(&model.Student{UserID: userID}).First(&student).RecordNotFound() {
+		return nil
+	}
+	return &student
+}
+
+// StudentBelongsToOwner definition, checks if the student joined any classroom of the owner
+func (ds *DataSource) StudentBelongsToOwner(studentID uint, ownerID uint) bool {
+	var count int
+
+	ds.DB.Table("classroom_students").
+		Joins("join classrooms on classrooms.id = classroom_students.classroom_id").
+		Where("classroom_students.student_id = ? AND classrooms.owner_id = ?", studentID, ownerID).
+		Where("classrooms.deleted_at IS NULL").
+		Count(&count)
+
+	return count > 0
+}
+
+func (ds *DataSource) findOrCreateStudent(userID uint) *model.Student {
+	student := model.Student{UserID: userID}
+
 	if ds.DB.
 		Where(&student).
 		First(&student).
 		RecordNotFound() {
 
 		log.WithFields(log.Fields{
-			"userID": user.ID,
+			"userID": userID,
 		}).Info("student not found will create")
 
 		ds.DB.Create(&student)
 	}
 
-	result.Students = append(result.Students, student)
-	ds.DB.Save(&result)
+	return &student
+}
+
+func isEnrolled(classroom *model.Classroom, userID uint) bool {
+	for _, student := range classroom.Students {
+		if student.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportRoster definition, enrolls the list of usernames in the classroom creating
+// users and students when they dont exist, nothing is saved when dryRun is true
+func (ds *DataSource) ImportRoster(classroom *model.Classroom, usernames []string, dryRun bool) *model.RosterImportResponse {
+	result := model.RosterImportResponse{
+		DryRun:          dryRun,
+		Enrolled:        make([]string, 0),
+		AlreadyEnrolled: make([]string, 0),
+		Invalid:         make([]model.RosterImportError, 0),
+	}
+
+	found := make(map[string]bool)
+
+	for _, username := range usernames {
+		username = strings.ToLower(strings.TrimSpace(username))
+
+		if !rosterUsername.MatchString(username) {
+			result.Invalid = append(result.Invalid, model.RosterImportError{
+				Username: username,
+				Reason:   "Username should have 3 to 40 letters, numbers or . _ @ -",
+			})
+			continue
+		}
+
+		if found[username] {
+			result.Invalid = append(result.Invalid, model.RosterImportError{
+				Username: username,
+				Reason:   "Username is duplicated in the roster",
+			})
+			continue
+		}
+		found[username] = true
+
+		user := ds.FindUserByUsername(username)
+		if user != nil && isEnrolled(classroom, user.ID) {
+			result.AlreadyEnrolled = append(result.AlreadyEnrolled, username)
+			continue
+		}
+
+		if !dryRun {
+			user = ds.CreateUser(username)
+			student := ds.findOrCreateStudent(user.ID)
+			ds.DB.Model(classroom).Association("Students").Append(student)
+		}
+
+		result.Enrolled = append(result.Enrolled, username)
+	}
 
 	log.WithFields(log.Fields{
-		"classroom": result,
-		"student":   student,
-	}).Info("joinClassroom")
+		"classroomID": classroom.ID,
+		"result":      result,
+	}).Info("ImportRoster")
 
 	return &result
 }
@@ -157,12 +264,15 @@ func (ds *DataSource) FindPublicAvailableMatch() *[]model.AvailableMatch {
 	return ds.FindAvailableMatchByClassroomID(0)
 }
 
+// FindAvailableMatchByClassroomID definition, matches of hidden maps are not listed
 func (ds *DataSource) FindAvailableMatchByClassroomID(id uint) *[]model.AvailableMatch {
 
 	var result []model.AvailableMatch
 
 	ds.DB.
-		Where("classroom_id = ?", id).
+		Joins("left join game_definitions on game_definitions.id = available_matches.game_definition_id").
+		Where("available_matches.classroom_id = ?", id).
+		Where("game_definitions.id is null or game_definitions.hidden = ?", false).
 		Find(&result)
 
 	log.WithFields(log.Fields{
@@ -208,14 +318,16 @@ func (ds *DataSource) FindAvailableMatchOwnedByUser(ownerID uint, skipCheckOwner
 	return &result
 }
 
-// FindAvailableMatchJoinedByUser definition
+// FindAvailableMatchJoinedByUser definition, matches of hidden maps are not listed
 func (ds *DataSource) FindAvailableMatchJoinedByUser(studentID uint) *[]model.AvailableMatch {
 
 	var result []model.AvailableMatch
 
 	ds.DB.
 		Joins("join classroom_students on classroom_students.classroom_id = available_matches.classroom_id").
+		Joins("left join game_definitions on game_definitions.id = available_matches.game_definition_id").
 		Where("classroom_students.student_id = ? ", studentID).
+		Where("game_definitions.id is null or game_definitions.hidden = ?", false).
 		Find(&result)
 
 	log.WithFields(log.Fields{
@@ -238,9 +350,10 @@ func (ds *DataSource) BuildStudentResponse(students []model.Student) []model.Stu
 	for n, student := range students {
 		user := ds.FindUserByID(student.UserID)
 		result[n] = model.StudentResponse{
-			StudentID: student.ID,
-			UserID:    student.UserID,
-			Username:  user.Username,
+			StudentID:   student.ID,
+			UserID:      student.UserID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
 		}
 	}
 
@@ -277,6 +390,13 @@ func (ds *DataSource) FindLevelGroupByName(name string) *model.LevelGroup {
 	return &result
 }
 
+// FindAllGrades definition, sorted from the lowest grade
+func (ds *DataSource) FindAllGrades() []model.Grade {
+	result := make([]model.Grade, 0)
+	ds.DB.Order("lowest").Find(&result)
+	return result
+}
+
 // AddGrade defines add grade
 func (ds *DataSource) AddGrade(c *model.Grade) *model.Grade {
 
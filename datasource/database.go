@@ -108,6 +108,11 @@ func NewDataSource(config *DBconfig) *DataSource {
 	// Migrate the schema
 	DB.AutoMigrate(&model.User{})
 	DB.AutoMigrate(&model.Session{})
+	DB.AutoMigrate(&model.APIKey{})
+	DB.AutoMigrate(&model.AccountDeletionRequest{})
+	DB.AutoMigrate(&model.ModerationFlag{})
+	DB.AutoMigrate(&model.ModerationAllowedWord{})
+	DB.AutoMigrate(&model.ModerationReport{})
 	DB.AutoMigrate(&model.UserSetting{})
 	DB.AutoMigrate(&model.UserLevel{})
 
@@ -125,7 +130,12 @@ func NewDataSource(config *DBconfig) *DataSource {
 	DB.AutoMigrate(&model.Team{})
 	DB.AutoMigrate(&model.TeamDefinition{})
 	DB.AutoMigrate(&model.NarrativeDefinition{})
+	DB.AutoMigrate(&model.ProgressionRule{})
+	DB.AutoMigrate(&model.MatchEvent{})
+	DB.AutoMigrate(&model.UserLevelChange{})
 	DB.AutoMigrate(&model.Media{})
+	DB.AutoMigrate(&model.MediaRendition{})
+	DB.AutoMigrate(&model.MediaUpload{})
 	DB.AutoMigrate(&model.Classroom{})
 	DB.AutoMigrate(&model.Student{})
 	DB.AutoMigrate(&model.AvailableMatch{})
@@ -137,6 +147,8 @@ func NewDataSource(config *DBconfig) *DataSource {
 	DB.AutoMigrate(&model.LevelGroup{})
 	DB.AutoMigrate(&model.Activity{})
 	DB.AutoMigrate(&model.Assignment{})
+	DB.AutoMigrate(&model.AssignmentEvaluation{})
+	DB.AutoMigrate(&model.AssignmentGrade{})
 
 	secret := os.Getenv("API_SECRET")
 
@@ -185,6 +197,15 @@ func (ds *DataSource) FindUserByID(id uint) *model.User {
 	return &user
 }
 
+// FindUserByUsername definition
+func (ds *DataSource) FindUserByUsername(username string) *model.User {
+	var user model.User
+	if ds.DB.Where(&model.User{Username: username}).First(&user).RecordNotFound() {
+		return nil
+	}
+	return &user
+}
+
 // Create if doesnt exist
 func (ds *DataSource) FindUserSettingByUser(user *model.User) *model.UserSetting {
 	var settings model.UserSetting
@@ -270,9 +291,18 @@ func (ds *DataSource) CreateLuchador(l *model.GameComponent) *model.GameComponen
 	return &luchador
 }
 
+// FindLuchador definition, the default luchador of the user or the first luchador
+// created when the default is not set
 func (ds *DataSource) FindLuchador(user *model.User) *model.GameComponent {
+	var settings model.UserSetting
+	if !ds.DB.Where(&model.UserSetting{UserID: user.ID}).First(&settings).RecordNotFound() {
+		if luchador := ds.FindUserLuchador(user.ID, settings.DefaultLuchadorID); luchador != nil {
+			return luchador
+		}
+	}
+
 	var luchador model.GameComponent
-	if ds.DB.Preload("Codes").Preload("Configs").Where(&model.GameComponent{UserID: user.ID}).First(&luchador).RecordNotFound() {
+	if ds.DB.Preload("Codes").Preload("Configs").Where(&model.GameComponent{UserID: user.ID}).Order("id").First(&luchador).RecordNotFound() {
 		return nil
 	}
 
@@ -390,6 +420,7 @@ func (ds *DataSource) FindActiveMatches(query interface{}, args ...interface{})
 		Preload("GameDefinition.TeamDefinition").
 		Preload("GameDefinition.TeamDefinition.Teams").
 		Preload("GameDefinition.NarrativeDefinitions").
+		Preload("GameDefinition.ProgressionRules").
 		Preload("GameDefinition.NarrativeDefinitions.Media").
 		Preload("Participants").
 		Preload("TeamParticipants").
@@ -495,6 +526,7 @@ func (ds *DataSource) FindMatchPreload(id uint) *model.Match {
 		Preload("GameDefinition.TeamDefinition").
 		Preload("GameDefinition.TeamDefinition.Teams").
 		Preload("GameDefinition.NarrativeDefinitions").
+		Preload("GameDefinition.ProgressionRules").
 		Preload("GameDefinition.NarrativeDefinitions.Media").
 		Where(&model.Match{ID: id}).First(&match)
 
@@ -673,7 +705,8 @@ func (ds *DataSource) EndMatch(match *model.Match) *model.Match {
 	return match
 }
 
-// UpdateParticipantsLevel definition
+// UpdateParticipantsLevel definition, raises the participants level to the
+// UnblockLevel of the game definition when its progression rules are satisfied
 func (ds *DataSource) UpdateParticipantsLevel(matchID uint) {
 
 	// Load match with participants
@@ -686,17 +719,28 @@ func (ds *DataSource) UpdateParticipantsLevel(matchID uint) {
 	unblockLevel := match.GameDefinition.UnblockLevel
 	log.WithFields(log.Fields{
 		"match unblockLevel": unblockLevel,
+		"progressionRules":   match.GameDefinition.ProgressionRules,
 	}).Info("UpdateParticipantsLevel")
 
+	scores := ds.findFinalScores(match.ID)
+	winners := findWinners(match, scores)
+
 	for _, participant := range match.Participants {
+		// NPCs dont have users to level up
+		if participant.UserID == 0 {
+			continue
+		}
+
 		userLevel := ds.FindUserLevelByUserID(participant.UserID)
 		log.WithFields(log.Fields{
 			"userLevel": userLevel,
 		}).Info("UpdateParticipantsLevel")
 
-		if userLevel.Level < unblockLevel {
-			userLevel.Level = unblockLevel
-			updatedLevel := ds.UpdateUserLevel(userLevel)
+		if userLevel.Level < unblockLevel && ds.meetsProgressionRules(match, participant.ID, scores, winners) {
+			updatedLevel := ds.ChangeUserLevel(participant.UserID, unblockLevel, model.UserLevelChange{
+				Reason:  model.LEVEL_CHANGE_MATCH,
+				MatchID: match.ID,
+			})
 			log.WithFields(log.Fields{
 				"userLevel": updatedLevel,
 			}).Info("UpdateParticipantsLevel")
@@ -882,6 +926,18 @@ func (ds *DataSource) UpdateGameDefinition(input *model.GameDefinition) *model.G
 			return nil
 		}
 
+		ds.DB.Model(gameDefinition).Association("ProgressionRules").Replace(input.ProgressionRules)
+		dbc = ds.DB.Save(gameDefinition)
+		if dbc.Error != nil {
+			log.WithFields(log.Fields{
+				"error":               dbc.Error,
+				"gameDefinition.Name": gameDefinition.Name,
+				"step":                "ProgressionRules",
+			}).Error("Error updating updateGameDefinition")
+
+			return nil
+		}
+
 		ds.DB.Model(gameDefinition).Association("GameComponents").Replace(input.GameComponents)
 		dbc = ds.DB.Save(gameDefinition)
 		if dbc.Error != nil {
@@ -974,6 +1030,7 @@ func (ds *DataSource) FindGameDefinition(id uint) *model.GameDefinition {
 		Preload("TeamDefinition").
 		Preload("TeamDefinition.Teams").
 		Preload("NarrativeDefinitions").
+		Preload("ProgressionRules").
 		Preload("NarrativeDefinitions.Media").
 		Where(&model.GameDefinition{ID: id}).
 		First(&gameDefinition).
@@ -1001,6 +1058,19 @@ func (ds *DataSource) FindGameDefinition(id uint) *model.GameDefinition {
 	return &gameDefinition
 }
 
+// FindNarrativeDefinition definition
+func (ds *DataSource) FindNarrativeDefinition(id uint) *model.NarrativeDefinition {
+	var narrativeDefinition model.NarrativeDefinition
+	if ds.DB.Where("id = ?", id).First(&narrativeDefinition).RecordNotFound() {
+		log.WithFields(log.Fields{
+			"ID": id,
+		}).Info("findNarrativeDefinition not found")
+
+		return nil
+	}
+	return &narrativeDefinition
+}
+
 // FindGameDefinitionByName definition
 func (ds *DataSource) FindGameDefinitionByName(name string) *model.GameDefinition {
 	var gameDefinition model.GameDefinition
@@ -1017,6 +1087,7 @@ func (ds *DataSource) FindGameDefinitionByName(name string) *model.GameDefinitio
 		Preload("TeamDefinition").
 		Preload("TeamDefinition.Teams").
 		Preload("NarrativeDefinitions").
+		Preload("ProgressionRules").
 		Preload("NarrativeDefinitions.Media").
 		Where(&model.GameDefinition{Name: name}).
 		First(&gameDefinition).
@@ -1060,6 +1131,7 @@ func (ds *DataSource) FindAllSystemGameDefinition() *[]model.GameDefinition {
 		Preload("TeamDefinition").
 		Preload("TeamDefinition.Teams").
 		Preload("NarrativeDefinitions").
+		Preload("ProgressionRules").
 		Preload("NarrativeDefinitions.Media").
 		Where(&model.GameDefinition{OwnerUserID: 0}).
 		Order("sort_order").
@@ -1095,6 +1167,7 @@ func (ds *DataSource) FindTutorialGameDefinition() *[]model.GameDefinition {
 		Preload("TeamDefinition").
 		Preload("TeamDefinition.Teams").
 		Preload("NarrativeDefinitions").
+		Preload("ProgressionRules").
 		Preload("NarrativeDefinitions.Media").
 		Where(&model.GameDefinition{Type: model.GAMEDEFINITION_TYPE_TUTORIAL}).
 		Order("sort_order").
@@ -1130,6 +1203,7 @@ func (ds *DataSource) FindGameDefinitionByOwner(ownerID uint) *[]model.GameDefin
 		Preload("TeamDefinition").
 		Preload("TeamDefinition.Teams").
 		Preload("NarrativeDefinitions").
+		Preload("ProgressionRules").
 		Preload("NarrativeDefinitions.Media").
 		Where(&model.GameDefinition{OwnerUserID: ownerID}).
 		Order("name").
@@ -13,7 +13,7 @@ func (ds *DataSource) FindAllActivities() *[]model.Activity {
 	var result []model.Activity
 
 	ds.DB.
-		Preload("Skill").
+		Preload("Skills").
 		Find(&result)
 
 	log.WithFields(log.Fields{
@@ -27,7 +27,8 @@ func (ds *DataSource) FindAllActivities() *[]model.Activity {
 func (ds *DataSource) FindLearningObjectiveByName(name string) *model.LearningObjective {
 	var result model.LearningObjective
 
-	if ds.DB.Where(&model.LearningObjective{Name: name}).Find(&result).RecordNotFound() {
+	// the unique index keeps the names of the deleted records
+	if ds.DB.Unscoped().Where(&model.LearningObjective{Name: name}).Find(&result).RecordNotFound() {
 		return nil
 	}
 
@@ -42,15 +43,22 @@ func (ds *DataSource) FindLearningObjectiveByName(name string) *model.LearningOb
 func (ds *DataSource) AddLearningObjective(c *model.LearningObjective) *model.LearningObjective {
 
 	objective := model.LearningObjective{
-		Name:   c.Name,
-		Skills: c.Skills,
+		Name:        c.Name,
+		Skills:      c.Skills,
+		OwnerUserID: c.OwnerUserID,
 	}
 
 	log.WithFields(log.Fields{
 		"objective": objective,
 	}).Debug("AddLearningObjective")
 
-	ds.DB.Create(&objective)
+	if err := ds.DB.Create(&objective).Error; err != nil {
+		log.WithFields(log.Fields{
+			"objective": objective,
+			"error":     err,
+		}).Error("Error on AddLearningObjective")
+		return nil
+	}
 
 	log.WithFields(log.Fields{
 		"objective": objective,
@@ -60,6 +68,234 @@ func (ds *DataSource) AddLearningObjective(c *model.LearningObjective) *model.Le
 }
 
 
+// FindAllLearningObjectives definition
+func (ds *DataSource) FindAllLearningObjectives() *[]model.LearningObjective {
+	var result []model.LearningObjective
+
+	ds.DB.
+		Preload("Skills").
+		Find(&result)
+
+	log.WithFields(log.Fields{
+		"objectives": result,
+	}).Debug("FindAllLearningObjectives")
+
+	return &result
+}
+
+// FindLearningObjective definition
+func (ds *DataSource) FindLearningObjective(id uint) *model.LearningObjective {
+	var result model.LearningObjective
+
+	if ds.DB.
+		Preload("Skills").
+		Where(&model.LearningObjective{ID: id}).
+		First(&result).
+		RecordNotFound() {
+		return nil
+	}
+
+	return &result
+}
+
+// UpdateLearningObjective definition, replaces the list of skills
+func (ds *DataSource) UpdateLearningObjective(objective *model.LearningObjective) *model.LearningObjective {
+	current := ds.FindLearningObjective(objective.ID)
+	current.Name = objective.Name
+	ds.DB.Save(current)
+	ds.DB.Model(current).Association("Skills").Replace(objective.Skills)
+
+	log.WithFields(log.Fields{
+		"objective": current,
+	}).Debug("UpdateLearningObjective")
+
+	return ds.FindLearningObjective(objective.ID)
+}
+
+// DeleteLearningObjective definition
+func (ds *DataSource) DeleteLearningObjective(id uint) {
+	objective := model.LearningObjective{ID: id}
+	ds.DB.Model(&objective).Association("Skills").Clear()
+	ds.DB.Delete(&objective)
+
+	log.WithFields(log.Fields{
+		"objective.id": id,
+	}).Debug("DeleteLearningObjective")
+}
+
+// FindAllSkills definition
+func (ds *DataSource) FindAllSkills() *[]model.Skill {
+	var result []model.Skill
+
+	ds.DB.Find(&result)
+
+	log.WithFields(log.Fields{
+		"skills": result,
+	}).Debug("FindAllSkills")
+
+	return &result
+}
+
+// FindSkill definition
+func (ds *DataSource) FindSkill(id uint) *model.Skill {
+	var result model.Skill
+
+	if ds.DB.Where(&model.Skill{ID: id}).First(&result).RecordNotFound() {
+		return nil
+	}
+
+	return &result
+}
+
+// FindSkillByName definition
+func (ds *DataSource) FindSkillByName(name string) *model.Skill {
+	var result model.Skill
+
+	// the unique index keeps the names of the deleted records
+	if ds.DB.Unscoped().Where(&model.Skill{Name: name}).First(&result).RecordNotFound() {
+		return nil
+	}
+
+	return &result
+}
+
+// AddSkill definition
+func (ds *DataSource) AddSkill(s *model.Skill) *model.Skill {
+	skill := model.Skill{
+		Name:        s.Name,
+		Description: s.Description,
+		OwnerUserID: s.OwnerUserID,
+	}
+
+	if err := ds.DB.Create(&skill).Error; err != nil {
+		log.WithFields(log.Fields{
+			"skill": skill,
+			"error": err,
+		}).Error("Error on AddSkill")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"skill": skill,
+	}).Debug("AddSkill")
+
+	return &skill
+}
+
+// UpdateSkill definition
+func (ds *DataSource) UpdateSkill(s *model.Skill) *model.Skill {
+	skill := ds.FindSkill(s.ID)
+	skill.Name = s.Name
+	skill.Description = s.Description
+	ds.DB.Save(skill)
+
+	log.WithFields(log.Fields{
+		"skill": skill,
+	}).Debug("UpdateSkill")
+
+	return skill
+}
+
+// SkillInUse definition, checks if any learning objective, activity or grade uses the skill
+func (ds *DataSource) SkillInUse(id uint) bool {
+	var objectives, activities, grades int
+
+	ds.DB.Table("learningobjective_skills").Where("skill_id = ?", id).Count(&objectives)
+	ds.DB.Table("activitiy_skills").Where("skill_id = ?", id).Count(&activities)
+	ds.DB.Model(&model.AssignmentGrade{}).Where(&model.AssignmentGrade{SkillID: id}).Count(&grades)
+
+	return objectives+activities+grades > 0
+}
+
+// DeleteSkill definition
+func (ds *DataSource) DeleteSkill(id uint) {
+	ds.DB.Delete(&model.Skill{ID: id})
+
+	log.WithFields(log.Fields{
+		"skill.id": id,
+	}).Debug("DeleteSkill")
+}
+
+// FindActivity definition
+func (ds *DataSource) FindActivity(id uint) *model.Activity {
+	var result model.Activity
+
+	if ds.DB.
+		Preload("Skills").
+		Where(&model.Activity{ID: id}).
+		First(&result).
+		RecordNotFound() {
+		return nil
+	}
+
+	return &result
+}
+
+// AddActivity definition
+func (ds *DataSource) AddActivity(a *model.Activity) *model.Activity {
+	activity := model.Activity{
+		Name:             a.Name,
+		Description:      a.Description,
+		Skills:           a.Skills,
+		GameDefinitionID: a.GameDefinitionID,
+		SourceURL:        a.SourceURL,
+		SourceName:       a.SourceName,
+		OwnerUserID:      a.OwnerUserID,
+	}
+
+	if err := ds.DB.Create(&activity).Error; err != nil {
+		log.WithFields(log.Fields{
+			"activity": activity,
+			"error":    err,
+		}).Error("Error on AddActivity")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"activity": activity,
+	}).Debug("AddActivity")
+
+	return ds.FindActivity(activity.ID)
+}
+
+// UpdateActivity definition, replaces the list of skills
+func (ds *DataSource) UpdateActivity(a *model.Activity) *model.Activity {
+	activity := ds.FindActivity(a.ID)
+	activity.Name = a.Name
+	activity.Description = a.Description
+	activity.GameDefinitionID = a.GameDefinitionID
+	activity.SourceURL = a.SourceURL
+	activity.SourceName = a.SourceName
+	ds.DB.Save(activity)
+	ds.DB.Model(activity).Association("Skills").Replace(a.Skills)
+
+	log.WithFields(log.Fields{
+		"activity": activity,
+	}).Debug("UpdateActivity")
+
+	return ds.FindActivity(a.ID)
+}
+
+// ActivityInUse definition, checks if any assignment not deleted uses the activity
+func (ds *DataSource) ActivityInUse(id uint) bool {
+	var count int
+	ds.DB.Model(&model.Assignment{}).
+		Joins("join assignment_activity on assignment_activity.assignment_id = assignments.id").
+		Where("assignment_activity.activity_id = ?", id).
+		Count(&count)
+	return count > 0
+}
+
+// DeleteActivity definition
+func (ds *DataSource) DeleteActivity(id uint) {
+	activity := model.Activity{ID: id}
+	ds.DB.Model(&activity).Association("Skills").Clear()
+	ds.DB.Delete(&activity)
+
+	log.WithFields(log.Fields{
+		"activity.id": id,
+	}).Debug("DeleteActivity")
+}
 
 // FindAllAssignment definition
 func (ds *DataSource) FindAllAssignments() *[]model.Assignment {
@@ -97,8 +333,10 @@ func (ds *DataSource) FindAssignmentById(id uint) *model.Assignment {
 
 func (ds *DataSource) AddAssignment(assignment *model.Assignment) *model.Assignment {
 	newAssignment := model.Assignment{
-		TimeStart: assignment.TimeStart,
-		TimeEnd:   assignment.TimeEnd,
+		Name:        assignment.Name,
+		Description: assignment.Description,
+		TimeStart:   assignment.TimeStart,
+		TimeEnd:     assignment.TimeEnd,
 	}
 
 	ds.DB.Create(&newAssignment)
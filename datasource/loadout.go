@@ -0,0 +1,66 @@
+package datasource
+
+import (
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// FindGameDefinitionNames definition, the names of the game definitions by id
+func (ds *DataSource) FindGameDefinitionNames(ids []uint) map[uint]string {
+	result := make(map[uint]string)
+	if len(ids) == 0 {
+		return result
+	}
+
+	var gameDefinitions []model.GameDefinition
+	ds.DB.Select("id, name").Where("id in (?)", ids).Find(&gameDefinitions)
+	for _, gameDefinition := range gameDefinitions {
+		result[gameDefinition.ID] = gameDefinition.Name
+	}
+	return result
+}
+
+// ReplaceLoadout definition, the codes of the luchador for the game definition are replaced
+// by the scripts of the codes, events without code use the default codes again
+func (ds *DataSource) ReplaceLoadout(luchador *model.GameComponent, gameDefinitionID uint, codes []model.Code) *model.GameComponent {
+	updated := make([]model.Code, 0, len(codes))
+	events := make(map[string]bool)
+	for _, code := range codes {
+		updated = append(updated, model.Code{
+			Event:            code.Event,
+			Script:           code.Script,
+			Blockly:          code.Blockly,
+			GameDefinitionID: gameDefinitionID,
+		})
+		events[code.Event] = true
+	}
+
+	current := make([]model.Code, 0, len(luchador.Codes))
+	removed := make([]string, 0)
+	for _, code := range luchador.Codes {
+		if code.GameDefinitionID == gameDefinitionID && !events[code.Event] {
+			removed = append(removed, code.Event)
+		} else {
+			current = append(current, code)
+		}
+	}
+
+	// older versions of the removed codes are also linked to the luchador
+	if len(removed) > 0 {
+		ds.DB.Exec("delete from gamecomponent_codes where game_component_id = ? and code_id in "+
+			"(select id from codes where game_definition_id = ? and event in (?))",
+			luchador.ID, gameDefinitionID, removed)
+	}
+
+	luchador.Codes = applyCodeChanges(current, updated)
+	ds.DB.Save(luchador)
+
+	log.WithFields(log.Fields{
+		"luchadorID":       luchador.ID,
+		"gameDefinitionID": gameDefinitionID,
+		"codes":            len(updated),
+		"removed":          removed,
+	}).Info("ReplaceLoadout")
+
+	return ds.FindLuchadorByID(luchador.ID)
+}
@@ -0,0 +1,75 @@
+package datasource
+
+import (
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// FindLuchadorsByUser definition, the luchadors of the user with the configs, by creation
+func (ds *DataSource) FindLuchadorsByUser(userID uint) []model.GameComponent {
+	result := make([]model.GameComponent, 0)
+	if userID == 0 {
+		return result
+	}
+
+	ds.DB.Preload("Configs").Where("user_id = ?", userID).Order("id").Find(&result)
+
+	log.WithFields(log.Fields{
+		"userID":    userID,
+		"luchadors": len(result),
+	}).Debug("FindLuchadorsByUser")
+
+	return result
+}
+
+// CountLuchadorsByUser definition
+func (ds *DataSource) CountLuchadorsByUser(userID uint) int {
+	var count int
+	ds.DB.Model(&model.GameComponent{}).Where("user_id = ?", userID).Count(&count)
+	return count
+}
+
+// FindUserLuchador definition, nil when the luchador is not found or owned by other user
+func (ds *DataSource) FindUserLuchador(userID uint, luchadorID uint) *model.GameComponent {
+	if userID == 0 || luchadorID == 0 {
+		return nil
+	}
+
+	var luchador model.GameComponent
+	if ds.DB.Preload("Codes").Preload("Configs").Where("id = ? and user_id = ?", luchadorID, userID).First(&luchador).RecordNotFound() {
+		return nil
+	}
+
+	luchador.Codes = removeDuplicates(luchador.Codes)
+	return &luchador
+}
+
+// SetDefaultLuchador definition, the luchador used when the user doesn't choose one
+func (ds *DataSource) SetDefaultLuchador(user *model.User, luchadorID uint) *model.UserSetting {
+	settings := ds.FindUserSettingByUser(user)
+	ds.DB.Model(settings).UpdateColumn("default_luchador_id", luchadorID)
+	settings.DefaultLuchadorID = luchadorID
+
+	log.WithFields(log.Fields{
+		"userID":     user.ID,
+		"luchadorID": luchadorID,
+	}).Info("Default luchador updated")
+
+	return settings
+}
+
+// DeleteLuchador definition, the name is released for new luchadors and the match
+// history is kept with the deleted luchador
+func (ds *DataSource) DeleteLuchador(luchador *model.GameComponent) error {
+	tx := ds.DB.Begin()
+	steps := []*gorm.DB{
+		tx.Exec("update game_components set name = "+concatID(tx)+" where id = ?", luchador.ID),
+		tx.Where("id = ?", luchador.ID).Delete(&model.GameComponent{}),
+		tx.Model(&model.UserSetting{}).
+			Where("user_id = ? and default_luchador_id = ?", luchador.UserID, luchador.ID).
+			UpdateColumn("default_luchador_id", 0),
+	}
+
+	return finishAccountTransaction(tx, steps, "DeleteLuchador", luchador.UserID)
+}
@@ -0,0 +1,189 @@
+package datasource
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// fraction of the grading scale a skill score should reach to be mastered
+const masteryThreshold = 0.5
+
+// weight of the newest evidence when combining the evidences of a skill,
+// older evidences fade as new grades or completed game definitions arrive
+const masteryRecencyWeight = 0.5
+
+// grading scale used when no grade is configured
+const masteryDefaultScale = 100
+
+// skillEvidence is a grade or a completed game definition for one skill
+type skillEvidence struct {
+	SkillID uint
+	Value   float32
+	Time    time.Time
+}
+
+// BuildStudentMastery definition, combines the assignment grades of the student
+// and the completed game definitions linked to activities for each skill
+func (ds *DataSource) BuildStudentMastery(user *model.User) *model.StudentMastery {
+	result := model.StudentMastery{
+		UserID:             user.ID,
+		Skills:             make([]model.SkillMastery, 0),
+		LearningObjectives: make([]model.LearningObjectiveMastery, 0),
+		Gaps:               make([]model.SkillMastery, 0),
+	}
+
+	grades := ds.FindAllGrades()
+	scale := float32(masteryDefaultScale)
+	if len(grades) > 0 {
+		scale = grades[len(grades)-1].Highest
+	}
+
+	evidences := make([]skillEvidence, 0)
+
+	student := ds.FindStudentByUserID(user.ID)
+	if student != nil {
+		result.StudentID = student.ID
+		evidences = append(evidences, ds.findGradeEvidences(student.ID)...)
+	}
+
+	luchadorIDs := ds.findLuchadorIDs(user.ID)
+	if len(luchadorIDs) > 0 {
+		evidences = append(evidences, ds.findCompletionEvidences(luchadorIDs, scale)...)
+	}
+
+	sort.SliceStable(evidences, func(i, j int) bool {
+		return evidences[i].Time.Before(evidences[j].Time)
+	})
+
+	bySkill := make(map[uint]model.SkillMastery)
+	for _, skill := range *ds.FindAllSkills() {
+		mastery := model.SkillMastery{SkillID: skill.ID, Name: skill.Name}
+		for _, evidence := range evidences {
+			if evidence.SkillID != skill.ID {
+				continue
+			}
+
+			if mastery.Evidences == 0 {
+				mastery.Score = evidence.Value
+			} else {
+				mastery.Score = masteryRecencyWeight*evidence.Value + (1-masteryRecencyWeight)*mastery.Score
+			}
+
+			mastery.Evidences++
+			last := evidence.Time
+			mastery.LastEvidence = &last
+		}
+
+		mastery.Grade = findGradeForScore(grades, mastery.Score)
+		mastery.Mastered = mastery.Evidences > 0 && mastery.Score >= scale*masteryThreshold
+
+		bySkill[skill.ID] = mastery
+		result.Skills = append(result.Skills, mastery)
+		if !mastery.Mastered {
+			result.Gaps = append(result.Gaps, mastery)
+		}
+	}
+
+	sort.SliceStable(result.Gaps, func(i, j int) bool {
+		return result.Gaps[i].Score < result.Gaps[j].Score
+	})
+
+	for _, objective := range *ds.FindAllLearningObjectives() {
+		mastery := model.LearningObjectiveMastery{
+			LearningObjectiveID: objective.ID,
+			Name:                objective.Name,
+			TotalSkills:         uint(len(objective.Skills)),
+		}
+
+		for _, skill := range objective.Skills {
+			mastery.Score += bySkill[skill.ID].Score
+			if bySkill[skill.ID].Mastered {
+				mastery.MasteredSkills++
+			}
+		}
+
+		if mastery.TotalSkills > 0 {
+			mastery.Score = mastery.Score / float32(mastery.TotalSkills)
+		}
+
+		mastery.Grade = findGradeForScore(grades, mastery.Score)
+		result.LearningObjectives = append(result.LearningObjectives, mastery)
+	}
+
+	log.WithFields(log.Fields{
+		"userID":    user.ID,
+		"evidences": len(evidences),
+		"gaps":      len(result.Gaps),
+	}).Debug("BuildStudentMastery")
+
+	return &result
+}
+
+// finds the grade where the score fits, scores between two grades use the lowest one
+func findGradeForScore(grades []model.Grade, score float32) *model.Grade {
+	var result *model.Grade
+	for n := range grades {
+		if score >= grades[n].Lowest {
+			result = &grades[n]
+		}
+	}
+	return result
+}
+
+// the grades of the student in the order they were given, the evidences are
+// sorted by time so grades given at the same time keep the order of their ids
+func (ds *DataSource) findGradeEvidences(studentID uint) []skillEvidence {
+	result := make([]skillEvidence, 0)
+
+	ds.DB.Table("assignment_grades").
+		Select("assignment_grades.skill_id, assignment_grades.grade as value, assignment_grades.created_at as time").
+		Joins("join assignment_evaluations on assignment_evaluations.id = assignment_grades.assignment_evaluation_id").
+		Where("assignment_evaluations.student_id = ?", studentID).
+		Where("assignment_grades.deleted_at IS NULL AND assignment_evaluations.deleted_at IS NULL").
+		Order("assignment_grades.created_at, assignment_grades.id").
+		Scan(&result)
+
+	return result
+}
+
+// each game definition completed by any of the luchadors counts once for every
+// skill of the activities using it, with the highest value of the scale
+func (ds *DataSource) findCompletionEvidences(luchadorIDs []uint, scale float32) []skillEvidence {
+	type completion struct {
+		SkillID          uint
+		GameDefinitionID uint
+		Time             time.Time
+	}
+
+	completions := make([]completion, 0)
+
+	ds.DB.Table("matches").
+		Select("activitiy_skills.skill_id, matches.game_definition_id, matches.time_end as time").
+		Joins("join match_participants on match_participants.match_id = matches.id").
+		Joins("join activities on activities.game_definition_id = matches.game_definition_id").
+		Joins("join activitiy_skills on activitiy_skills.activity_id = activities.id").
+		Where("match_participants.game_component_id in (?)", luchadorIDs).
+		Where("matches.status = ?", model.MatchStatusFinished).
+		Where("matches.deleted_at IS NULL AND activities.deleted_at IS NULL").
+		Scan(&completions)
+
+	// keep the most recent completion of each game definition by skill
+	type key struct{ skillID, gameDefinitionID uint }
+	latest := make(map[key]time.Time)
+	for _, c := range completions {
+		k := key{c.SkillID, c.GameDefinitionID}
+		if current, found := latest[k]; !found || c.Time.After(current) {
+			latest[k] = c.Time
+		}
+	}
+
+	result := make([]skillEvidence, 0)
+	for k, t := range latest {
+		result = append(result, skillEvidence{SkillID: k.skillID, Value: scale, Time: t})
+	}
+
+	return result
+}
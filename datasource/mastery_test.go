@@ -0,0 +1,116 @@
+package datasource
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gotest.tools/assert"
+)
+
+func TestBuildStudentMastery(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	ds.AddGrade(&model.Grade{Name: "Beginner", Lowest: 0, Highest: 10})
+	ds.AddGrade(&model.Grade{Name: "Advanced", Lowest: 11, Highest: 20})
+
+	loops := ds.AddSkill(&model.Skill{Name: "Loops"})
+	events := ds.AddSkill(&model.Skill{Name: "Events"})
+	variables := ds.AddSkill(&model.Skill{Name: "Variables"})
+	ds.AddLearningObjective(&model.LearningObjective{
+		Name:   "Coding",
+		Skills: []model.Skill{*loops, *events},
+	})
+
+	user := ds.CreateUser("student")
+	ds.JoinClassroom(user, ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: 99}).AccessCode)
+	student := ds.FindStudentByUserID(user.ID)
+
+	// older low grade followed by a recent high grade for loops
+	evaluation := model.AssignmentEvaluation{StudentID: student.ID}
+	ds.DB.Create(&evaluation)
+	ds.DB.Create(&model.AssignmentGrade{AssignmentEvaluationID: evaluation.ID, SkillID: loops.ID, Grade: 4})
+	ds.DB.Create(&model.AssignmentGrade{AssignmentEvaluationID: evaluation.ID, SkillID: loops.ID, Grade: 20})
+
+	// finished match of a game definition used by an activity with events
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestBuildStudentMastery"
+	created := ds.CreateGameDefinition(&gd)
+	ds.AddActivity(&model.Activity{Name: "Events", GameDefinitionID: created.ID, Skills: []model.Skill{*events}})
+
+	luchador := ds.CreateLuchador(&model.GameComponent{Name: "student", UserID: user.ID})
+	match := model.Match{GameDefinitionID: created.ID, Status: model.MatchStatusFinished, TimeEnd: time.Now()}
+	ds.DB.Create(&match)
+	ds.AddMatchParticipant(&model.MatchParticipant{MatchID: match.ID, LuchadorID: luchador.ID})
+
+	mastery := ds.BuildStudentMastery(user)
+	assert.Equal(t, student.ID, mastery.StudentID)
+	assert.Equal(t, 3, len(mastery.Skills))
+
+	assert.Equal(t, float32(12), mastery.Skills[0].Score)
+	assert.Equal(t, uint(2), mastery.Skills[0].Evidences)
+	assert.Equal(t, "Advanced", mastery.Skills[0].Grade.Name)
+	assert.Assert(t, mastery.Skills[0].Mastered)
+
+	assert.Equal(t, float32(20), mastery.Skills[1].Score)
+	assert.Equal(t, uint(1), mastery.Skills[1].Evidences)
+	assert.Assert(t, mastery.Skills[1].Mastered)
+
+	assert.Equal(t, 1, len(mastery.Gaps))
+	assert.Equal(t, variables.ID, mastery.Gaps[0].SkillID)
+	assert.Equal(t, "Beginner", mastery.Gaps[0].Grade.Name)
+
+	assert.Equal(t, 1, len(mastery.LearningObjectives))
+	assert.Equal(t, float32(16), mastery.LearningObjectives[0].Score)
+	assert.Equal(t, uint(2), mastery.LearningObjectives[0].MasteredSkills)
+}
+
+func TestBuildStudentMasterySameTimeGrades(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	ds.AddGrade(&model.Grade{Name: "Beginner", Lowest: 0, Highest: 20})
+	loops := ds.AddSkill(&model.Skill{Name: "Loops"})
+
+	user := ds.CreateUser("student")
+	ds.JoinClassroom(user, ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: 99}).AccessCode)
+	student := ds.FindStudentByUserID(user.ID)
+
+	// grades saved at the same time are applied in the order they were created
+	now := time.Now()
+	evaluation := model.AssignmentEvaluation{StudentID: student.ID}
+	ds.DB.Create(&evaluation)
+	ds.DB.Create(&model.AssignmentGrade{AssignmentEvaluationID: evaluation.ID, SkillID: loops.ID, Grade: 4, CreatedAt: now})
+	ds.DB.Create(&model.AssignmentGrade{AssignmentEvaluationID: evaluation.ID, SkillID: loops.ID, Grade: 20, CreatedAt: now})
+
+	for i := 0; i < 5; i++ {
+		mastery := ds.BuildStudentMastery(user)
+		assert.Equal(t, float32(12), mastery.Skills[0].Score)
+	}
+}
+
+func TestBuildStudentMasteryAllLuchadors(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	ds.AddGrade(&model.Grade{Name: "Beginner", Lowest: 0, Highest: 20})
+	events := ds.AddSkill(&model.Skill{Name: "Events"})
+
+	user := ds.CreateUser("student")
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestBuildStudentMasteryAllLuchadors"
+	created := ds.CreateGameDefinition(&gd)
+	ds.AddActivity(&model.Activity{Name: "Events", GameDefinitionID: created.ID, Skills: []model.Skill{*events}})
+
+	// the match is played by the second luchador, the default is the first one
+	ds.CreateLuchador(&model.GameComponent{Name: "first", UserID: user.ID})
+	second := ds.CreateLuchador(&model.GameComponent{Name: "second", UserID: user.ID})
+	match := model.Match{GameDefinitionID: created.ID, Status: model.MatchStatusFinished, TimeEnd: time.Now()}
+	ds.DB.Create(&match)
+	ds.AddMatchParticipant(&model.MatchParticipant{MatchID: match.ID, LuchadorID: second.ID})
+
+	mastery := ds.BuildStudentMastery(user)
+	assert.Equal(t, uint(1), mastery.Skills[0].Evidences)
+	assert.Assert(t, mastery.Skills[0].Mastered)
+}
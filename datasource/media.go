@@ -0,0 +1,315 @@
+package datasource
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// AddMedia definition, the renditions are saved with the media
+func (ds *DataSource) AddMedia(media *model.Media) *model.Media {
+	ds.DB.Create(media)
+
+	log.WithFields(log.Fields{
+		"id":         media.ID,
+		"userID":     media.UserID,
+		"size":       media.Size,
+		"renditions": len(media.Renditions),
+	}).Info("AddMedia")
+
+	return media
+}
+
+// FindMediaByHash definition, the media in the library of the user with the same content
+func (ds *DataSource) FindMediaByHash(userID uint, hash string) *model.Media {
+	var result model.Media
+	if ds.DB.Preload("Renditions").
+		Where("user_id = ? and hash = ? and source_media_id = 0", userID, hash).
+		First(&result).
+		RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// FindMediaUsageByUser definition, bytes used by the media and the renditions of the user,
+// the attached copies share the files and are not counted
+func (ds *DataSource) FindMediaUsageByUser(userID uint) int64 {
+	var media, renditions struct{ Total int64 }
+
+	ds.DB.Model(&model.Media{}).
+		Select("coalesce(sum(size), 0) as total").
+		Where("user_id = ? and source_media_id = 0", userID).
+		Scan(&media)
+
+	ds.DB.Model(&model.MediaRendition{}).
+		Select("coalesce(sum(media_renditions.size), 0) as total").
+		Joins("join media on media.id = media_renditions.media_id").
+		Where("media.user_id = ? and media.source_media_id = 0 and media.deleted_at is null", userID).
+		Scan(&renditions)
+
+	return media.Total + renditions.Total
+}
+
+// FindMediaLibrary definition, the media uploaded by the user with the file name
+// containing the search text
+func (ds *DataSource) FindMediaLibrary(userID uint, search string) []model.Media {
+	result := make([]model.Media, 0)
+
+	query := ds.DB.Preload("Renditions").Where("user_id = ? and source_media_id = 0", userID)
+	if search != "" {
+		query = query.Where("lower(file_name) like ?", "%"+strings.ToLower(search)+"%")
+	}
+	query.Order("id desc").Find(&result)
+
+	log.WithFields(log.Fields{
+		"userID": userID,
+		"search": search,
+		"count":  len(result),
+	}).Debug("FindMediaLibrary")
+
+	return result
+}
+
+// FindLibraryMedia definition, a media uploaded by the user, attached copies are not found
+func (ds *DataSource) FindLibraryMedia(userID uint, id uint) *model.Media {
+	var result model.Media
+	if ds.DB.Preload("Renditions").
+		Where("id = ? and user_id = ? and source_media_id = 0", id, userID).
+		First(&result).
+		RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// UpdateMediaFileName definition, the attached copies are renamed with the media
+func (ds *DataSource) UpdateMediaFileName(media *model.Media, fileName string) *model.Media {
+	ds.DB.Model(&model.Media{}).
+		Where("id = ? or source_media_id = ?", media.ID, media.ID).
+		UpdateColumn("file_name", fileName)
+	media.FileName = fileName
+
+	log.WithFields(log.Fields{
+		"id":       media.ID,
+		"fileName": fileName,
+	}).Info("UpdateMediaFileName")
+
+	return media
+}
+
+// MediaIsReferenced definition, the media is attached to a game definition or a narrative
+// or is the avatar of a user
+func (ds *DataSource) MediaIsReferenced(media *model.Media) bool {
+	if media.GameDefinitionID != 0 || media.NarrativeDefinitionID != 0 {
+		return true
+	}
+
+	var copies, avatars int
+	ds.DB.Model(&model.Media{}).Where("source_media_id = ?", media.ID).Count(&copies)
+	ds.DB.Model(&model.User{}).Where("avatar_media_id = ?", media.ID).Count(&avatars)
+
+	return copies > 0 || avatars > 0
+}
+
+// DeleteMedia definition, the media and the renditions
+func (ds *DataSource) DeleteMedia(media *model.Media) error {
+	tx := ds.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err := tx.Where("media_id = ?", media.ID).Delete(&model.MediaRendition{}).Error
+	if err == nil {
+		err = tx.Delete(media).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"id":     media.ID,
+		"userID": media.UserID,
+	}).Info("DeleteMedia")
+
+	return tx.Commit().Error
+}
+
+// AttachMedia definition, the media replaces the media of the game definition or the narrative
+// as a copy sharing the files, the copies previously attached are removed and the uploads
+// attached directly return to the library
+func (ds *DataSource) AttachMedia(media *model.Media, target model.MediaAttachRequest) (*model.Media, error) {
+	tx := ds.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	err := detachMedia(tx, target, 0)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	attached := model.Media{
+		GameDefinitionID:      target.GameDefinitionID,
+		NarrativeDefinitionID: target.NarrativeDefinitionID,
+		UserID:                media.UserID,
+		FileName:              media.FileName,
+		URL:                   media.URL,
+		Thumbnail:             media.Thumbnail,
+		ContentType:           media.ContentType,
+		Size:                  media.Size,
+		Width:                 media.Width,
+		Height:                media.Height,
+		Hash:                  media.Hash,
+		SourceMediaID:         media.ID,
+		Renditions:            make([]model.MediaRendition, 0, len(media.Renditions)),
+	}
+	for _, rendition := range media.Renditions {
+		attached.Renditions = append(attached.Renditions, model.MediaRendition{
+			Name:        rendition.Name,
+			URL:         rendition.URL,
+			ContentType: rendition.ContentType,
+			Size:        rendition.Size,
+			Width:       rendition.Width,
+			Height:      rendition.Height,
+		})
+	}
+
+	err = tx.Create(&attached).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"id":                    attached.ID,
+		"sourceMediaID":         media.ID,
+		"gameDefinitionID":      target.GameDefinitionID,
+		"narrativeDefinitionID": target.NarrativeDefinitionID,
+	}).Info("AttachMedia")
+
+	return &attached, tx.Commit().Error
+}
+
+// DetachMedia definition, removes the media from the game definition or the narrative
+func (ds *DataSource) DetachMedia(media *model.Media, target model.MediaAttachRequest) error {
+	tx := ds.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err := detachMedia(tx, target, media.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"id":                    media.ID,
+		"gameDefinitionID":      target.GameDefinitionID,
+		"narrativeDefinitionID": target.NarrativeDefinitionID,
+	}).Info("DetachMedia")
+
+	return tx.Commit().Error
+}
+
+// the copies attached to the target are deleted and the uploads return to the library,
+// only the media and its copies are detached when mediaID is not zero
+func detachMedia(tx *gorm.DB, target model.MediaAttachRequest, mediaID uint) error {
+	query := tx.Model(&model.Media{})
+	if target.GameDefinitionID != 0 {
+		query = query.Where("game_definition_id = ?", target.GameDefinitionID)
+	} else {
+		query = query.Where("narrative_definition_id = ?", target.NarrativeDefinitionID)
+	}
+	if mediaID != 0 {
+		query = query.Where("id = ? or source_media_id = ?", mediaID, mediaID)
+	}
+
+	var copies []model.Media
+	err := query.Where("source_media_id <> 0").Find(&copies).Error
+	if err != nil {
+		return err
+	}
+
+	for n := range copies {
+		err = tx.Where("media_id = ?", copies[n].ID).Delete(&model.MediaRendition{}).Error
+		if err == nil {
+			err = tx.Delete(&copies[n]).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return query.Where("source_media_id = 0").
+		UpdateColumns(map[string]interface{}{"game_definition_id": 0, "narrative_definition_id": 0}).Error
+}
+
+// AddMediaUpload definition
+func (ds *DataSource) AddMediaUpload(upload *model.MediaUpload) *model.MediaUpload {
+	ds.DB.Create(upload)
+
+	log.WithFields(log.Fields{
+		"id":     upload.ID,
+		"userID": upload.UserID,
+		"size":   upload.Size,
+	}).Info("AddMediaUpload")
+
+	return upload
+}
+
+// FindMediaUpload definition, the upload of the user
+func (ds *DataSource) FindMediaUpload(userID uint, id uint) *model.MediaUpload {
+	var result model.MediaUpload
+	if ds.DB.Where("id = ? and user_id = ?", id, userID).First(&result).RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// UpdateMediaUpload definition, the received bytes and the media created
+func (ds *DataSource) UpdateMediaUpload(upload *model.MediaUpload) *model.MediaUpload {
+	ds.DB.Model(upload).UpdateColumns(map[string]interface{}{
+		"received":   upload.Received,
+		"media_id":   upload.MediaID,
+		"updated_at": time.Now(),
+	})
+	return upload
+}
+
+// DeleteMediaUpload definition
+func (ds *DataSource) DeleteMediaUpload(upload *model.MediaUpload) {
+	ds.DB.Delete(upload)
+}
+
+// FindOpenMediaUploads definition, the uploads of the user still receiving chunks
+func (ds *DataSource) FindOpenMediaUploads(userID uint) []model.MediaUpload {
+	result := make([]model.MediaUpload, 0)
+	ds.DB.Where("user_id = ? and media_id = 0", userID).Find(&result)
+	return result
+}
+
+// DeleteExpiredMediaUploads definition, removes the uploads of all the users not completed
+// since the time and returns them
+func (ds *DataSource) DeleteExpiredMediaUploads(since time.Time) []model.MediaUpload {
+	result := make([]model.MediaUpload, 0)
+	ds.DB.Where("media_id = 0 and updated_at < ?", since).Find(&result)
+
+	for n := range result {
+		ds.DB.Delete(&result[n])
+	}
+
+	if len(result) > 0 {
+		log.WithFields(log.Fields{
+			"count": len(result),
+		}).Info("DeleteExpiredMediaUploads")
+	}
+
+	return result
+}
@@ -0,0 +1,190 @@
+package datasource
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// AddModerationFlag definition
+func (ds *DataSource) AddModerationFlag(flag *model.ModerationFlag) *model.ModerationFlag {
+	ds.DB.Create(flag)
+
+	log.WithFields(log.Fields{
+		"userID":      flag.UserID,
+		"contentType": flag.ContentType,
+		"contentID":   flag.ContentID,
+		"field":       flag.Field,
+		"matches":     flag.Matches,
+	}).Info("AddModerationFlag")
+
+	return flag
+}
+
+// UpdateModerationFlag definition
+func (ds *DataSource) UpdateModerationFlag(flag *model.ModerationFlag) *model.ModerationFlag {
+	ds.DB.Save(flag)
+	return flag
+}
+
+// FindModerationFlag definition
+func (ds *DataSource) FindModerationFlag(id uint) *model.ModerationFlag {
+	var result model.ModerationFlag
+	if ds.DB.First(&result, id).RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// FindModerationFlagsByStatus definition, oldest flags first
+func (ds *DataSource) FindModerationFlagsByStatus(status string) []model.ModerationFlag {
+	result := make([]model.ModerationFlag, 0)
+	ds.DB.Where(&model.ModerationFlag{Status: status}).Order("id").Find(&result)
+	return result
+}
+
+// AddModerationAllowedWord definition, words already allowed for the locale are ignored
+func (ds *DataSource) AddModerationAllowedWord(word *model.ModerationAllowedWord) *model.ModerationAllowedWord {
+	word.Word = strings.ToLower(strings.TrimSpace(word.Word))
+
+	var found model.ModerationAllowedWord
+	filter := "word = ? and locale = ?"
+	if !ds.DB.Where(filter, word.Word, word.Locale).First(&found).RecordNotFound() {
+		return &found
+	}
+
+	ds.DB.Create(word)
+
+	log.WithFields(log.Fields{
+		"word":   word.Word,
+		"locale": word.Locale,
+	}).Info("AddModerationAllowedWord")
+
+	return word
+}
+
+// FindAllModerationAllowedWords definition
+func (ds *DataSource) FindAllModerationAllowedWords() []model.ModerationAllowedWord {
+	result := make([]model.ModerationAllowedWord, 0)
+	ds.DB.Order("id").Find(&result)
+	return result
+}
+
+// FindClassroomLocaleByStudent definition, the locale of the first classroom of the student
+// with a locale, empty when the user is not a student
+func (ds *DataSource) FindClassroomLocaleByStudent(userID uint) string {
+	var classroom model.Classroom
+	if ds.DB.
+		Joins("join classroom_students on classroom_students.classroom_id = classrooms.id").
+		Joins("join students on students.id = classroom_students.student_id").
+		Where("students.user_id = ? and classrooms.locale <> ''", userID).
+		Order("classrooms.id").
+		First(&classroom).
+		RecordNotFound() {
+		return ""
+	}
+	return classroom.Locale
+}
+
+// AddModerationReport definition, a pending report of the same user for the
+// same content is returned instead of creating a new one
+func (ds *DataSource) AddModerationReport(report *model.ModerationReport) *model.ModerationReport {
+	var found model.ModerationReport
+	if !ds.DB.Where(&model.ModerationReport{
+		ReporterUserID: report.ReporterUserID,
+		ContentType:    report.ContentType,
+		ContentID:      report.ContentID,
+		Status:         model.MODERATION_REPORT_PENDING,
+	}).First(&found).RecordNotFound() {
+		return &found
+	}
+
+	ds.DB.Create(report)
+
+	log.WithFields(log.Fields{
+		"reporterUserID": report.ReporterUserID,
+		"contentType":    report.ContentType,
+		"contentID":      report.ContentID,
+		"reportedUserID": report.ReportedUserID,
+	}).Info("AddModerationReport")
+
+	return report
+}
+
+// UpdateModerationReport definition
+func (ds *DataSource) UpdateModerationReport(report *model.ModerationReport) *model.ModerationReport {
+	ds.DB.Save(report)
+	return report
+}
+
+// FindModerationReport definition
+func (ds *DataSource) FindModerationReport(id uint) *model.ModerationReport {
+	var result model.ModerationReport
+	if ds.DB.First(&result, id).RecordNotFound() {
+		return nil
+	}
+	return &result
+}
+
+// FindModerationReportsByStatus definition, oldest reports first
+func (ds *DataSource) FindModerationReportsByStatus(status string) []model.ModerationReport {
+	result := make([]model.ModerationReport, 0)
+	ds.DB.Where(&model.ModerationReport{Status: status}).Order("id").Find(&result)
+	return result
+}
+
+// SuspendUser definition, the active sessions of the user are revoked
+// and the session middleware rejects the user until it is unsuspended
+func (ds *DataSource) SuspendUser(user *model.User, reason string) *model.User {
+	now := time.Now()
+	user.SuspendedAt = &now
+	user.SuspensionReason = reason
+	ds.DB.Model(user).Updates(map[string]interface{}{
+		"suspended_at":      now,
+		"suspension_reason": reason,
+	})
+	ds.RevokeUserSessions(user.ID)
+
+	log.WithFields(log.Fields{
+		"userID": user.ID,
+		"reason": reason,
+	}).Info("SuspendUser")
+
+	return user
+}
+
+// UnsuspendUser definition
+func (ds *DataSource) UnsuspendUser(user *model.User) *model.User {
+	user.SuspendedAt = nil
+	user.SuspensionReason = ""
+	ds.DB.Model(user).Updates(map[string]interface{}{
+		"suspended_at":      nil,
+		"suspension_reason": "",
+	})
+
+	log.WithFields(log.Fields{
+		"userID": user.ID,
+	}).Info("UnsuspendUser")
+
+	return user
+}
+
+// UpdateGameDefinitionHidden definition, hidden maps are not listed in the available matches,
+// false when the game definition is not found
+func (ds *DataSource) UpdateGameDefinitionHidden(id uint, hidden bool) bool {
+	var gameDefinition model.GameDefinition
+	if ds.DB.Select("id").First(&gameDefinition, id).RecordNotFound() {
+		return false
+	}
+
+	ds.DB.Model(&gameDefinition).UpdateColumn("hidden", hidden)
+
+	log.WithFields(log.Fields{
+		"gameDefinitionID": id,
+		"hidden":           hidden,
+	}).Info("UpdateGameDefinitionHidden")
+
+	return true
+}
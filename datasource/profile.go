@@ -0,0 +1,109 @@
+package datasource
+
+import (
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// SyncUserClaims definition, updates the user with the identity claims when they changed,
+// the display name starts as the first name and the locale as the identity locale
+func (ds *DataSource) SyncUserClaims(user *model.User, claims model.User) *model.User {
+	changes := make(map[string]interface{})
+
+	if user.Name != claims.Name {
+		changes["name"] = claims.Name
+	}
+	if user.FirstName != claims.FirstName {
+		changes["first_name"] = claims.FirstName
+	}
+	if user.LastName != claims.LastName {
+		changes["last_name"] = claims.LastName
+	}
+	if user.Email != claims.Email {
+		changes["email"] = claims.Email
+	}
+	if user.EmailVerified != claims.EmailVerified {
+		changes["email_verified"] = claims.EmailVerified
+	}
+	if user.DisplayName == "" && claims.FirstName != "" {
+		changes["display_name"] = claims.FirstName
+	}
+	if user.Locale == "" && claims.Locale != "" {
+		changes["locale"] = claims.Locale
+	}
+
+	if len(changes) == 0 {
+		return user
+	}
+
+	ds.DB.Model(user).Updates(changes)
+
+	log.WithFields(log.Fields{
+		"id":      user.ID,
+		"changes": len(changes),
+	}).Info("SyncUserClaims")
+
+	return user
+}
+
+// UpdateUserProfile definition
+func (ds *DataSource) UpdateUserProfile(user *model.User, request model.UserProfileRequest, avatar *model.Media) *model.User {
+	avatarURL := ""
+	if avatar != nil {
+		avatarURL = avatar.URL
+	}
+
+	ds.DB.Model(user).Updates(map[string]interface{}{
+		"display_name":       request.DisplayName,
+		"avatar_media_id":    request.AvatarMediaID,
+		"avatar_url":         avatarURL,
+		"profile_visibility": request.ProfileVisibility,
+		"locale":             request.Locale,
+	})
+
+	log.WithFields(log.Fields{
+		"id":          user.ID,
+		"displayName": user.DisplayName,
+		"visibility":  user.ProfileVisibility,
+	}).Info("UpdateUserProfile")
+
+	return user
+}
+
+// FindMedia definition
+func (ds *DataSource) FindMedia(id uint) *model.Media {
+	var media model.Media
+	if ds.DB.First(&media, id).RecordNotFound() {
+		return nil
+	}
+	return &media
+}
+
+// FindClassmates definition, private profiles only show the username
+func (ds *DataSource) FindClassmates(classroom *model.Classroom) []model.ClassmateProfile {
+	result := make([]model.ClassmateProfile, 0)
+
+	for _, student := range classroom.Students {
+		user := ds.FindUserByID(student.UserID)
+		if user == nil {
+			continue
+		}
+
+		profile := model.ClassmateProfile{
+			UserID:   user.ID,
+			Username: user.Username,
+		}
+		if user.ProfileVisibility == model.PROFILE_VISIBILITY_CLASSMATES {
+			profile.DisplayName = user.DisplayName
+			profile.AvatarURL = user.AvatarURL
+		}
+		result = append(result, profile)
+	}
+
+	return result
+}
+
+// IsClassroomMember definition, the owner and the students are members
+func IsClassroomMember(classroom *model.Classroom, userID uint) bool {
+	return classroom.OwnerID == userID || isEnrolled(classroom, userID)
+}
@@ -0,0 +1,150 @@
+package datasource
+
+import (
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// ChangeUserLevel definition, updates the user level and records the change in the audit log
+func (ds *DataSource) ChangeUserLevel(userID uint, level uint, change model.UserLevelChange) *model.UserLevel {
+	userLevel := ds.FindUserLevelByUserID(userID)
+
+	change.UserID = userID
+	change.PreviousLevel = userLevel.Level
+	change.Level = level
+
+	userLevel.Level = level
+	updated := ds.UpdateUserLevel(userLevel)
+	ds.DB.Create(&change)
+
+	log.WithFields(log.Fields{
+		"change": change,
+	}).Info("ChangeUserLevel")
+
+	return updated
+}
+
+// FindUserLevelChanges definition, most recent changes first
+func (ds *DataSource) FindUserLevelChanges(userID uint) []model.UserLevelChange {
+	result := make([]model.UserLevelChange, 0)
+
+	ds.DB.
+		Where(&model.UserLevelChange{UserID: userID}).
+		Order("id desc").
+		Find(&result)
+
+	return result
+}
+
+// AddMatchEvent definition
+func (ds *DataSource) AddMatchEvent(e *model.MatchEvent) *model.MatchEvent {
+	event := model.MatchEvent{
+		MatchID:    e.MatchID,
+		LuchadorID: e.LuchadorID,
+		Event:      e.Event,
+	}
+
+	ds.DB.Create(&event)
+
+	log.WithFields(log.Fields{
+		"event": event,
+	}).Info("AddMatchEvent")
+
+	return &event
+}
+
+func (ds *DataSource) matchEventExists(matchID uint, luchadorID uint, event string) bool {
+	var count int
+
+	ds.DB.Model(&model.MatchEvent{}).
+		Where(&model.MatchEvent{MatchID: matchID, LuchadorID: luchadorID, Event: event}).
+		Count(&count)
+
+	return count > 0
+}
+
+// keeps the last score sent for each luchador in the match
+func (ds *DataSource) findFinalScores(matchID uint) map[uint]model.MatchScore {
+	result := make(map[uint]model.MatchScore)
+
+	for _, score := range *ds.GetMatchScoresByMatchID(matchID) {
+		if current, found := result[score.LuchadorID]; !found || score.ID > current.ID {
+			result[score.LuchadorID] = score
+		}
+	}
+
+	return result
+}
+
+// finds the luchadors of the team with the highest score, matches
+// without teams have the luchadors with the highest score as winners
+func findWinners(match *model.Match, scores map[uint]model.MatchScore) map[uint]bool {
+	teamOf := make(map[uint]uint)
+	for _, participant := range match.TeamParticipants {
+		teamOf[participant.LuchadorID] = participant.TeamID
+	}
+
+	// when there are no teams each luchador is its own team
+	teamScore := make(map[uint]int)
+	luchadorTeam := func(luchadorID uint) uint {
+		if len(teamOf) == 0 {
+			return luchadorID
+		}
+		return teamOf[luchadorID]
+	}
+
+	for _, participant := range match.Participants {
+		teamScore[luchadorTeam(participant.ID)] += scores[participant.ID].Score
+	}
+
+	best := 0
+	first := true
+	for _, score := range teamScore {
+		if first || score > best {
+			best = score
+			first = false
+		}
+	}
+
+	result := make(map[uint]bool)
+	for _, participant := range match.Participants {
+		result[participant.ID] = teamScore[luchadorTeam(participant.ID)] == best
+	}
+
+	return result
+}
+
+// checks all the progression rules of the game definition for one participant
+func (ds *DataSource) meetsProgressionRules(match *model.Match, luchadorID uint, scores map[uint]model.MatchScore, winners map[uint]bool) bool {
+	score := scores[luchadorID]
+
+	for _, rule := range match.GameDefinition.ProgressionRules {
+		var passed bool
+
+		switch rule.Type {
+		case model.PROGRESSION_RULE_MIN_SCORE:
+			passed = score.Score >= rule.Value
+		case model.PROGRESSION_RULE_MIN_KILLS:
+			passed = score.Kills >= rule.Value
+		case model.PROGRESSION_RULE_WINNING_TEAM:
+			passed = winners[luchadorID]
+		case model.PROGRESSION_RULE_NARRATIVE_EVENT:
+			passed = ds.matchEventExists(match.ID, luchadorID, rule.Event)
+		default:
+			log.WithFields(log.Fields{
+				"rule": rule,
+			}).Warn("unknown progression rule type")
+		}
+
+		if !passed {
+			log.WithFields(log.Fields{
+				"matchID":    match.ID,
+				"luchadorID": luchadorID,
+				"rule":       rule,
+			}).Info("progression rule not satisfied")
+			return false
+		}
+	}
+
+	return true
+}
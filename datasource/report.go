@@ -0,0 +1,123 @@
+package datasource
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// number of matches listed for each student in the classroom report
+const reportRecentMatches = 5
+
+// BuildClassroomReport definition, LastSeen is not filled here as
+// page events are stored in the events datasource
+func (ds *DataSource) BuildClassroomReport(classroom *model.Classroom) *model.ClassroomReport {
+	report := model.ClassroomReport{
+		ClassroomID: classroom.ID,
+		Name:        classroom.Name,
+		Students:    make([]model.StudentProgress, 0),
+	}
+
+	for _, student := range classroom.Students {
+		user := ds.FindUserByID(student.UserID)
+		if user == nil {
+			log.WithFields(log.Fields{
+				"student": student,
+			}).Warn("user not found for student")
+			continue
+		}
+
+		progress := model.StudentProgress{
+			StudentID:      student.ID,
+			UserID:         user.ID,
+			Username:       user.Username,
+			Level:          ds.FindUserLevelByUserID(user.ID).Level,
+			PlayedTutorial: ds.FindUserSettingByUser(user).PlayedTutorial,
+			RecentMatches:  make([]model.StudentMatchResult, 0),
+		}
+
+		// all the luchadors of the student count, not only the default one
+		luchadorIDs := ds.findLuchadorIDs(user.ID)
+		if len(luchadorIDs) > 0 {
+			progress.TutorialsCompleted = ds.countTutorialsCompleted(luchadorIDs)
+			progress.MatchesPlayed = ds.countMatchesPlayed(luchadorIDs)
+			progress.RecentMatches = ds.FindRecentMatchResults(luchadorIDs, reportRecentMatches)
+			progress.CodeVersions, progress.LastCodeUpdate = ds.findCodeActivity(luchadorIDs)
+		}
+
+		report.Students = append(report.Students, progress)
+	}
+
+	log.WithFields(log.Fields{
+		"classroomID": classroom.ID,
+		"students":    len(report.Students),
+	}).Debug("BuildClassroomReport")
+
+	return &report
+}
+
+// counts the distinct tutorial game definitions with finished matches for the luchadors
+func (ds *DataSource) countTutorialsCompleted(luchadorIDs []uint) uint {
+	var count uint
+
+	ds.DB.Model(&model.Match{}).
+		Joins("join match_participants on match_participants.match_id = matches.id").
+		Joins("join game_definitions on game_definitions.id = matches.game_definition_id").
+		Where("match_participants.game_component_id in (?)", luchadorIDs).
+		Where("game_definitions.type = ?", model.GAMEDEFINITION_TYPE_TUTORIAL).
+		Where("matches.status = ?", model.MatchStatusFinished).
+		Select("count(distinct matches.game_definition_id)").
+		Row().
+		Scan(&count)
+
+	return count
+}
+
+// counts the distinct matches, a match played by two luchadors of the user counts once
+func (ds *DataSource) countMatchesPlayed(luchadorIDs []uint) uint {
+	var count uint
+
+	ds.DB.Model(&model.Match{}).
+		Joins("join match_participants on match_participants.match_id = matches.id").
+		Where("match_participants.game_component_id in (?)", luchadorIDs).
+		Select("count(distinct matches.id)").
+		Row().
+		Scan(&count)
+
+	return count
+}
+
+// FindRecentMatchResults definition, most recent scores first
+func (ds *DataSource) FindRecentMatchResults(luchadorIDs []uint, limit int) []model.StudentMatchResult {
+	result := make([]model.StudentMatchResult, 0)
+
+	ds.DB.Table("match_scores").
+		Select("match_scores.match_id, matches.game_definition_id, game_definitions.name as game_definition_name, "+
+			"matches.time_start, match_scores.kills, match_scores.deaths, match_scores.score").
+		Joins("join matches on matches.id = match_scores.match_id").
+		Joins("join game_definitions on game_definitions.id = matches.game_definition_id").
+		Where("match_scores.luchador_id in (?) AND match_scores.deleted_at IS NULL", luchadorIDs).
+		Order("match_scores.id desc").
+		Limit(limit).
+		Scan(&result)
+
+	return result
+}
+
+// returns the amount of code versions saved by the luchadors and the time of the last one
+func (ds *DataSource) findCodeActivity(luchadorIDs []uint) (uint, *time.Time) {
+	var count uint
+	var last model.CodeHistory
+
+	query := ds.DB.Model(&model.CodeHistory{}).
+		Joins("join gamecomponent_codes on gamecomponent_codes.code_id = code_histories.code_id").
+		Where("gamecomponent_codes.game_component_id in (?)", luchadorIDs)
+
+	query.Count(&count)
+	if query.Order("code_histories.id desc").First(&last).RecordNotFound() {
+		return count, nil
+	}
+
+	return count, &last.CreatedAt
+}
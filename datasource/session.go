@@ -0,0 +1,108 @@
+package datasource
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// minimum time between updates of the session last seen date
+const sessionTouchInterval = time.Minute
+
+// CreateSession definition
+func (ds *DataSource) CreateSession(session *model.Session, roles []string) *model.Session {
+	id, err := uuid.NewV4()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Error creating session UUID")
+		return nil
+	}
+
+	session.UUID = id.String()
+	session.Roles = strings.Join(roles, ",")
+	session.LastSeenAt = time.Now()
+
+	// concurrent first requests with the same token create a single session
+	if ds.DB.Create(session).Error != nil {
+		return ds.FindSessionByTokenHash(session.TokenHash)
+	}
+
+	log.WithFields(log.Fields{
+		"UUID":   session.UUID,
+		"userID": session.UserID,
+	}).Info("CreateSession")
+
+	return session
+}
+
+// FindSessionByTokenHash definition, revoked sessions are included
+func (ds *DataSource) FindSessionByTokenHash(hash string) *model.Session {
+	var session model.Session
+	if ds.DB.Where("token_hash = ?", hash).First(&session).RecordNotFound() {
+		return nil
+	}
+	return &session
+}
+
+// FindSessionByUUID definition
+func (ds *DataSource) FindSessionByUUID(UUID string) *model.Session {
+	var session model.Session
+	if ds.DB.Where("uuid = ?", UUID).First(&session).RecordNotFound() {
+		return nil
+	}
+	return &session
+}
+
+// FindActiveSessions definition, not revoked and not expired sessions of the user
+func (ds *DataSource) FindActiveSessions(userID uint, now time.Time) []model.Session {
+	result := make([]model.Session, 0)
+
+	ds.DB.
+		Where("user_id = ? and revoked_at is null and expires_at > ?", userID, now).
+		Order("last_seen_at desc").
+		Find(&result)
+
+	return result
+}
+
+// TouchSession definition, records when the session was used
+func (ds *DataSource) TouchSession(session *model.Session, now time.Time) {
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	session.LastSeenAt = now
+	ds.DB.Model(session).UpdateColumn("last_seen_at", now)
+}
+
+// RevokeSession definition
+func (ds *DataSource) RevokeSession(session *model.Session) *model.Session {
+	now := time.Now()
+	session.RevokedAt = &now
+	ds.DB.Model(session).UpdateColumn("revoked_at", now)
+
+	log.WithFields(log.Fields{
+		"UUID":   session.UUID,
+		"userID": session.UserID,
+	}).Info("RevokeSession")
+
+	return session
+}
+
+// RevokeUserSessions definition, revokes all the active sessions of the user
+func (ds *DataSource) RevokeUserSessions(userID uint) int64 {
+	result := ds.DB.Model(&model.Session{}).
+		Where("user_id = ? and revoked_at is null", userID).
+		UpdateColumn("revoked_at", time.Now())
+
+	log.WithFields(log.Fields{
+		"userID":  userID,
+		"revoked": result.RowsAffected,
+	}).Info("RevokeUserSessions")
+
+	return result.RowsAffected
+}
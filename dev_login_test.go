@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func devLoginToken(t *testing.T, username string) string {
+	body, _ := json.Marshal(model.DevLoginRequest{Username: username})
+	w := test.PerformRequestNoAuth(router, "POST", "/public/dev-login", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.DevLoginResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return "Bearer " + response.Token
+}
+
+func TestDevLogin(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	users := []auth.JWTUser{
+		{Username: "maria"},
+		{Username: "prof", Roles: []string{"dashboard_user"}},
+	}
+	provider, err := auth.NewDevProvider(users)
+	assert.Nil(t, err)
+	auth.EnableDevProvider(provider)
+	defer auth.EnableDevProvider(nil)
+
+	router = createRouter(test.API_KEY, "true", auth.SessionIsValid, auth.SessionIsValid)
+
+	w := test.PerformRequestNoAuth(router, "GET", "/public/dev-users", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "maria")
+
+	student := devLoginToken(t, "maria")
+	teacher := devLoginToken(t, "prof")
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", student)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var details model.UserDetails
+	json.Unmarshal(w.Body.Bytes(), &details)
+	assert.Equal(t, "maria", details.User.Username)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/classroom", "", student)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/classroom", "", teacher)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", "maria")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	body, _ := json.Marshal(model.DevLoginRequest{Username: "unknown"})
+	w = test.PerformRequestNoAuth(router, "POST", "/public/dev-login", string(body))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the login endpoints only exist when the provider is enabled
+	auth.EnableDevProvider(nil)
+	router = createRouter(test.API_KEY, "true", auth.SessionIsValid, auth.SessionIsValid)
+	w = test.PerformRequestNoAuth(router, "GET", "/public/dev-users", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -138,3 +138,50 @@ func (ds *DataSource) AddMatchMetric(m *model.MatchMetric) *model.MatchMetric {
 
 	return &metric
 }
+
+// FindLastEventByUser definition
+func (ds *DataSource) FindLastEventByUser(userID uint) *model.PageEvent {
+	var event model.PageEvent
+	if ds.DB.Where(&model.PageEvent{UserID: userID}).Order("id desc").First(&event).RecordNotFound() {
+		return nil
+	}
+
+	return &event
+}
+
+// FindEventsByUser definition
+func (ds *DataSource) FindEventsByUser(userID uint) []model.PageEvent {
+	result := make([]model.PageEvent, 0)
+	ds.DB.Where(&model.PageEvent{UserID: userID}).Order("id").Find(&result)
+	return result
+}
+
+// AnonymizeEventsByUser definition, removes the user, address and user agent from the events
+func (ds *DataSource) AnonymizeEventsByUser(userID uint) int64 {
+	result := ds.DB.Model(&model.PageEvent{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"user_id":     0,
+			"remote_addr": "",
+			"user_agent":  "",
+		})
+
+	log.WithFields(log.Fields{
+		"userID":  userID,
+		"updated": result.RowsAffected,
+	}).Info("AnonymizeEventsByUser")
+
+	return result.RowsAffected
+}
+
+// DeleteEventsByUser definition
+func (ds *DataSource) DeleteEventsByUser(userID uint) int64 {
+	result := ds.DB.Unscoped().Where("user_id = ?", userID).Delete(&model.PageEvent{})
+
+	log.WithFields(log.Fields{
+		"userID":  userID,
+		"deleted": result.RowsAffected,
+	}).Info("DeleteEventsByUser")
+
+	return result.RowsAffected
+}
@@ -40,3 +40,14 @@ func UserDetailsFromContext(c *gin.Context) *model.UserDetails {
 	userDetails := val.(model.UserDetails)
 	return &userDetails
 }
+
+// SessionFromContext get the current session from the request context,
+// returns nil when the session validator dont create sessions
+func SessionFromContext(c *gin.Context) *model.Session {
+	val, found := c.Get("session")
+	if !found {
+		return nil
+	}
+	session, _ := val.(*model.Session)
+	return session
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func TestLearningCatalogueNullBody(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	for _, path := range []string{"/dashboard/skill", "/dashboard/learning-objective", "/dashboard/activity"} {
+		w := test.PerformRequestNoAuth(router, "POST", path, "null")
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+
+		w = test.PerformRequestNoAuth(router, "PUT", path+"/1", "null")
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func loadoutScripts(loadout model.Loadout) map[string]string {
+	result := make(map[string]string)
+	for _, code := range loadout.Codes {
+		result[code.Event] = fmt.Sprintf("%v:%v", code.GameDefinitionID, code.Script)
+	}
+	return result
+}
+
+func TestLuchadorLoadout(t *testing.T) {
+	userName := "loadout"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestLuchadorLoadout"
+	gd.LuchadorSuggestedCodes = []model.Code{
+		{Event: "onRepeat", Script: "move(10)"},
+		{Event: "onStart", Script: "turn(90)"},
+	}
+	gd = *ds.CreateGameDefinition(&gd)
+
+	luchador.Codes = []model.Code{
+		{Event: "onRepeat", Script: "fire(1)"},
+		{Event: "onHitWall", Script: "turn(180)"},
+		{Event: "onRepeat", Script: "move(1)", GameDefinitionID: gd.ID},
+	}
+	body, _ := json.Marshal(luchador)
+	w := test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	base := fmt.Sprintf("/private/luchadors/%v/loadout", luchador.ID)
+	w = test.PerformRequest(router, "GET", base, "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var loadouts []model.Loadout
+	json.Unmarshal(w.Body.Bytes(), &loadouts)
+	assert.Equal(t, 2, len(loadouts))
+	assert.Equal(t, map[string]string{"onRepeat": "0:fire(1)", "onHitWall": "0:turn(180)"}, loadoutScripts(loadouts[0]))
+	assert.Equal(t, gd.Name, loadouts[1].GameDefinitionName)
+
+	// the events without codes for the game definition use the default codes
+	gdID := gd.ID
+	assert.Equal(t, map[string]string{
+		"onRepeat":  fmt.Sprintf("%v:move(1)", gdID),
+		"onHitWall": "0:turn(180)",
+	}, loadoutScripts(loadouts[1]))
+
+	path := fmt.Sprintf("%v/%v", base, gdID)
+	w = test.PerformRequest(router, "POST", path+"/reset", "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var loadout model.Loadout
+	json.Unmarshal(w.Body.Bytes(), &loadout)
+	assert.Equal(t, map[string]string{
+		"onRepeat":  fmt.Sprintf("%v:move(10)", gdID),
+		"onStart":   fmt.Sprintf("%v:turn(90)", gdID),
+		"onHitWall": "0:turn(180)",
+	}, loadoutScripts(loadout))
+
+	// the codes of the default loadout replace the codes of the game definition
+	w = test.PerformRequest(router, "POST", path+"/copy", `{"fromGameDefinitionID": 0}`, userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", path, "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+	loadout = model.Loadout{}
+	json.Unmarshal(w.Body.Bytes(), &loadout)
+	assert.Equal(t, map[string]string{
+		"onRepeat":  fmt.Sprintf("%v:fire(1)", gdID),
+		"onHitWall": fmt.Sprintf("%v:turn(180)", gdID),
+	}, loadoutScripts(loadout))
+
+	w = test.PerformRequest(router, "POST", base+"/0/reset", "", userName)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequest(router, "POST", path+"/copy", fmt.Sprintf(`{"fromGameDefinitionID": %v}`, gdID), userName)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequest(router, "GET", base+"/9999", "", userName)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// only the luchadors of the user
+	w = test.PerformRequest(router, "GET", base, "", "other")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gitlab.com/robolucha/robolucha-api/auth"
 	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/mask"
 	"gitlab.com/robolucha/robolucha-api/model"
 	"gitlab.com/robolucha/robolucha-api/test"
 )
@@ -148,9 +149,14 @@ func TestLuchadorUpdateRandomMask(t *testing.T) {
 	var originalConfigs []model.Config = luchador.Configs
 	updatedConfigs := make([]model.Config, len(originalConfigs))
 
+	randomConfigs := make(map[string]string)
+	for _, config := range model.RandomConfig() {
+		randomConfigs[config.Key] = config.Value
+	}
+
 	for n, config := range originalConfigs {
 		updatedConfigs[n].Key = config.Key
-		updatedConfigs[n].Value = config.Value + "A"
+		updatedConfigs[n].Value = randomConfigs[config.Key]
 	}
 
 	luchador.Configs = updatedConfigs
@@ -216,3 +222,180 @@ func TestLuchadorUpdateBlocklyCode(t *testing.T) {
 	}).Error("TestLuchadorUpdateBlocklyCode")
 
 }
+
+func TestLuchadorUpdateInvalidMask(t *testing.T) {
+	userName := "mask"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	original := append([]model.Config{}, luchador.Configs...)
+	for n := range luchador.Configs {
+		switch luchador.Configs[n].Key {
+		case "mask.shape":
+			luchador.Configs[n].Value = "not a mask value"
+		case "eyes.color":
+			luchador.Configs[n].Value = "red"
+		}
+	}
+
+	// a fixed name, only the mask errors are expected
+	luchador.Name = "Invalid Mask"
+	body, _ := json.Marshal(luchador)
+	w := test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.ElementsMatch(t, []string{
+		"Mask config mask.shape should be one of the shape options",
+		"Mask config eyes.color should be a #RRGGBB color",
+	}, response.Errors)
+
+	// the configs are not changed
+	AssertConfigMatch(t, original, GetLuchadorWithName(t, userName).Configs)
+}
+
+func TestGetMaskSchema(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequestNoAuth(router, "GET", "/private/mask-schema", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var schema mask.Schema
+	json.Unmarshal(w.Body.Bytes(), &schema)
+	assert.NotEmpty(t, schema.Version)
+	assert.Equal(t, len(model.MaskShapes()), len(schema.Shapes))
+	assert.Equal(t, len(model.NMSColors()), len(schema.Palette))
+}
+
+func TestLuchadorUpdateLockedMask(t *testing.T) {
+	userName := "locked"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	setMaskShape := func(value string) string {
+		for n := range luchador.Configs {
+			if luchador.Configs[n].Key == "mask.shape" {
+				luchador.Configs[n].Value = value
+			}
+		}
+		body, _ := json.Marshal(luchador)
+		return string(body)
+	}
+
+	// the shapes available before the catalogue stay unlocked
+	w := test.PerformRequest(router, "PUT", "/private/luchador", setMaskShape("segunda_cor0015.png"), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+
+	body := setMaskShape("segunda_cor0018.png")
+	w = test.PerformRequest(router, "PUT", "/private/luchador", body, userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response = model.UpdateLuchadorResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Mask config mask.shape is locked until level 15"}, response.Errors)
+
+	// unlocked after the user reaches the level
+	level := ds.FindUserLevelByUserID(luchador.UserID)
+	level.Level = 15
+	ds.UpdateUserLevel(level)
+
+	w = test.PerformRequest(router, "PUT", "/private/luchador", body, userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response = model.UpdateLuchadorResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+	AssertConfigMatch(t, luchador.Configs, GetLuchadorWithName(t, userName).Configs)
+}
+
+func TestLuchadorUpdateInvalidScript(t *testing.T) {
+	userName := "script"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	luchador.Codes = []model.Code{
+		{Event: "onStart", Script: "turnGun(90)"},
+		{Event: "onRepeat", Script: "move(10)\nos.execute('ls')"},
+	}
+
+	body, _ := json.Marshal(luchador)
+	w := test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Code onRepeat uses os at line 2, it is not available for the luchadors"}, response.Errors)
+
+	// the codes are not saved
+	assert.Empty(t, GetLuchadorWithName(t, userName).Codes)
+
+	luchador.Codes[1].Script = "move(10)\nfire(1)"
+	body, _ = json.Marshal(luchador)
+	w = test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response = model.UpdateLuchadorResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, 2, len(GetLuchadorWithName(t, userName).Codes))
+}
+
+func TestLuchadorUpdateStoredInvalidScript(t *testing.T) {
+	userName := "stored"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	// scripts saved before the checks are kept when other fields are changed
+	luchador.Codes = []model.Code{{Event: "onRepeat", Script: "move(10"}}
+	ds.DB.Save(luchador)
+
+	luchador = ds.FindLuchadorByID(luchador.ID)
+	luchador.Name = "Stored Script"
+	body, _ := json.Marshal(luchador)
+	w := test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "Stored Script", GetLuchadorWithName(t, userName).Name)
+
+	luchador.Codes[0].Script = "move(20"
+	body, _ = json.Marshal(luchador)
+	w = test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	response = model.UpdateLuchadorResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, len(response.Errors))
+}
+
+func TestGetMaskCatalogue(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequestNoAuth(router, "GET", "/private/mask-catalogue", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var catalogue mask.Catalogue
+	json.Unmarshal(w.Body.Bytes(), &catalogue)
+	assert.Equal(t, uint(0), catalogue.Level)
+	assert.Equal(t, len(model.MaskShapes()), len(catalogue.Shapes))
+	assert.Equal(t, len(model.NMSColors()), len(catalogue.Colors))
+	assert.Contains(t, catalogue.NameLocales, model.DefaultMaskNameLocale)
+
+	locked := 0
+	for _, shape := range catalogue.Shapes {
+		for _, item := range shape.Items {
+			assert.Equal(t, item.MinLevel > 0, item.Locked)
+			if item.Locked {
+				locked++
+			}
+		}
+	}
+	assert.True(t, locked > 0)
+}
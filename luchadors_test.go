@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func createTestLuchador(t *testing.T, userName string, name string) model.UpdateLuchadorResponse {
+	body, _ := json.Marshal(model.GameComponent{Name: name})
+	w := test.PerformRequest(router, "POST", "/private/luchadors", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func getTestLuchadors(t *testing.T, userName string) []model.GameComponent {
+	w := test.PerformRequest(router, "GET", "/private/luchadors", "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result []model.GameComponent
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestCreateAndDeleteLuchadors(t *testing.T) {
+	userName := "luchadors"
+	first := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	response := createTestLuchador(t, userName, "Tutorial Bot")
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "Tutorial Bot", response.Luchador.Name)
+	assert.NotEmpty(t, response.Luchador.Configs)
+
+	// random name when empty
+	random := createTestLuchador(t, userName, "")
+	assert.Empty(t, random.Errors)
+	assert.NotEmpty(t, random.Luchador.Name)
+
+	// names are unique across users
+	assert.Equal(t, []string{"Luchador with this name already exists"}, createTestLuchador(t, "other", "Tutorial Bot").Errors)
+
+	luchadors := getTestLuchadors(t, userName)
+	assert.Equal(t, 3, len(luchadors))
+	assert.Equal(t, first.ID, luchadors[0].ID)
+	assert.Equal(t, response.Luchador.ID, luchadors[1].ID)
+
+	// the default luchador is not changed by the new luchadors
+	assert.Equal(t, first.ID, GetLuchadorWithName(t, userName).ID)
+
+	// only the luchadors of the user are deleted
+	path := fmt.Sprintf("/private/luchadors/%v", response.Luchador.ID)
+	w := test.PerformRequest(router, "DELETE", path, "", "other")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequest(router, "DELETE", path, "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(getTestLuchadors(t, userName)))
+
+	// the name of the deleted luchador can be used again
+	assert.Empty(t, createTestLuchador(t, userName, "Tutorial Bot").Errors)
+
+	w = test.PerformRequest(router, "DELETE", fmt.Sprintf("/private/luchadors/%v", random.Luchador.ID), "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the last luchador is kept
+	luchadors = getTestLuchadors(t, userName)
+	for _, luchador := range luchadors[1:] {
+		w = test.PerformRequest(router, "DELETE", fmt.Sprintf("/private/luchadors/%v", luchador.ID), "", userName)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	w = test.PerformRequest(router, "DELETE", fmt.Sprintf("/private/luchadors/%v", first.ID), "", userName)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateLuchadorLimit(t *testing.T) {
+	userName := "limit"
+	SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	for n := 1; n < maxLuchadorsPerUser; n++ {
+		assert.Empty(t, createTestLuchador(t, userName, fmt.Sprintf("Limit Bot %v", n)).Errors)
+	}
+
+	response := createTestLuchador(t, userName, "Limit Bot")
+	assert.Equal(t, []string{fmt.Sprintf("Users can have at most %v luchadors", maxLuchadorsPerUser)}, response.Errors)
+}
+
+func TestDefaultLuchador(t *testing.T) {
+	userName := "default"
+	first := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	second := createTestLuchador(t, userName, "PvP Bot").Luchador
+
+	w := test.PerformRequest(router, "PUT", fmt.Sprintf("/private/luchadors/%v/default", first.ID), "", "other")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequest(router, "PUT", fmt.Sprintf("/private/luchadors/%v/default", second.ID), "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var settings model.UserSetting
+	json.Unmarshal(w.Body.Bytes(), &settings)
+	assert.Equal(t, second.ID, settings.DefaultLuchadorID)
+	assert.Equal(t, second.ID, GetLuchadorWithName(t, userName).ID)
+
+	// the luchadors that are not the default can be updated
+	first.Name = "Renamed Bot"
+	body, _ := json.Marshal(first)
+	w = test.PerformRequest(router, "PUT", "/private/luchador", string(body), userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "Renamed Bot", response.Luchador.Name)
+
+	// but not the luchadors of other users
+	w = test.PerformRequest(router, "PUT", "/private/luchador", string(body), "other")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the first luchador is the default after deleting the default luchador
+	w = test.PerformRequest(router, "DELETE", fmt.Sprintf("/private/luchadors/%v", second.ID), "", userName)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, first.ID, GetLuchadorWithName(t, userName).ID)
+}
+
+func TestCreateLuchadorWithOtherUserConfigs(t *testing.T) {
+	victim := SetupWithUserName(t, "victim")
+	defer ds.DB.Close()
+	GetLuchadorWithName(t, "attacker")
+
+	body, _ := json.Marshal(model.GameComponent{Name: "Config Thief", Configs: victim.Configs})
+	w := test.PerformRequest(router, "POST", "/private/luchadors", string(body), "attacker")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.UpdateLuchadorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Errors)
+
+	victimIDs := make(map[uint]bool)
+	for _, config := range victim.Configs {
+		victimIDs[config.ID] = true
+	}
+	for _, config := range response.Luchador.Configs {
+		assert.False(t, victimIDs[config.ID])
+	}
+
+	// the changes of the new luchador don't change the configs of the victim
+	luchador := response.Luchador
+	for n := range luchador.Configs {
+		if luchador.Configs[n].Key == "mask.primary.color" {
+			luchador.Configs[n].Value = "#000000"
+		}
+	}
+	body, _ = json.Marshal(luchador)
+	w = test.PerformRequest(router, "PUT", "/private/luchador", string(body), "attacker")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	AssertConfigMatch(t, victim.Configs, GetLuchadorWithName(t, "victim").Configs)
+	assert.Equal(t, len(victim.Configs), len(ds.FindLuchadorByID(victim.ID).Configs))
+}
+
+func TestCreateLuchadorNullBody(t *testing.T) {
+	SetupWithUserName(t, "null")
+	defer ds.DB.Close()
+
+	w := test.PerformRequest(router, "POST", "/private/luchadors", "null", "null")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateLuchadorFlaggedColorName(t *testing.T) {
+	userName := "flagged"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	// the name of the color is flagged by the moderation
+	for n := range luchador.Configs {
+		switch luchador.Configs[n].Key {
+		case "mask.primary.color":
+			luchador.Configs[n].Value = "#AD709A"
+		case "mask.primary.color.name":
+			luchador.Configs[n].Value = "Muscat Grapes"
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		body, _ := json.Marshal(model.GameComponent{Configs: luchador.Configs})
+		w := test.PerformRequest(router, "POST", "/private/luchadors", string(body), userName)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.UpdateLuchadorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Empty(t, response.Errors)
+		assert.NotContains(t, response.Luchador.Name, "Muscat")
+	}
+}
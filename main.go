@@ -9,14 +9,19 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-contrib/cors"
 	useragent "github.com/mileusna/useragent"
@@ -29,15 +34,23 @@ import (
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/events"
 	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/mask"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 	"gitlab.com/robolucha/robolucha-api/pubsub"
 	"gitlab.com/robolucha/robolucha-api/routes"
+	"gitlab.com/robolucha/robolucha-api/routes/apikey"
 	"gitlab.com/robolucha/robolucha-api/routes/learning"
+	"gitlab.com/robolucha/robolucha-api/routes/loadout"
 	"gitlab.com/robolucha/robolucha-api/routes/mapeditor"
+	"gitlab.com/robolucha/robolucha-api/routes/maskimage"
 	"gitlab.com/robolucha/robolucha-api/routes/media"
 	"gitlab.com/robolucha/robolucha-api/routes/play"
+	"gitlab.com/robolucha/robolucha-api/routes/report"
+	"gitlab.com/robolucha/robolucha-api/routes/review"
+	"gitlab.com/robolucha/robolucha-api/script"
 	"gitlab.com/robolucha/robolucha-api/setup"
-	"gitlab.com/robolucha/robolucha-api/utility"
+	"gitlab.com/robolucha/robolucha-api/storage"
 
 	_ "gitlab.com/robolucha/robolucha-api/docs"
 )
@@ -47,6 +60,14 @@ var eventsDS *events.DataSource
 
 var publisher pubsub.Publisher
 
+var mediaStore storage.MediaStore
+
+var mediaConfig = media.DefaultConfig()
+var maskRenderer = mask.NewRendererFromEnv()
+
+// players and teachers can keep a few robots, as a pvp and a tutorial luchador
+const maxLuchadorsPerUser = 10
+
 func main() {
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetOutput(os.Stdout)
@@ -70,9 +91,38 @@ func main() {
 		os.Exit(2)
 	}
 
+	if fileName := os.Getenv("AUTH_ROLE_PERMISSIONS_FILE"); fileName != "" {
+		err := auth.LoadRolePermissions(fileName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Error loading role permissions")
+			os.Exit(2)
+		}
+	}
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Error creating media store")
+		os.Exit(2)
+	}
+	mediaStore = store
+
+	mediaConfig, err = media.ConfigFromEnv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Error reading media config")
+		os.Exit(2)
+	}
+
 	metadataFolder := os.Args[1]
 	setup.LoadMetadataFromFolder(metadataFolder, ds)
 	setup.CreateAvailableMatches(ds)
+	moderation.LoadReviewedWords(ds)
+	moderation.WatchFromEnv()
 
 	port := os.Getenv("API_PORT")
 	if len(port) == 0 {
@@ -87,6 +137,14 @@ func main() {
 	logRequestBody := os.Getenv("GIM_LOG_REQUEST_BODY")
 	disableAuth := os.Getenv("DISABLE_AUTH")
 
+	err = auth.EnableDevProviderFromEnv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Error enabling dev auth provider")
+		os.Exit(2)
+	}
+
 	var router *gin.Engine
 
 	if disableAuth == "true" {
@@ -95,6 +153,7 @@ func main() {
 		router = createRouter(internalAPIKey, logRequestBody, auth.SessionIsValid, auth.SessionIsValid)
 	}
 
+	go media.WatchExpiredUploads(nil)
 	router.Run(":" + port)
 
 	log.WithFields(log.Fields{
@@ -125,24 +184,42 @@ func createRouter(internalAPIKey string, logRequestBody string,
 	publicAPI := router.Group("/public")
 	{
 		publicAPI.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+		if auth.GetDevProvider() != nil {
+			publicAPI.GET("/dev-users", getDevUsers)
+			publicAPI.POST("/dev-login", devLogin)
+		}
+
+		if server, ok := mediaStore.(storage.FileServer); ok {
+			publicAPI.GET(storage.LocalRoute+"/*key", server.ServeFile)
+		}
+
+		maskImageRouter := maskimage.Init(ds, publisher).WithRenderer(maskRenderer)
+		routes.Use(publicAPI, maskImageRouter)
 	}
 
 	internalAPI := router.Group("/internal")
-	internalAPI.Use(auth.KeyIsValid(internalAPIKey))
+	internalAPI.Use(auth.APIKeyIsValid(ds, internalAPIKey))
 	{
-		internalAPI.GET("/game-definition/:name", getGameDefinitionByName)
-		internalAPI.GET("/game-definition-id/:id", getGameDefinitionByIDInternal)
-		internalAPI.POST("/game-definition", createGameDefinition)
-		internalAPI.PUT("/game-definition", updateGameDefinition)
-		internalAPI.POST("/game-component", createGameComponent)
-		internalAPI.POST("/luchador", getLuchadorByIDAndGamedefinitionID)
-		internalAPI.POST("/match-participant", addMatchPartipant)
-		internalAPI.PUT("/end-match", endMatch)
-		internalAPI.PUT("/run-match", runMatch)
+		runner := auth.RequireScope(model.APIKEY_SCOPE_RUNNER)
+		metadata := auth.RequireScope(model.APIKEY_SCOPE_METADATA)
+		runnerOrMetadata := auth.RequireScope(model.APIKEY_SCOPE_RUNNER, model.APIKEY_SCOPE_METADATA)
+		runnerOrAnalytics := auth.RequireScope(model.APIKEY_SCOPE_RUNNER, model.APIKEY_SCOPE_ANALYTICS)
+
+		internalAPI.GET("/game-definition/:name", runnerOrMetadata, getGameDefinitionByName)
+		internalAPI.GET("/game-definition-id/:id", runnerOrMetadata, getGameDefinitionByIDInternal)
+		internalAPI.POST("/game-definition", metadata, createGameDefinition)
+		internalAPI.PUT("/game-definition", metadata, updateGameDefinition)
+		internalAPI.POST("/game-component", runner, createGameComponent)
+		internalAPI.POST("/luchador", runner, getLuchadorByIDAndGamedefinitionID)
+		internalAPI.POST("/match-participant", runner, addMatchPartipant)
+		internalAPI.PUT("/end-match", runner, endMatch)
+		internalAPI.PUT("/run-match", runner, runMatch)
 		internalAPI.GET("/ready", getReady)
-		internalAPI.POST("/add-match-scores", addMatchScores)
-		internalAPI.GET("/match-single", getMatchInternal)
-		internalAPI.POST("/match-metric", addMatchMetric)
+		internalAPI.POST("/add-match-scores", runner, addMatchScores)
+		internalAPI.GET("/match-single", runnerOrAnalytics, getMatchInternal)
+		internalAPI.POST("/match-metric", runnerOrAnalytics, addMatchMetric)
+		internalAPI.POST("/match-event", runner, addMatchEvent)
 	}
 
 	privateAPI := router.Group("/private")
@@ -152,11 +229,19 @@ func createRouter(internalAPIKey string, logRequestBody string,
 		privateAPI.GET("/get-user", getUser)
 		privateAPI.GET("/luchador", getLuchador)
 		privateAPI.PUT("/luchador", updateLuchador)
+		privateAPI.GET("/luchadors", getLuchadors)
+		privateAPI.POST("/luchadors", createLuchador)
+		privateAPI.DELETE("/luchadors/:id", deleteLuchador)
+		privateAPI.PUT("/luchadors/:id/default", setDefaultLuchador)
 		privateAPI.GET("/mask-config/:id", getMaskConfig)
+		privateAPI.GET("/mask-schema", getMaskSchema)
+		privateAPI.GET("/mask-catalogue", getMaskCatalogue)
 		privateAPI.GET("/mask-random", getRandomMaskConfig)
 		privateAPI.GET("/mask-random-bulk/:amount", getBulkRandomMaskConfig)
 		privateAPI.PUT("/user/setting", updateUserSetting)
 		privateAPI.GET("/user/setting", findUserSetting)
+		privateAPI.PUT("/user/profile", updateUserProfile)
+		privateAPI.GET("/classroom/:id/classmates", getClassmates)
 		privateAPI.GET("/match", getActiveMatches)
 		privateAPI.GET("/match-multiplayer", getActiveMultiplayerMatches)
 
@@ -167,7 +252,7 @@ func createRouter(internalAPIKey string, logRequestBody string,
 		privateAPI.GET("/game-definition-id/:id", getGameDefinitionByID)
 		privateAPI.GET("/game-definition-all", getGameDefinition)
 		privateAPI.GET("/classroom", getClassroom)
-		privateAPI.POST("/classroom", addClassroom)
+		privateAPI.POST("/classroom", auth.RequirePermission(auth.PermissionClassroomManage), addClassroom)
 		privateAPI.POST("/join-classroom/:accessCode", joinClassroom)
 		privateAPI.GET("/available-match-public", getPublicAvailableMatch)
 		privateAPI.GET("/available-match-classroom/:id", getClassroomAvailableMatch)
@@ -175,16 +260,38 @@ func createRouter(internalAPIKey string, logRequestBody string,
 		privateAPI.GET("/available-match-classroom-joined", getClassroomAvailableMatchJoined)
 		privateAPI.POST("/page-events", addEvents)
 		privateAPI.GET("/level-group", getLevelGroup)
+		privateAPI.GET("/mastery", getMastery)
+		privateAPI.GET("/session", getSessions)
+		privateAPI.POST("/logout", logout)
+		privateAPI.DELETE("/session/:uuid", revokeSession)
+		privateAPI.GET("/account/export", exportAccount)
+		privateAPI.GET("/account/delete", getAccountDeletionRequests)
+		privateAPI.POST("/account/delete", deleteAccount)
 
 	}
 
 	dashboardAPI := router.Group("/dashboard")
 	dashboardAPI.Use(dashboardFactory(ds))
+	dashboardAPI.Use(auth.RequirePermission(auth.PermissionDashboardAccess))
 	{
+		classroomManage := auth.RequirePermission(auth.PermissionClassroomManage)
+
 		dashboardAPI.GET("/get-user", getUserDashboard)
 		dashboardAPI.GET("/classroom", getClassroom)
-		dashboardAPI.POST("/classroom", addClassroom)
-		dashboardAPI.GET("/classroom/students/:id", getClassroomStudents)
+		dashboardAPI.POST("/classroom", classroomManage, addClassroom)
+		dashboardAPI.GET("/classroom/students/:id", classroomManage, getClassroomStudents)
+		dashboardAPI.GET("/classroom/report/:id", classroomManage, getClassroomReport)
+		dashboardAPI.POST("/classroom/:id/roster", classroomManage, importClassroomRoster)
+		dashboardAPI.GET("/student/:id/mastery", classroomManage, getStudentMastery)
+		dashboardAPI.PUT("/student/:id/level", classroomManage, updateStudentLevel)
+		dashboardAPI.GET("/student/:id/level-history", classroomManage, getStudentLevelHistory)
+		dashboardAPI.GET("/account/deletion-request", classroomManage, getPendingAccountDeletionRequests)
+		dashboardAPI.POST("/account/deletion-request/:id/approve", classroomManage, approveAccountDeletionRequest)
+		dashboardAPI.POST("/account/deletion-request/:id/reject", classroomManage, rejectAccountDeletionRequest)
+
+		sessionAdmin := auth.RequirePermission(auth.PermissionSessionAdmin)
+		dashboardAPI.GET("/user/:id/session", sessionAdmin, getUserSessions)
+		dashboardAPI.DELETE("/user/:id/session", sessionAdmin, revokeUserSessions)
 	}
 
 	learningRouter := learning.Init(ds, publisher)
@@ -193,12 +300,24 @@ func createRouter(internalAPIKey string, logRequestBody string,
 	playRouter := play.Init(ds, publisher)
 	routes.Use(privateAPI, playRouter)
 
+	loadoutRouter := loadout.Init(ds, publisher)
+	routes.Use(privateAPI, loadoutRouter)
+
 	mapeditorRouter := mapeditor.Init(ds, publisher)
 	routes.Use(privateAPI, mapeditorRouter)
 
-	mediaRouter := media.Init(ds, publisher)
+	mediaRouter := media.Init(ds, publisher).WithStore(mediaStore).WithConfig(mediaConfig)
 	routes.Use(privateAPI, mediaRouter)
 
+	apikeyRouter := apikey.Init(ds, publisher)
+	routes.Use(dashboardAPI, apikeyRouter)
+
+	reviewRouter := review.Init(ds, publisher).WithLuchadorRenamer(renameLuchador)
+	routes.Use(dashboardAPI, reviewRouter)
+
+	reportRouter := report.Init(ds, publisher)
+	routes.Use(privateAPI, reportRouter)
+
 	return router
 }
 
@@ -223,6 +342,143 @@ func findUserSetting(c *gin.Context) {
 	c.JSON(http.StatusOK, userSetting)
 }
 
+// getMastery godoc
+// @Summary mastery levels and gaps of the current user by skill and learning objective
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.StudentMastery
+// @Security ApiKeyAuth
+// @Router /private/mastery [get]
+func getMastery(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	result := ds.BuildStudentMastery(user)
+
+	log.WithFields(log.Fields{
+		"userID": user.ID,
+		"gaps":   len(result.Gaps),
+	}).Info("getMastery")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getStudentMastery godoc
+// @Summary mastery levels and gaps of a student from one of the current user classrooms
+// @Accept json
+// @Produce json
+// @Param id path int true "Student id"
+// @Success 200 {object} model.StudentMastery
+// @Security ApiKeyAuth
+// @Router /dashboard/student/{id}/mastery [get]
+func getStudentMastery(c *gin.Context) {
+	studentUser := findOwnedStudentUser(c, "getStudentMastery")
+	if studentUser == nil {
+		return
+	}
+
+	result := ds.BuildStudentMastery(studentUser)
+
+	log.WithFields(log.Fields{
+		"studentID": result.StudentID,
+		"gaps":      len(result.Gaps),
+	}).Info("getStudentMastery")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// updateStudentLevel godoc
+// @Summary sets the level of a student from one of the current user classrooms
+// @Accept json
+// @Produce json
+// @Param id path int true "Student id"
+// @Param request body model.StudentLevelRequest true "StudentLevelRequest"
+// @Success 200 {object} model.UserLevel
+// @Security ApiKeyAuth
+// @Router /dashboard/student/{id}/level [put]
+func updateStudentLevel(c *gin.Context) {
+	studentUser := findOwnedStudentUser(c, "updateStudentLevel")
+	if studentUser == nil {
+		return
+	}
+
+	var request *model.StudentLevelRequest
+	err := c.BindJSON(&request)
+	if err != nil || request == nil {
+		log.Info("Invalid body content on updateStudentLevel")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if request.Level > model.MAX_USER_LEVEL {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			fmt.Sprintf("level should be less or equal to %v", model.MAX_USER_LEVEL))
+		return
+	}
+
+	user := httphelper.UserFromContext(c)
+	result := ds.ChangeUserLevel(studentUser.ID, request.Level, model.UserLevelChange{
+		Reason:          model.LEVEL_CHANGE_TEACHER,
+		ChangedByUserID: user.ID,
+	})
+
+	log.WithFields(log.Fields{
+		"userLevel": result,
+		"teacher":   user.ID,
+	}).Info("updateStudentLevel")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getStudentLevelHistory godoc
+// @Summary level changes of a student from one of the current user classrooms
+// @Accept json
+// @Produce json
+// @Param id path int true "Student id"
+// @Success 200 {array} model.UserLevelChange
+// @Security ApiKeyAuth
+// @Router /dashboard/student/{id}/level-history [get]
+func getStudentLevelHistory(c *gin.Context) {
+	studentUser := findOwnedStudentUser(c, "getStudentLevelHistory")
+	if studentUser == nil {
+		return
+	}
+
+	result := ds.FindUserLevelChanges(studentUser.ID)
+	c.JSON(http.StatusOK, result)
+}
+
+// finds the user of the student from the id parameter checking if the student
+// joined any classroom of the current user, aborts the request and returns nil otherwise
+func findOwnedStudentUser(c *gin.Context, context string) *model.User {
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		log.Info("Invalid body content on " + context)
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	user := httphelper.UserFromContext(c)
+	student := ds.FindStudentByID(id)
+	if student == nil || !ds.StudentBelongsToOwner(student.ID, user.ID) {
+		log.WithFields(log.Fields{
+			"studentID": id,
+			"user":      user,
+		}).Warn("Student is not in the classrooms of the current user")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	studentUser := ds.FindUserByID(student.UserID)
+	if studentUser == nil {
+		log.WithFields(log.Fields{
+			"student": student,
+		}).Warn("user not found for student")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	return studentUser
+}
+
 // updateUserSetting godoc
 // @Summary Updates user userSetting
 // @Accept  json
@@ -272,7 +528,7 @@ func createGameDefinition(c *gin.Context) {
 
 	var gameDefinition *model.GameDefinition
 	err := c.BindJSON(&gameDefinition)
-	if err != nil {
+	if err != nil || gameDefinition == nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("Invalid body content on createGameDefinition")
@@ -281,6 +537,11 @@ func createGameDefinition(c *gin.Context) {
 		return
 	}
 
+	if ruleErrors := model.ProgressionRuleErrors(gameDefinition.ProgressionRules); len(ruleErrors) > 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ruleErrors)
+		return
+	}
+
 	log.WithFields(log.Fields{
 		"gameDefinition": gameDefinition,
 	}).Info("createGameDefinition")
@@ -315,7 +576,7 @@ func updateGameDefinition(c *gin.Context) {
 
 	var gameDefinition *model.GameDefinition
 	err := c.BindJSON(&gameDefinition)
-	if err != nil {
+	if err != nil || gameDefinition == nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("Invalid body content on createGameDefinition")
@@ -324,6 +585,11 @@ func updateGameDefinition(c *gin.Context) {
 		return
 	}
 
+	if ruleErrors := model.ProgressionRuleErrors(gameDefinition.ProgressionRules); len(ruleErrors) > 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ruleErrors)
+		return
+	}
+
 	log.WithFields(log.Fields{
 		"gameDefinition": gameDefinition,
 	}).Info("updateGameDefinition")
@@ -393,8 +659,9 @@ func getLuchador(c *gin.Context) {
 			Name:   fmt.Sprintf("Luchador%d", user.ID),
 		}
 
-		luchador.Configs = model.RandomConfig()
-		luchador.Name = model.RandomName(luchador.Configs)
+		level := ds.FindUserLevelByUserID(user.ID).Level
+		luchador.Configs = model.RandomConfigForLevel(level)
+		luchador.Name = randomLuchadorName(c, user, luchador.Configs)
 		log.WithFields(log.Fields{
 			"getLuchador": model.LogGameComponent(luchador),
 		}).Info("creating luchador")
@@ -420,7 +687,7 @@ func getLuchador(c *gin.Context) {
 }
 
 // updateLuchador godoc
-// @Summary Updates Luchador
+// @Summary Updates one of the luchadors of the user, the changed scripts of the codes are checked before saving
 // @Accept  json
 // @Produce  json
 // @Param request body model.GameComponent true "Luchador"
@@ -429,7 +696,6 @@ func getLuchador(c *gin.Context) {
 // @Router /private/luchador [put]
 func updateLuchador(c *gin.Context) {
 	user := httphelper.UserFromContext(c)
-	response := model.UpdateLuchadorResponse{Errors: []string{}}
 
 	var luchador *model.GameComponent
 	err := c.BindJSON(&luchador)
@@ -439,32 +705,13 @@ func updateLuchador(c *gin.Context) {
 		return
 	}
 
-	luchador.Name = cleanName(luchador.Name)
-
-	if len(luchador.Name) < 3 {
-		response.Errors = append(response.Errors, "Luchador name length should be at least 3 characters")
-	}
-
-	if len(luchador.Name) > 40 {
-		response.Errors = append(response.Errors, "Luchador name length should be less or equal to 40 characters")
-	}
-
-	if utility.ContainsBadWord(luchador.Name) {
-		response.Errors = append(response.Errors, "Luchador name contains inappropriate language")
-	}
-
-	if ds.NameExist(luchador.ID, luchador.Name) {
-		response.Errors = append(response.Errors, "Luchador with this name already exists")
+	response := validateLuchador(c, luchador)
+	maskErrors := mask.CurrentSchema().Validate(luchador.Configs)
+	if len(maskErrors) > 0 {
+		response.Errors = append(response.Errors, maskErrors...)
+		response.Luchador = luchador
 	}
-
 	if len(response.Errors) > 0 {
-		response.Luchador = luchador
-
-		log.WithFields(log.Fields{
-			"luchador": luchador,
-			"response": response,
-		}).Debug("updateLuchador")
-
 		c.JSON(http.StatusOK, response)
 		return
 	}
@@ -474,126 +721,398 @@ func updateLuchador(c *gin.Context) {
 		"action":   "before save",
 	}).Debug("updateLuchador")
 
-	// validate if the luchador is the same from the user
-	currentLuchador := ds.FindLuchador(user)
+	// validate if the luchador is from the user
+	currentLuchador := ds.FindUserLuchador(user.ID, luchador.ID)
 	log.WithFields(log.Fields{
 		"luchador": luchador,
 		"user.ID":  user.ID,
 	}).Info("find luchador for current user")
 
-	if luchador.ID != currentLuchador.ID {
+	if currentLuchador == nil {
 		log.Info("Invalid Luchador.ID on updateLuchador")
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
-	response.Luchador = ds.UpdateLuchador(luchador)
+	level := ds.FindUserLevelByUserID(user.ID).Level
+	lockedErrors := mask.LockedConfigs(luchador.Configs, currentLuchador.Configs, level)
 
-	if response.Luchador == nil {
-		log.Info("Invalid Luchador when saving, missing ID?")
-		response.Errors = append(response.Errors, "Invalid Luchador when saving, missing ID?")
-	} else {
-		channel := fmt.Sprintf("luchador.%v.update", response.Luchador.ID)
-		luchadorUpdateJSON, _ := json.Marshal(response.Luchador)
-		message := string(luchadorUpdateJSON)
-		publisher.Publish(channel, message)
+	// only the scripts changed by the user are checked
+	scriptErrors := script.Check("Code", script.Changed(luchador.Codes, currentLuchador.Codes))
+	if len(lockedErrors) > 0 || len(scriptErrors) > 0 {
+		response.Errors = append(response.Errors, lockedErrors...)
+		response.Errors = append(response.Errors, scriptErrors...)
+		response.Luchador = luchador
+		c.JSON(http.StatusOK, response)
+		return
 	}
 
-	log.WithFields(log.Fields{
-		"response": response,
-		"action":   "after save",
-		"errors":   response.Errors,
-	}).Info("updateLuchador")
-
+	saveLuchador(luchador, &response)
 	c.JSON(http.StatusOK, response)
 }
 
-func cleanName(name string) string {
-	name = strings.TrimSpace(name)
-	return name
-}
-
-// getTutorialGameDefinition godoc
-// @Summary find tutorial GameDefinition
+// getLuchadors godoc
+// @Summary find the luchadors of the current user
 // @Accept json
 // @Produce json
-// @Success 200 {array} model.GameDefinition
+// @Success 200 {array} model.GameComponent
 // @Security ApiKeyAuth
-// @Router /private/tutorial [get]
-func getTutorialGameDefinition(c *gin.Context) {
-
-	tutorials := ds.FindTutorialGameDefinition()
-
-	log.WithFields(log.Fields{
-		"tutorials": tutorials,
-	}).Info("getTutorialGameDefinition")
-
-	c.JSON(http.StatusOK, tutorials)
+// @Router /private/luchadors [get]
+func getLuchadors(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	c.JSON(http.StatusOK, ds.FindLuchadorsByUser(user.ID))
 }
 
-// getMaskConfig godoc
-// @Summary find maskConfig for a luchador
-// @Accept json
-// @Produce json
-// @Param id path int true "Luchador ID"
-// @Success 200 {array} model.Config
+// createLuchador godoc
+// @Summary Creates a luchador for the current user, random name and configs are used when empty
+// @Accept  json
+// @Produce  json
+// @Param request body model.GameComponent true "Luchador"
+// @Success 200 {object} model.UpdateLuchadorResponse
 // @Security ApiKeyAuth
-// @Router /private/mask-config/{id} [get]
-func getMaskConfig(c *gin.Context) {
+// @Router /private/luchadors [post]
+func createLuchador(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
 
-	id := c.Param("id")
-	aid, err := strconv.Atoi(id)
-	if err != nil {
-		log.Info("Invalid ID")
+	var luchador *model.GameComponent
+	err := c.BindJSON(&luchador)
+	if err != nil || luchador == nil {
+		log.Info("Invalid body content on createLuchador")
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
-	log.WithFields(log.Fields{
-		"id": aid,
-	}).Info("getMaskConfig")
+	// only the keys and values of the configs, the ids would link the rows of other luchadors
+	configs := make([]model.Config, 0, len(luchador.Configs))
+	for _, config := range luchador.Configs {
+		configs = append(configs, model.Config{Key: config.Key, Value: config.Value})
+	}
 
-	configs := ds.FindMaskConfig(uint(aid))
+	level := ds.FindUserLevelByUserID(user.ID).Level
+	luchador = &model.GameComponent{
+		UserID:  user.ID,
+		Name:    luchador.Name,
+		Configs: configs,
+		Codes:   []model.Code{},
+	}
+	if len(luchador.Configs) == 0 {
+		luchador.Configs = model.RandomConfigForLevel(level)
+	}
+	if cleanName(luchador.Name) == "" {
+		luchador.Name = randomLuchadorName(c, user, luchador.Configs)
+	}
+
+	response := validateLuchador(c, luchador)
+	response.Errors = append(response.Errors, mask.CurrentSchema().Validate(luchador.Configs)...)
+	response.Errors = append(response.Errors, mask.LockedConfigs(luchador.Configs, []model.Config{}, level)...)
+	if ds.CountLuchadorsByUser(user.ID) >= maxLuchadorsPerUser {
+		response.Errors = append(response.Errors, fmt.Sprintf("Users can have at most %v luchadors", maxLuchadorsPerUser))
+	}
+	if len(response.Errors) > 0 {
+		response.Luchador = luchador
+		c.JSON(http.StatusOK, response)
+		return
+	}
 
+	response.Luchador = ds.CreateLuchador(luchador)
 	log.WithFields(log.Fields{
-		"configs": configs,
-	}).Info("getMaskConfig")
+		"luchador": model.LogGameComponent(response.Luchador),
+		"user.id":  user.ID,
+	}).Info("createLuchador")
 
-	c.JSON(http.StatusOK, configs)
+	c.JSON(http.StatusOK, response)
 }
 
-// getGameDefinitionByName godoc
-// @Summary find a game definition
+// random names too long, already used or with words flagged by the moderation are tried
+// again before the last one is returned to be rejected by the validation, the names without
+// the color are tried when the name of the mask color is flagged
+func randomLuchadorName(c *gin.Context, user *model.User, configs []model.Config) string {
+	locale := moderation.Locale(ds, c, user)
+
+	name, valid := tryRandomLuchadorNames(configs, locale)
+	if !valid {
+		name, _ = tryRandomLuchadorNames([]model.Config{}, locale)
+	}
+	return name
+}
+
+func tryRandomLuchadorNames(configs []model.Config, locale string) (string, bool) {
+	var name string
+	for attempt := 0; attempt < 10; attempt++ {
+		name = model.RandomNameForLocale(configs, locale)
+		if len(name) <= 40 && !ds.NameExist(0, name) && !moderation.ContainsBadWord(name, locale) {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// deleteLuchador godoc
+// @Summary Deletes one of the luchadors of the current user, the last luchador is kept
 // @Accept json
 // @Produce json
-// @Param name path string true "GameDefinition name"
-// @Success 200 {object} model.GameDefinition
+// @Param id path int true "Luchador ID"
+// @Success 200 {array} model.GameComponent
 // @Security ApiKeyAuth
-// @Router /internal/game-definition/{name} [get]
-func getGameDefinitionByName(c *gin.Context) {
-
-	name := c.Param("name")
-
-	log.WithFields(log.Fields{
-		"name": name,
-	}).Info("getGameDefinition")
+// @Router /private/luchadors/{id} [delete]
+func deleteLuchador(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	luchador := findUserLuchadorParam(c, user, "deleteLuchador")
+	if luchador == nil {
+		return
+	}
 
-	gameDefinition := ds.FindGameDefinitionByName(name)
+	if ds.CountLuchadorsByUser(user.ID) <= 1 {
+		c.AbortWithStatusJSON(http.StatusConflict, "The last luchador can't be deleted")
+		return
+	}
 
-	log.WithFields(log.Fields{
-		"gameDefinition": gameDefinition,
-	}).Info("getGameDefinition")
+	err := ds.DeleteLuchador(luchador)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
 
-	c.JSON(http.StatusOK, gameDefinition)
+	c.JSON(http.StatusOK, ds.FindLuchadorsByUser(user.ID))
 }
 
-// getGameDefinitionByIDInternal godoc
-// @Summary find a game definition
+// setDefaultLuchador godoc
+// @Summary Sets the luchador used when the user doesn't choose one
 // @Accept json
 // @Produce json
-// @Param id path int true "GameDefinition id"
-// @Success 200 {object} model.GameDefinition
-// @Security ApiKeyAuth
+// @Param id path int true "Luchador ID"
+// @Success 200 {object} model.UserSetting
+// @Security ApiKeyAuth
+// @Router /private/luchadors/{id}/default [put]
+func setDefaultLuchador(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	luchador := findUserLuchadorParam(c, user, "setDefaultLuchador")
+	if luchador == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.SetDefaultLuchador(user, luchador.ID))
+}
+
+// the luchador of the id param owned by the user, nil when the request is aborted
+func findUserLuchadorParam(c *gin.Context, user *model.User, context string) *model.GameComponent {
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	luchador := ds.FindUserLuchador(user.ID, id)
+	if luchador == nil {
+		log.WithFields(log.Fields{
+			"id":      id,
+			"user.id": user.ID,
+		}).Info("Luchador not found on " + context)
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+	return luchador
+}
+
+// renameLuchador changes the luchador name with the same validations and update message
+// of updateLuchador, used by the moderators, nil when the luchador is not found
+func renameLuchador(c *gin.Context, luchadorID uint, name string) *model.UpdateLuchadorResponse {
+	luchador := ds.FindLuchadorByID(luchadorID)
+	if luchador == nil {
+		return nil
+	}
+
+	luchador.Name = name
+	response := validateLuchador(c, luchador)
+	if len(response.Errors) == 0 {
+		saveLuchador(luchador, &response)
+	}
+
+	return &response
+}
+
+func validateLuchador(c *gin.Context, luchador *model.GameComponent) model.UpdateLuchadorResponse {
+	response := model.UpdateLuchadorResponse{Errors: []string{}}
+
+	luchador.Name = cleanName(luchador.Name)
+
+	if len(luchador.Name) < 3 {
+		response.Errors = append(response.Errors, "Luchador name length should be at least 3 characters")
+	}
+
+	if len(luchador.Name) > 40 {
+		response.Errors = append(response.Errors, "Luchador name length should be less or equal to 40 characters")
+	}
+
+	if inappropriateText(c, "", model.MODERATION_CONTENT_LUCHADOR, luchador.ID, moderation.Field{Name: "name", Text: luchador.Name}) {
+		response.Errors = append(response.Errors, "Luchador name contains inappropriate language")
+	}
+
+	if ds.NameExist(luchador.ID, luchador.Name) {
+		response.Errors = append(response.Errors, "Luchador with this name already exists")
+	}
+
+	if len(response.Errors) > 0 {
+		response.Luchador = luchador
+
+		log.WithFields(log.Fields{
+			"luchador": luchador,
+			"response": response,
+		}).Debug("updateLuchador")
+	}
+
+	return response
+}
+
+func saveLuchador(luchador *model.GameComponent, response *model.UpdateLuchadorResponse) {
+	response.Luchador = ds.UpdateLuchador(luchador)
+
+	if response.Luchador == nil {
+		log.Info("Invalid Luchador when saving, missing ID?")
+		response.Errors = append(response.Errors, "Invalid Luchador when saving, missing ID?")
+	} else {
+		channel := fmt.Sprintf("luchador.%v.update", response.Luchador.ID)
+		luchadorUpdateJSON, _ := json.Marshal(response.Luchador)
+		message := string(luchadorUpdateJSON)
+		publisher.Publish(channel, message)
+	}
+
+	log.WithFields(log.Fields{
+		"response": response,
+		"action":   "after save",
+		"errors":   response.Errors,
+	}).Info("updateLuchador")
+}
+
+func cleanName(name string) string {
+	name = strings.TrimSpace(name)
+	return name
+}
+
+// checks the free text fields of the content changed by the current user with the locale,
+// or the locale of the user when empty, flagged fields are added to the moderation review queue
+func inappropriateText(c *gin.Context, locale string, contentType string, contentID uint, fields ...moderation.Field) bool {
+	user := httphelper.UserFromContext(c)
+	if locale == "" {
+		locale = moderation.Locale(ds, c, user)
+	}
+
+	flags := moderation.Inspect(ds, moderation.Content{
+		Type:   contentType,
+		ID:     contentID,
+		UserID: user.ID,
+		Locale: locale,
+		Fields: fields,
+	})
+	return len(flags) > 0
+}
+
+// getTutorialGameDefinition godoc
+// @Summary find tutorial GameDefinition
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.GameDefinition
+// @Security ApiKeyAuth
+// @Router /private/tutorial [get]
+func getTutorialGameDefinition(c *gin.Context) {
+
+	tutorials := ds.FindTutorialGameDefinition()
+
+	log.WithFields(log.Fields{
+		"tutorials": tutorials,
+	}).Info("getTutorialGameDefinition")
+
+	c.JSON(http.StatusOK, tutorials)
+}
+
+// getMaskConfig godoc
+// @Summary find maskConfig for a luchador
+// @Accept json
+// @Produce json
+// @Param id path int true "Luchador ID"
+// @Success 200 {array} model.Config
+// @Security ApiKeyAuth
+// @Router /private/mask-config/{id} [get]
+func getMaskConfig(c *gin.Context) {
+
+	id := c.Param("id")
+	aid, err := strconv.Atoi(id)
+	if err != nil {
+		log.Info("Invalid ID")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id": aid,
+	}).Info("getMaskConfig")
+
+	configs := ds.FindMaskConfig(uint(aid))
+
+	log.WithFields(log.Fields{
+		"configs": configs,
+	}).Info("getMaskConfig")
+
+	c.JSON(http.StatusOK, configs)
+}
+
+// getMaskSchema godoc
+// @Summary find the shapes and colors accepted in the luchador mask configs
+// @Accept json
+// @Produce json
+// @Success 200 {object} mask.Schema
+// @Security ApiKeyAuth
+// @Router /private/mask-schema [get]
+func getMaskSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, mask.CurrentSchema())
+}
+
+// getMaskCatalogue godoc
+// @Summary find the shapes and colors of the luchador mask with the items locked for the user level
+// @Accept json
+// @Produce json
+// @Success 200 {object} mask.Catalogue
+// @Security ApiKeyAuth
+// @Router /private/mask-catalogue [get]
+func getMaskCatalogue(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	level := ds.FindUserLevelByUserID(user.ID).Level
+
+	c.JSON(http.StatusOK, mask.CatalogueForLevel(level))
+}
+
+// getGameDefinitionByName godoc
+// @Summary find a game definition
+// @Accept json
+// @Produce json
+// @Param name path string true "GameDefinition name"
+// @Success 200 {object} model.GameDefinition
+// @Security ApiKeyAuth
+// @Router /internal/game-definition/{name} [get]
+func getGameDefinitionByName(c *gin.Context) {
+
+	name := c.Param("name")
+
+	log.WithFields(log.Fields{
+		"name": name,
+	}).Info("getGameDefinition")
+
+	gameDefinition := ds.FindGameDefinitionByName(name)
+
+	log.WithFields(log.Fields{
+		"gameDefinition": gameDefinition,
+	}).Info("getGameDefinition")
+
+	c.JSON(http.StatusOK, gameDefinition)
+}
+
+// getGameDefinitionByIDInternal godoc
+// @Summary find a game definition
+// @Accept json
+// @Produce json
+// @Param id path int true "GameDefinition id"
+// @Success 200 {object} model.GameDefinition
+// @Security ApiKeyAuth
 // @Router /internal/game-definition-id/{id} [get]
 func getGameDefinitionByIDInternal(c *gin.Context) {
 	getGameDefinitionByID(c)
@@ -649,7 +1168,7 @@ func getGameDefinition(c *gin.Context) {
 }
 
 // getRandomMaskConfig godoc
-// @Summary create random maskConfig
+// @Summary create random maskConfig with the items unlocked for the user level
 // @Accept json
 // @Produce json
 // @Success 200 {array} model.Config
@@ -658,7 +1177,8 @@ func getGameDefinition(c *gin.Context) {
 func getRandomMaskConfig(c *gin.Context) {
 
 	log.Info("getRandomMaskConfig")
-	configs := model.RandomConfig()
+	user := httphelper.UserFromContext(c)
+	configs := model.RandomConfigForLevel(ds.FindUserLevelByUserID(user.ID).Level)
 
 	log.WithFields(log.Fields{
 		"configs": configs,
@@ -668,7 +1188,7 @@ func getRandomMaskConfig(c *gin.Context) {
 }
 
 // getBulkRandomMaskConfig godoc
-// @Summary create random maskConfig in bulk
+// @Summary create random maskConfig in bulk with the items unlocked for the user level
 // @Accept json
 // @Produce json
 // @Param amount path int true "Amount of random configs, max 2048"
@@ -693,10 +1213,12 @@ func getBulkRandomMaskConfig(c *gin.Context) {
 		"amount": amount,
 	}).Info("getBulkRandomMaskConfig")
 
+	user := httphelper.UserFromContext(c)
+	level := ds.FindUserLevelByUserID(user.ID).Level
 	result := make([]model.BulkConfig, 0)
 
 	for i := 0; i < amount; i++ {
-		configs := model.RandomConfig()
+		configs := model.RandomConfigForLevel(level)
 		result = append(result, model.BulkConfig{Configs: configs})
 	}
 
@@ -969,7 +1491,10 @@ func joinMatch(c *gin.Context) {
 		return
 	}
 
-	// make sure it will join with the luchador associated with the user
+	// make sure it will join with a luchador of the user
+	if selected := ds.FindUserLuchador(user.ID, joinMatch.LuchadorID); selected != nil {
+		luchador = selected
+	}
 	joinMatch.LuchadorID = luchador.ID
 
 	var match *model.Match
@@ -997,7 +1522,7 @@ func joinMatch(c *gin.Context) {
 }
 
 // getLuchadorByID godoc
-// @Summary find Luchador by ID
+// @Summary find Luchador by ID with the codes of the loadout of the game definition
 // @Accept json
 // @Produce json
 // @Param request body model.FindLuchadorWithGamedefinition true "FindLuchadorWithGamedefinition"
@@ -1026,13 +1551,7 @@ func getLuchadorByIDAndGamedefinitionID(c *gin.Context) {
 		return
 	}
 
-	filteredCodes := make([]model.Code, 0)
-	for _, code := range luchador.Codes {
-		if code.GameDefinitionID == parameters.GameDefinitionID {
-			filteredCodes = append(filteredCodes, code)
-		}
-	}
-	luchador.Codes = filteredCodes
+	luchador.Codes = model.LoadoutCodes(luchador.Codes, parameters.GameDefinitionID)
 
 	log.WithFields(log.Fields{
 		"getLuchador": model.LogGameComponent(luchador),
@@ -1050,6 +1569,465 @@ func getReady(c *gin.Context) {
 	c.JSON(http.StatusOK, "ok")
 }
 
+// updateUserProfile godoc
+// @Summary update the display name, avatar and profile visibility of the current user
+// @Accept json
+// @Produce json
+// @Param request body model.UserProfileRequest true "UserProfileRequest"
+// @Success 200 {object} model.User
+// @Security ApiKeyAuth
+// @Router /private/user/profile [put]
+func updateUserProfile(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	var request model.UserProfileRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on updateUserProfile")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.DisplayName = strings.TrimSpace(request.DisplayName)
+	message := ""
+
+	request.Locale = strings.TrimSpace(request.Locale)
+
+	if request.Locale != "" && !moderation.Default().SupportsLocale(request.Locale) {
+		message = "Locale should be one of " + strings.Join(moderation.Default().Locales(), ", ")
+	} else if utf8.RuneCountInString(request.DisplayName) > 40 {
+		message = "Display name length should be less or equal to 40 characters"
+	} else if inappropriateText(c, request.Locale, model.MODERATION_CONTENT_PROFILE, user.ID, moderation.Field{Name: "displayName", Text: request.DisplayName}) {
+		message = "Display name contains inappropriate language"
+	} else if request.ProfileVisibility != "" &&
+		request.ProfileVisibility != model.PROFILE_VISIBILITY_PRIVATE &&
+		request.ProfileVisibility != model.PROFILE_VISIBILITY_CLASSMATES {
+		message = "Profile visibility should be private or classmates"
+	}
+
+	var avatar *model.Media
+	if message == "" && request.AvatarMediaID != 0 {
+		avatar = ds.FindMedia(request.AvatarMediaID)
+		if avatar == nil || avatar.UserID != user.ID {
+			message = "Avatar should be a media uploaded by the current user"
+		}
+	}
+
+	if message != "" {
+		log.WithFields(log.Fields{
+			"user":    user.ID,
+			"message": message,
+		}).Info("Invalid profile on updateUserProfile")
+		c.AbortWithStatusJSON(http.StatusBadRequest, message)
+		return
+	}
+
+	result := ds.UpdateUserProfile(user, request, avatar)
+	c.JSON(http.StatusOK, result)
+}
+
+// getClassmates godoc
+// @Summary find the profiles of the classroom students, available to the classroom members
+// @Accept json
+// @Produce json
+// @Param id path int true "Classroom id"
+// @Success 200 {array} model.ClassmateProfile
+// @Security ApiKeyAuth
+// @Router /private/classroom/{id}/classmates [get]
+func getClassmates(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "getClassmates")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	classroom := ds.FindClassroomByID(id)
+	if classroom.ID == 0 || !datasource.IsClassroomMember(classroom, user.ID) {
+		log.WithFields(log.Fields{
+			"classroom": id,
+			"user":      user.ID,
+		}).Info("User is not a classroom member on getClassmates")
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.FindClassmates(classroom))
+}
+
+// exportAccount godoc
+// @Summary export all the data of the current user as json or as a zip archive with the luchador code files
+// @Produce json
+// @Param format query string false "json or zip"
+// @Success 200 {object} model.AccountExport
+// @Security ApiKeyAuth
+// @Router /private/account/export [get]
+func exportAccount(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	export := ds.BuildAccountExport(user)
+	export.PageEvents = eventsDS.FindEventsByUser(user.ID)
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.Header("Content-Disposition", "attachment; filename=account.json")
+		c.JSON(http.StatusOK, export)
+	case "zip":
+		content, err := buildAccountArchive(export)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Error creating account archive")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=account.zip")
+		c.Data(http.StatusOK, "application/zip", content)
+	default:
+		c.AbortWithStatus(http.StatusBadRequest)
+	}
+}
+
+// account.json with all the data and one file per luchador code
+func buildAccountArchive(export *model.AccountExport) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	archive := zip.NewWriter(buffer)
+
+	files := map[string][]byte{}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files["account.json"] = data
+
+	for _, luchador := range export.Luchadors {
+		for _, code := range luchador.Codes {
+			folder := fmt.Sprintf("luchador-%v/gamedefinition-%v", luchador.ID, code.GameDefinitionID)
+			files[fmt.Sprintf("%v/%v.lua", folder, code.Event)] = []byte(code.Script)
+			if code.Blockly != "" {
+				files[fmt.Sprintf("%v/%v.blockly.xml", folder, code.Event)] = []byte(code.Blockly)
+			}
+		}
+	}
+
+	for name, content := range files {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = writer.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// getAccountDeletionRequests godoc
+// @Summary find the account deletion requests of the current user
+// @Produce json
+// @Success 200 {array} model.AccountDeletionRequest
+// @Security ApiKeyAuth
+// @Router /private/account/delete [get]
+func getAccountDeletionRequests(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	result := ds.FindAccountDeletionRequestsByUser(user.ID)
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteAccount godoc
+// @Summary request the deletion of the current user account
+// @Description students enrolled in a classroom wait for the approval of a teacher, other accounts are deleted immediately
+// @Accept json
+// @Produce json
+// @Param request body model.AccountDeletionRequestBody true "anonymize or delete"
+// @Success 200 {object} model.AccountDeletionRequest
+// @Security ApiKeyAuth
+// @Router /private/account/delete [post]
+func deleteAccount(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	var body model.AccountDeletionRequestBody
+	err := c.BindJSON(&body)
+	if err != nil || (body.Mode != model.ACCOUNT_DELETION_ANONYMIZE && body.Mode != model.ACCOUNT_DELETION_DELETE) {
+		log.Info("Invalid body content on deleteAccount")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if ds.FindPendingAccountDeletionRequest(user.ID) != nil {
+		log.WithFields(log.Fields{
+			"user": user.ID,
+		}).Info("Account deletion already requested")
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+
+	request := ds.AddAccountDeletionRequest(&model.AccountDeletionRequest{
+		UserID:   user.ID,
+		Username: user.Username,
+		Mode:     body.Mode,
+		Status:   model.ACCOUNT_DELETION_PENDING,
+	})
+
+	if !ds.IsEnrolledStudent(user.ID) {
+		err = completeAccountDeletion(request)
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// anonymizes or deletes the user in the main and events datasources, the files of the
+// removed media are deleted from the store after the transaction
+func completeAccountDeletion(request *model.AccountDeletionRequest) error {
+	keys := ds.FindAccountMediaKeys(request.UserID)
+
+	var err error
+	if request.Mode == model.ACCOUNT_DELETION_DELETE {
+		err = ds.DeleteAccount(request.UserID)
+		if err == nil {
+			eventsDS.DeleteEventsByUser(request.UserID)
+			request.Username = ""
+		}
+	} else {
+		err = ds.AnonymizeAccount(request.UserID)
+		if err == nil {
+			eventsDS.AnonymizeEventsByUser(request.UserID)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	storage.DeleteKeys(mediaStore, keys)
+
+	now := time.Now()
+	request.Status = model.ACCOUNT_DELETION_COMPLETED
+	request.CompletedAt = &now
+	ds.UpdateAccountDeletionRequest(request)
+	return nil
+}
+
+// getPendingAccountDeletionRequests godoc
+// @Summary find the pending account deletion requests of the students of the current user classrooms
+// @Produce json
+// @Success 200 {array} model.AccountDeletionRequest
+// @Security ApiKeyAuth
+// @Router /dashboard/account/deletion-request [get]
+func getPendingAccountDeletionRequests(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	result := ds.FindPendingAccountDeletionRequestsByOwner(user.ID)
+	c.JSON(http.StatusOK, result)
+}
+
+// finds a pending request of a student of the current user
+func findPendingStudentDeletionRequest(c *gin.Context, context string) *model.AccountDeletionRequest {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	request := ds.FindAccountDeletionRequest(id)
+	if request == nil || request.Status != model.ACCOUNT_DELETION_PENDING ||
+		!ds.IsStudentOfOwner(request.UserID, user.ID) {
+		log.WithFields(log.Fields{
+			"id":   id,
+			"user": user.ID,
+		}).Info("Pending deletion request not found for the teacher on " + context)
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+
+	now := time.Now()
+	request.ReviewedByUserID = user.ID
+	request.ReviewedAt = &now
+	return request
+}
+
+// approveAccountDeletionRequest godoc
+// @Summary approve the account deletion of a student, the account is deleted immediately
+// @Produce json
+// @Param id path int true "AccountDeletionRequest id"
+// @Success 200 {object} model.AccountDeletionRequest
+// @Security ApiKeyAuth
+// @Router /dashboard/account/deletion-request/{id}/approve [post]
+func approveAccountDeletionRequest(c *gin.Context) {
+	request := findPendingStudentDeletionRequest(c, "approveAccountDeletionRequest")
+	if request == nil {
+		return
+	}
+
+	err := completeAccountDeletion(request)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// rejectAccountDeletionRequest godoc
+// @Summary reject the account deletion of a student
+// @Produce json
+// @Param id path int true "AccountDeletionRequest id"
+// @Success 200 {object} model.AccountDeletionRequest
+// @Security ApiKeyAuth
+// @Router /dashboard/account/deletion-request/{id}/reject [post]
+func rejectAccountDeletionRequest(c *gin.Context) {
+	request := findPendingStudentDeletionRequest(c, "rejectAccountDeletionRequest")
+	if request == nil {
+		return
+	}
+
+	request.Status = model.ACCOUNT_DELETION_REJECTED
+	c.JSON(http.StatusOK, ds.UpdateAccountDeletionRequest(request))
+}
+
+// getSessions godoc
+// @Summary find the active sessions of the current user
+// @Produce json
+// @Success 200 {array} model.Session
+// @Security ApiKeyAuth
+// @Router /private/session [get]
+func getSessions(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	result := ds.FindActiveSessions(user.ID, time.Now())
+	c.JSON(http.StatusOK, result)
+}
+
+// logout godoc
+// @Summary revoke the current session, the token is rejected after the logout
+// @Produce json
+// @Success 200 {object} model.Session
+// @Security ApiKeyAuth
+// @Router /private/logout [post]
+func logout(c *gin.Context) {
+	session := httphelper.SessionFromContext(c)
+	if session == nil {
+		log.Info("No session to logout")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.RevokeSession(session))
+}
+
+// revokeSession godoc
+// @Summary revoke one of the sessions of the current user
+// @Produce json
+// @Param uuid path string true "Session UUID"
+// @Success 200 {object} model.Session
+// @Security ApiKeyAuth
+// @Router /private/session/{uuid} [delete]
+func revokeSession(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	session := ds.FindSessionByUUID(c.Param("uuid"))
+	if session == nil || session.UserID != user.ID {
+		log.WithFields(log.Fields{
+			"uuid": c.Param("uuid"),
+			"user": user,
+		}).Info("Session not found for the current user")
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.RevokeSession(session))
+}
+
+// getUserSessions godoc
+// @Summary find the active sessions of a user
+// @Produce json
+// @Param id path int true "User id"
+// @Success 200 {array} model.Session
+// @Security ApiKeyAuth
+// @Router /dashboard/user/{id}/session [get]
+func getUserSessions(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "getUserSessions")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result := ds.FindActiveSessions(id, time.Now())
+	c.JSON(http.StatusOK, result)
+}
+
+// revokeUserSessions godoc
+// @Summary revoke all the sessions of a user, returns the amount of revoked sessions
+// @Produce json
+// @Param id path int true "User id"
+// @Success 200 {integer} int
+// @Security ApiKeyAuth
+// @Router /dashboard/user/{id}/session [delete]
+func revokeUserSessions(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "revokeUserSessions")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if ds.FindUserByID(id) == nil {
+		log.WithFields(log.Fields{
+			"id": id,
+		}).Info("User not found on revokeUserSessions")
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.RevokeUserSessions(id))
+}
+
+// getDevUsers godoc
+// @Summary list the users of the dev auth provider, only available for local development
+// @Produce json
+// @Success 200 {array} auth.JWTUser
+// @Router /public/dev-users [get]
+func getDevUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.GetDevProvider().Users())
+}
+
+// devLogin godoc
+// @Summary issues a session token for a dev auth provider user, only available for local development
+// @Accept json
+// @Produce json
+// @Param request body model.DevLoginRequest true "DevLoginRequest"
+// @Success 200 {object} model.DevLoginResponse
+// @Router /public/dev-login [post]
+func devLogin(c *gin.Context) {
+	var request model.DevLoginRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on devLogin")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	token, expiresAt, err := auth.GetDevProvider().Login(request.Username)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Info("Invalid user on devLogin")
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.DevLoginResponse{Token: token, ExpiresAt: expiresAt})
+}
+
 // addMatchPartipant godoc
 // @Summary Adds luchador to a match
 // @Accept json
@@ -1223,6 +2201,35 @@ func addMatchMetric(c *gin.Context) {
 	c.JSON(http.StatusOK, "")
 }
 
+// addMatchEvent godoc
+// @Summary saves a narrative event reached by a luchador during the match
+// @Accept json
+// @Produce json
+// @Param request body model.MatchEvent true "MatchEvent"
+// @Success 200 {object} model.MatchEvent
+// @Security ApiKeyAuth
+// @Router /internal/match-event [post]
+func addMatchEvent(c *gin.Context) {
+	var event *model.MatchEvent
+	err := c.BindJSON(&event)
+	if err != nil {
+		log.Info("Invalid body content on addMatchEvent")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if ds.FindMatch(event.MatchID).ID == 0 || ds.FindLuchadorByID(event.LuchadorID) == nil {
+		log.WithFields(log.Fields{
+			"event": event,
+		}).Error("Match or luchador not found on addMatchEvent")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result := ds.AddMatchEvent(event)
+	c.JSON(http.StatusOK, result)
+}
+
 // getClassroom godoc
 // @Summary find all Classroom
 // @Accept json
@@ -1241,6 +2248,23 @@ func getClassroom(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// finds the classroom checking if the current user is the owner,
+// aborts the request and returns nil otherwise
+func findOwnedClassroom(c *gin.Context, id uint) *model.Classroom {
+	user := httphelper.UserFromContext(c)
+	classroom := ds.FindClassroomByID(id)
+	if classroom.OwnerID != user.ID {
+		log.WithFields(log.Fields{
+			"classroom": classroom,
+			"user":      user,
+		}).Warn("Current user is not the owner of this classroom")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil
+	}
+
+	return classroom
+}
+
 // getClassroomStudents godoc
 // @Summary find all Classroom students
 // @Accept json
@@ -1260,14 +2284,8 @@ func getClassroomStudents(c *gin.Context) {
 	}
 
 	// check if current user can see the list of students
-	user := httphelper.UserFromContext(c)
-	classroom := ds.FindClassroomByID(id)
-	if classroom.OwnerID != user.ID {
-		log.WithFields(log.Fields{
-			"classroom": classroom,
-			"user":      user,
-		}).Warn("Current user is not the owner of this classroom")
-		c.AbortWithStatus(http.StatusBadRequest)
+	classroom := findOwnedClassroom(c, id)
+	if classroom == nil {
 		return
 	}
 
@@ -1282,6 +2300,175 @@ func getClassroomStudents(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// getClassroomReport godoc
+// @Summary progress report of the classroom students
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Param id path int true "Classroom id"
+// @Param format query string false "use csv to export the report as CSV"
+// @Success 200 {object} model.ClassroomReport
+// @Security ApiKeyAuth
+// @Router /dashboard/classroom/report/{id} [get]
+func getClassroomReport(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "getClassroomReport")
+	if err != nil {
+		log.Info("Invalid body content on getClassroomReport")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// only the owner can see the classroom report
+	classroom := findOwnedClassroom(c, id)
+	if classroom == nil {
+		return
+	}
+
+	report := ds.BuildClassroomReport(classroom)
+	for n, student := range report.Students {
+		event := eventsDS.FindLastEventByUser(student.UserID)
+		if event != nil {
+			report.Students[n].LastSeen = &event.CreatedAt
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"classroomID": report.ClassroomID,
+		"students":    len(report.Students),
+	}).Info("getClassroomReport")
+
+	if c.Query("format") == "csv" {
+		fileName := fmt.Sprintf("classroom-%v-report.csv", classroom.ID)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%v", fileName))
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+
+		writer := csv.NewWriter(c.Writer)
+		writer.WriteAll(classroomReportToCSV(report))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func classroomReportToCSV(report *model.ClassroomReport) [][]string {
+	formatTime := func(value *time.Time) string {
+		if value == nil {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339)
+	}
+
+	rows := [][]string{{
+		"studentID", "userID", "username", "level", "playedTutorial", "tutorialsCompleted",
+		"matchesPlayed", "recentMatches", "codeVersions", "lastCodeUpdate", "lastSeen",
+	}}
+
+	for _, student := range report.Students {
+		matches := make([]string, len(student.RecentMatches))
+		for n, match := range student.RecentMatches {
+			matches[n] = fmt.Sprintf("%v:%v", match.GameDefinitionName, match.Score)
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprint(student.StudentID),
+			fmt.Sprint(student.UserID),
+			student.Username,
+			fmt.Sprint(student.Level),
+			strconv.FormatBool(student.PlayedTutorial),
+			fmt.Sprint(student.TutorialsCompleted),
+			fmt.Sprint(student.MatchesPlayed),
+			strings.Join(matches, "; "),
+			fmt.Sprint(student.CodeVersions),
+			formatTime(student.LastCodeUpdate),
+			formatTime(student.LastSeen),
+		})
+	}
+
+	return rows
+}
+
+// importClassroomRoster godoc
+// @Summary enrolls a CSV list of usernames in the classroom
+// @Description one username per line in the first column, a "username" header line is optional.
+// @Description the roster can be sent as the request body or as the "roster" multipart file
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Classroom id"
+// @Param dryRun query bool false "validates the roster without saving"
+// @Success 200 {object} model.RosterImportResponse
+// @Security ApiKeyAuth
+// @Router /dashboard/classroom/{id}/roster [post]
+func importClassroomRoster(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "importClassroomRoster")
+	if err != nil {
+		log.Info("Invalid body content on importClassroomRoster")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	classroom := findOwnedClassroom(c, id)
+	if classroom == nil {
+		return
+	}
+
+	var reader io.Reader = c.Request.Body
+	file, _, err := c.Request.FormFile("roster")
+	if err == nil {
+		defer file.Close()
+		reader = file
+	}
+
+	usernames, err := readRoster(reader)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Info("Invalid roster on importClassroomRoster")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	result := ds.ImportRoster(classroom, usernames, dryRun)
+
+	log.WithFields(log.Fields{
+		"classroomID":     classroom.ID,
+		"dryRun":          dryRun,
+		"enrolled":        len(result.Enrolled),
+		"alreadyEnrolled": len(result.AlreadyEnrolled),
+		"invalid":         len(result.Invalid),
+	}).Info("importClassroomRoster")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// reads the first column of the CSV roster skipping empty lines and the header
+func readRoster(reader io.Reader) ([]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	for n, record := range records {
+		username := strings.TrimSpace(record[0])
+		if n == 0 && strings.EqualFold(username, "username") {
+			continue
+		}
+		if username == "" {
+			continue
+		}
+		result = append(result, username)
+	}
+
+	return result, nil
+}
+
 // addClassroom godoc
 // @Summary add a Classroom
 // @Accept json
@@ -1301,6 +2488,17 @@ func addClassroom(c *gin.Context) {
 		return
 	}
 
+	classroom.Name = strings.TrimSpace(classroom.Name)
+	if classroom.Locale != "" && !moderation.Default().SupportsLocale(classroom.Locale) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Classroom locale should be one of "+strings.Join(moderation.Default().Locales(), ", "))
+		return
+	}
+
+	if inappropriateText(c, classroom.Locale, model.MODERATION_CONTENT_CLASSROOM, 0, moderation.Field{Name: "name", Text: classroom.Name}) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Classroom name contains inappropriate language")
+		return
+	}
+
 	classroom.OwnerID = user.ID
 
 	result := ds.AddClassroom(classroom)
@@ -1398,7 +2596,7 @@ func getClassroomAvailableMatchOwned(c *gin.Context) {
 	details := httphelper.UserDetailsFromContext(c)
 
 	// dont check ownership when user is a system editor
-	skipCheckOwnerShip := auth.UserBelongsToRole(details, auth.SystemEditorRole)
+	skipCheckOwnerShip := auth.UserHasPermission(details, auth.PermissionMatchAdmin)
 
 	result := ds.FindAvailableMatchOwnedByUser(details.User.ID, skipCheckOwnerShip)
 
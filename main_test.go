@@ -19,10 +19,17 @@ import (
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/events"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/setup"
 	"gitlab.com/robolucha/robolucha-api/test"
 	"gotest.tools/assert"
 )
 
+// the random luchador configs and names use the mask catalogue
+func TestMain(m *testing.M) {
+	setup.SetupMaskCatalogueFromFolder("metadata/mask")
+	os.Exit(m.Run())
+}
+
 func SetupMain(t *testing.T) {
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetOutput(os.Stdout)
@@ -174,7 +181,7 @@ func TestCreateGameComponent(t *testing.T) {
 	}).Debug("configs from luchador")
 
 	// all the Mask config items should be present
-	for _, color := range model.MaskColors {
+	for _, color := range model.MaskColors() {
 		found := false
 		for _, config := range luchadorFromDB.Configs {
 			if config.Key == color {
@@ -188,7 +195,7 @@ func TestCreateGameComponent(t *testing.T) {
 		}).Debug("Color found in luchador config")
 	}
 
-	for shape, _ := range model.MaskShapes {
+	for shape, _ := range model.MaskShapes() {
 		found := false
 		for _, config := range luchadorFromDB.Configs {
 			if config.Key == shape {
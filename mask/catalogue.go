@@ -0,0 +1,87 @@
+package mask
+
+import (
+	"fmt"
+	"sort"
+
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// CatalogueItem definition, Locked when the level of the user is below MinLevel
+type CatalogueItem struct {
+	model.MaskItem
+	Locked bool `json:"locked"`
+}
+
+// CatalogueShape definition, the items of the shape config Key
+type CatalogueShape struct {
+	Key   string          `json:"key"`
+	Items []CatalogueItem `json:"items"`
+}
+
+// Catalogue definition, the shapes and colors of the mask for a user with Level,
+// NameLocales are the locales of the random name word lists
+type Catalogue struct {
+	Version     string           `json:"version"`
+	Level       uint             `json:"level"`
+	Shapes      []CatalogueShape `json:"shapes"`
+	ColorKeys   []string         `json:"colorKeys"`
+	Colors      []CatalogueItem  `json:"colors"`
+	NameLocales []string         `json:"nameLocales"`
+}
+
+// CatalogueForLevel definition, the items of the mask catalogue locked for the level
+func CatalogueForLevel(level uint) Catalogue {
+	catalogue := model.GetMaskCatalogue()
+
+	result := Catalogue{
+		Version:     CurrentSchema().Version,
+		Level:       level,
+		Shapes:      make([]CatalogueShape, 0, len(catalogue.Shapes)),
+		ColorKeys:   append([]string{}, catalogue.ColorKeys...),
+		Colors:      catalogueItems(catalogue.Colors, level),
+		NameLocales: catalogue.NameLocales(),
+	}
+
+	for key, items := range catalogue.Shapes {
+		result.Shapes = append(result.Shapes, CatalogueShape{Key: key, Items: catalogueItems(items, level)})
+	}
+	sort.Slice(result.Shapes, func(i, j int) bool {
+		return result.Shapes[i].Key < result.Shapes[j].Key
+	})
+
+	return result
+}
+
+func catalogueItems(items []model.MaskItem, level uint) []CatalogueItem {
+	result := make([]CatalogueItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, CatalogueItem{MaskItem: item, Locked: item.MinLevel > level})
+	}
+	return result
+}
+
+// LockedConfigs definition, the errors of the configs changed to items locked for the level,
+// values kept from the current configs are accepted as the items could be unlocked before
+func LockedConfigs(configs []model.Config, current []model.Config, level uint) []string {
+	errors := make([]string, 0)
+	catalogue := model.GetMaskCatalogue()
+
+	currentValues := make(map[string]string)
+	for _, config := range current {
+		currentValues[config.Key] = config.Value
+	}
+
+	for _, config := range configs {
+		if value, found := currentValues[config.Key]; found && value == config.Value {
+			continue
+		}
+
+		minLevel, found := catalogue.MinLevel(config.Key, config.Value)
+		if found && minLevel > level {
+			errors = append(errors, fmt.Sprintf("Mask config %v is locked until level %v", config.Key, minLevel))
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,86 @@
+package mask
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+func TestCatalogueForLevel(t *testing.T) {
+	lockedItems := func(catalogue Catalogue) int {
+		result := 0
+		for _, shape := range catalogue.Shapes {
+			for _, item := range shape.Items {
+				if item.Locked {
+					result++
+				}
+			}
+		}
+		for _, item := range catalogue.Colors {
+			if item.Locked {
+				result++
+			}
+		}
+		return result
+	}
+
+	catalogue := CatalogueForLevel(0)
+	assert.Equal(t, len(model.MaskShapes()), len(catalogue.Shapes))
+	assert.Equal(t, model.MaskColors(), catalogue.ColorKeys)
+	assert.Equal(t, CurrentSchema().Version, catalogue.Version)
+	assert.True(t, lockedItems(catalogue) > 0)
+	assert.True(t, lockedItems(CatalogueForLevel(5)) < lockedItems(catalogue))
+	assert.Equal(t, 0, lockedItems(CatalogueForLevel(100)))
+}
+
+func TestLockedConfigs(t *testing.T) {
+	current := []model.Config{{Key: "mask.shape", Value: "segunda_cor0018.png"}}
+	configs := []model.Config{
+		{Key: "mask.shape", Value: "segunda_cor0018.png"},
+		{Key: "mouth.shape", Value: "boca0022.png"},
+		{Key: "eyes.shape", Value: "olho0001.png"},
+		{Key: "eyes.color", Value: "#7a1f3d"},
+	}
+
+	// the current mask shape was unlocked before
+	assert.Equal(t, []string{
+		"Mask config mouth.shape is locked until level 10",
+		"Mask config eyes.color is locked until level 5",
+	}, LockedConfigs(configs, current, 0))
+
+	assert.Equal(t, []string{"Mask config mouth.shape is locked until level 10"}, LockedConfigs(configs, current, 5))
+	assert.Empty(t, LockedConfigs(configs, current, 10))
+	assert.Equal(t, 1, len(LockedConfigs(configs, []model.Config{}, 10)))
+
+	// the items of the masks created before the levels are never locked
+	assert.Empty(t, LockedConfigs([]model.Config{
+		{Key: "mask.shape", Value: "segunda_cor0015.png"},
+		{Key: "mouth.shape", Value: "boca0020.png"},
+		{Key: "face.shape", Value: "rosto0010.png"},
+		{Key: "eyes.color", Value: "#9a1c1f"},
+	}, []model.Config{}, 0))
+}
+
+func TestRandomConfigForLevel(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		configs := model.RandomConfig()
+		assert.Empty(t, LockedConfigs(configs, []model.Config{}, 0))
+		assert.Empty(t, CurrentSchema().Validate(configs))
+	}
+
+	assert.Empty(t, CurrentSchema().Validate(model.RandomConfigForLevel(100)))
+}
+
+func TestRandomNameForLocale(t *testing.T) {
+	configs := []model.Config{{Key: "mask.primary.color.name", Value: "Potato"}}
+
+	assert.True(t, strings.HasPrefix(model.RandomName(configs), "El "))
+	assert.True(t, strings.HasPrefix(model.RandomNameForLocale(configs, "pt"), "O "))
+	assert.True(t, strings.HasPrefix(model.RandomNameForLocale(configs, "en"), "The "))
+	assert.Contains(t, model.RandomNameForLocale(configs, "en"), " Potato ")
+
+	// locales without word lists use the default
+	assert.True(t, strings.HasPrefix(model.RandomNameForLocale(configs, "fr"), "El "))
+}
@@ -0,0 +1,327 @@
+package mask
+
+import (
+	"bytes"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+const assetsFolderEnv = "MASK_ASSETS_FOLDER"
+const defaultAssetsFolder = "mask-assets"
+
+// rendered images kept in memory, the cache is cleared when it is full
+const maxCachedImages = 512
+
+// image formats of the renderer
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// ErrAssetNotFound the image of a shape is not in the assets folder
+var ErrAssetNotFound = errors.New("mask asset not found")
+
+// Layer definition, the image of the shape is tinted with the color
+type Layer struct {
+	Shape string
+	Color string
+}
+
+// Layers from the bottom to the top of the mask
+var Layers = []Layer{
+	{Shape: "face.shape", Color: "mask.primary.color"},
+	{Shape: "mask.shape", Color: "mask.secondary.color"},
+	{Shape: "mask.decoration.bottom.shape", Color: "mask.decoration.bottom.color"},
+	{Shape: "mask.decoration.top.shape", Color: "mask.decoration.top.color"},
+	{Shape: "eyes.shape", Color: "eyes.color"},
+	{Shape: "mouth.shape", Color: "skin.color"},
+}
+
+// Image definition, Hash identifies the configs used by the image
+type Image struct {
+	Data        []byte
+	ContentType string
+	Hash        string
+}
+
+type asset struct {
+	data  []byte
+	image image.Image
+}
+
+// Renderer definition, composites the layers of the mask from the shape images
+// in the assets folder, the locks only guard the maps and the images are rendered
+// concurrently
+type Renderer struct {
+	folder      string
+	assetsMutex sync.RWMutex
+	assets      map[string]*asset
+	cacheMutex  sync.RWMutex
+	cache       map[string]*Image
+}
+
+// NewRenderer creates a renderer reading the shape images from the folder
+func NewRenderer(folder string) *Renderer {
+	return &Renderer{
+		folder: folder,
+		assets: make(map[string]*asset),
+		cache:  make(map[string]*Image),
+	}
+}
+
+// NewRendererFromEnv creates a renderer reading the shape images from MASK_ASSETS_FOLDER,
+// the folder has one png named as each shape of metadata/mask/shapes.json and the
+// masks are not rendered when it is missing
+func NewRendererFromEnv() *Renderer {
+	folder := os.Getenv(assetsFolderEnv)
+	if folder == "" {
+		folder = defaultAssetsFolder
+	}
+
+	if _, err := os.Stat(folder); err != nil {
+		log.WithFields(log.Fields{
+			"folder": folder,
+			"error":  err,
+		}).Warn("Mask assets folder not found, set " + assetsFolderEnv)
+	} else {
+		log.WithFields(log.Fields{
+			"folder": folder,
+		}).Info("Mask assets folder")
+	}
+
+	return NewRenderer(folder)
+}
+
+// Hash definition, the hash of the shapes and colors of the layers
+func Hash(configs []model.Config) string {
+	values := layerValues(configs)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%v=%v\n", key, values[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// only the configs used by the layers
+func layerValues(configs []model.Config) map[string]string {
+	used := make(map[string]bool)
+	for _, layer := range Layers {
+		used[layer.Shape] = true
+		used[layer.Color] = true
+	}
+
+	values := make(map[string]string)
+	for _, config := range configs {
+		if used[config.Key] {
+			values[config.Key] = config.Value
+		}
+	}
+	return values
+}
+
+// Render definition, the image of the configs in the format, the images are cached by the hash
+func (renderer *Renderer) Render(configs []model.Config, format string) (*Image, error) {
+	if format != FormatPNG && format != FormatSVG {
+		return nil, fmt.Errorf("invalid mask format %q", format)
+	}
+
+	hash := Hash(configs)
+	key := format + "-" + hash
+
+	renderer.cacheMutex.RLock()
+	cached, found := renderer.cache[key]
+	renderer.cacheMutex.RUnlock()
+	if found {
+		return cached, nil
+	}
+
+	layers, err := renderer.loadLayers(layerValues(configs))
+	if err != nil {
+		return nil, err
+	}
+
+	result := Image{Hash: hash}
+	if format == FormatPNG {
+		result.ContentType = "image/png"
+		result.Data, err = renderPNG(layers)
+	} else {
+		result.ContentType = "image/svg+xml"
+		result.Data = renderSVG(layers)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	renderer.cacheMutex.Lock()
+	if len(renderer.cache) >= maxCachedImages {
+		renderer.cache = make(map[string]*Image)
+	}
+	renderer.cache[key] = &result
+	renderer.cacheMutex.Unlock()
+
+	return &result, nil
+}
+
+type tintedLayer struct {
+	asset *asset
+	color [3]uint8
+}
+
+// layers with shapes outside of the catalogue are not rendered
+func (renderer *Renderer) loadLayers(values map[string]string) ([]tintedLayer, error) {
+	layers := make([]tintedLayer, 0, len(Layers))
+
+	for _, layer := range Layers {
+		shape := values[layer.Shape]
+		if !validShape(layer.Shape, shape) {
+			log.WithFields(log.Fields{
+				"shape": layer.Shape,
+				"value": shape,
+			}).Debug("mask layer not rendered")
+			continue
+		}
+
+		asset, err := renderer.loadAsset(shape)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, tintedLayer{asset: asset, color: parseColor(values[layer.Color])})
+	}
+
+	return layers, nil
+}
+
+func validShape(key string, value string) bool {
+	for _, option := range model.MaskShapes()[key] {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// the assets are decoded once and kept in memory
+func (renderer *Renderer) loadAsset(name string) (*asset, error) {
+	renderer.assetsMutex.RLock()
+	loaded, found := renderer.assets[name]
+	renderer.assetsMutex.RUnlock()
+	if found {
+		return loaded, nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(renderer.folder, filepath.Base(name)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAssetNotFound, err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid mask asset %q, %v", name, err)
+	}
+
+	loaded = &asset{data: data, image: img}
+	renderer.assetsMutex.Lock()
+	renderer.assets[name] = loaded
+	renderer.assetsMutex.Unlock()
+	return loaded, nil
+}
+
+// parses "#RRGGBB", invalid colors keep the shape colors
+func parseColor(value string) [3]uint8 {
+	white := [3]uint8{255, 255, 255}
+
+	value = strings.TrimPrefix(value, "#")
+	if len(value) != 6 {
+		return white
+	}
+
+	parsed, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return white
+	}
+	return [3]uint8{uint8(parsed >> 16), uint8(parsed >> 8), uint8(parsed)}
+}
+
+// the size of the largest layer
+func bounds(layers []tintedLayer) image.Rectangle {
+	result := image.Rect(0, 0, 1, 1)
+	for _, layer := range layers {
+		result = result.Union(layer.asset.image.Bounds())
+	}
+	return result
+}
+
+// multiplies the colors of the shape by the color of the layer keeping the transparency
+func tint(img image.Image, color [3]uint8) *image.NRGBA {
+	result := imaging.Clone(img)
+	for n := 0; n < len(result.Pix); n += 4 {
+		result.Pix[n] = uint8(uint16(result.Pix[n]) * uint16(color[0]) / 255)
+		result.Pix[n+1] = uint8(uint16(result.Pix[n+1]) * uint16(color[1]) / 255)
+		result.Pix[n+2] = uint8(uint16(result.Pix[n+2]) * uint16(color[2]) / 255)
+	}
+	return result
+}
+
+func renderPNG(layers []tintedLayer) ([]byte, error) {
+	canvas := image.NewNRGBA(bounds(layers))
+	for _, layer := range layers {
+		tinted := tint(layer.asset.image, layer.color)
+		draw.Draw(canvas, layer.asset.image.Bounds(), tinted, tinted.Bounds().Min, draw.Over)
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, canvas)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding mask, %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// the shapes are embedded in the svg and tinted by a color matrix filter
+func renderSVG(layers []tintedLayer) []byte {
+	size := bounds(layers)
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="%d %d %d %d">`,
+		size.Dx(), size.Dy(), size.Min.X, size.Min.Y, size.Dx(), size.Dy())
+	buffer.WriteString("<defs>")
+	for n, layer := range layers {
+		fmt.Fprintf(&buffer, `<filter id="layer%d" color-interpolation-filters="sRGB"><feColorMatrix type="matrix" values="%.4f 0 0 0 0 0 %.4f 0 0 0 0 0 %.4f 0 0 0 0 0 1 0"/></filter>`,
+			n, float64(layer.color[0])/255, float64(layer.color[1])/255, float64(layer.color[2])/255)
+	}
+	buffer.WriteString("</defs>")
+
+	for n, layer := range layers {
+		rect := layer.asset.image.Bounds()
+		fmt.Fprintf(&buffer, `<image x="%d" y="%d" width="%d" height="%d" filter="url(#layer%d)" xlink:href="data:image/png;base64,%s"/>`,
+			rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy(), n, b64.StdEncoding.EncodeToString(layer.asset.data))
+	}
+	buffer.WriteString("</svg>")
+
+	return buffer.Bytes()
+}
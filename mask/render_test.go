@@ -0,0 +1,148 @@
+package mask
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// face shapes are white squares, the other shapes only have the top left pixel
+func writeTestAssets(t *testing.T, folder string) {
+	for key, options := range model.MaskShapes() {
+		for _, option := range options {
+			img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+			if key == "face.shape" {
+				for n := range img.Pix {
+					img.Pix[n] = 255
+				}
+			} else {
+				img.SetNRGBA(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+
+			var buffer bytes.Buffer
+			assert.Nil(t, png.Encode(&buffer, img))
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(folder, option), buffer.Bytes(), 0644))
+		}
+	}
+}
+
+var testConfigs = []model.Config{
+	{Key: "face.shape", Value: "rosto0001.png"},
+	{Key: "mask.primary.color", Value: "#FF0000"},
+	{Key: "mask.shape", Value: "segunda_cor0002.png"},
+	{Key: "mask.secondary.color", Value: "#00FF00"},
+	{Key: "mask.primary.color.name", Value: "Red"},
+}
+
+func TestRenderPNG(t *testing.T) {
+	folder, err := ioutil.TempDir("", "mask")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+	writeTestAssets(t, folder)
+
+	renderer := NewRenderer(folder)
+	result, err := renderer.Render(testConfigs, FormatPNG)
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", result.ContentType)
+	assert.Equal(t, Hash(testConfigs), result.Hash)
+
+	img, err := png.Decode(bytes.NewReader(result.Data))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, img.Bounds().Dx())
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, []uint32{0, 0xffff, 0}, []uint32{r, g, b})
+	r, g, b, _ = img.At(1, 1).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+
+	// the images are cached by the hash
+	cached, err := renderer.Render(testConfigs, FormatPNG)
+	assert.Nil(t, err)
+	assert.True(t, result == cached)
+}
+
+func TestRenderSVG(t *testing.T) {
+	folder, err := ioutil.TempDir("", "mask")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+	writeTestAssets(t, folder)
+
+	result, err := NewRenderer(folder).Render(testConfigs, FormatSVG)
+	assert.Nil(t, err)
+	assert.Equal(t, "image/svg+xml", result.ContentType)
+
+	svg := string(result.Data)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Equal(t, 2, strings.Count(svg, "<image"))
+	assert.Contains(t, svg, `values="1.0000 0 0 0 0 0 0.0000 0 0 0 0 0 0.0000 0 0 0 0 0 1 0"`)
+}
+
+func TestRenderErrors(t *testing.T) {
+	folder, err := ioutil.TempDir("", "mask")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+
+	renderer := NewRenderer(folder)
+	_, err = renderer.Render(testConfigs, FormatPNG)
+	assert.True(t, errors.Is(err, ErrAssetNotFound))
+
+	_, err = renderer.Render(testConfigs, "gif")
+	assert.NotNil(t, err)
+
+	// shapes outside the catalogue are not read
+	writeTestAssets(t, folder)
+	result, err := renderer.Render([]model.Config{{Key: "face.shape", Value: "../../etc/passwd"}}, FormatSVG)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, strings.Count(string(result.Data), "<image"))
+}
+
+func TestRenderConcurrent(t *testing.T) {
+	folder, err := ioutil.TempDir("", "mask")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+	writeTestAssets(t, folder)
+
+	renderer := NewRenderer(folder)
+	var wait sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wait.Add(1)
+		go func(n int) {
+			defer wait.Done()
+			configs := append([]model.Config{{Key: "eyes.color", Value: fmt.Sprintf("#0000%02X", n)}}, testConfigs...)
+			_, err := renderer.Render(configs, FormatPNG)
+			assert.Nil(t, err)
+		}(n)
+	}
+	wait.Wait()
+	assert.Equal(t, 8, len(renderer.cache))
+}
+
+func TestHash(t *testing.T) {
+	reversed := make([]model.Config, 0, len(testConfigs))
+	for n := len(testConfigs) - 1; n >= 0; n-- {
+		reversed = append(reversed, testConfigs[n])
+	}
+	assert.Equal(t, Hash(testConfigs), Hash(reversed))
+
+	// only the configs of the layers change the hash
+	assert.Equal(t, Hash(testConfigs), Hash(append(reversed, model.Config{Key: "name", Value: "test"})))
+	assert.NotEqual(t, Hash(testConfigs), Hash(testConfigs[:2]))
+}
+
+func TestParseColor(t *testing.T) {
+	assert.Equal(t, [3]uint8{0xEF, 0x50, 0x26}, parseColor("#EF5026"))
+	assert.Equal(t, [3]uint8{255, 255, 255}, parseColor("red"))
+	assert.Equal(t, [3]uint8{255, 255, 255}, parseColor("#GGGGGG"))
+}
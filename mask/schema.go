@@ -0,0 +1,138 @@
+package mask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// suffix of the config with the name of the color
+const colorNameSuffix = ".name"
+
+// maximum length of the color names
+const maxColorNameLength = 40
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// ShapeSchema definition, the config Key accepts one of the Options
+type ShapeSchema struct {
+	Key     string   `json:"key"`
+	Options []string `json:"options"`
+}
+
+// ColorSchema definition, the config Key accepts a "#RRGGBB" color and NameKey its name
+type ColorSchema struct {
+	Key     string `json:"key"`
+	NameKey string `json:"nameKey"`
+}
+
+// PaletteColor definition, colors suggested by the editor
+type PaletteColor struct {
+	Name string `json:"name"`
+	Hex  string `json:"hex"`
+}
+
+// Schema definition, the configs accepted for the luchador mask, Version is the hash
+// of the schema and changes when shapes or colors are added
+type Schema struct {
+	Version    string         `json:"version"`
+	MaxConfigs int            `json:"maxConfigs"`
+	Shapes     []ShapeSchema  `json:"shapes"`
+	Colors     []ColorSchema  `json:"colors"`
+	Palette    []PaletteColor `json:"palette"`
+}
+
+// CurrentSchema definition, the schema of the shapes and colors of the model
+func CurrentSchema() Schema {
+	catalogue := model.GetMaskCatalogue()
+	schema := Schema{
+		Shapes:  make([]ShapeSchema, 0, len(catalogue.Shapes)),
+		Colors:  make([]ColorSchema, 0, len(catalogue.ColorKeys)),
+		Palette: make([]PaletteColor, 0, len(catalogue.Colors)),
+	}
+
+	for key, items := range catalogue.Shapes {
+		options := make([]string, 0, len(items))
+		for _, item := range items {
+			options = append(options, item.Name)
+		}
+		schema.Shapes = append(schema.Shapes, ShapeSchema{Key: key, Options: options})
+	}
+	sort.Slice(schema.Shapes, func(i, j int) bool {
+		return schema.Shapes[i].Key < schema.Shapes[j].Key
+	})
+
+	for _, key := range catalogue.ColorKeys {
+		schema.Colors = append(schema.Colors, ColorSchema{Key: key, NameKey: key + colorNameSuffix})
+	}
+
+	for _, color := range catalogue.Colors {
+		schema.Palette = append(schema.Palette, PaletteColor{Name: color.Name, Hex: color.Hex})
+	}
+
+	schema.MaxConfigs = len(schema.Shapes) + 2*len(schema.Colors)
+
+	content, _ := json.Marshal(schema)
+	sum := sha256.Sum256(content)
+	schema.Version = hex.EncodeToString(sum[:8])
+
+	return schema
+}
+
+// Validate definition, the errors of the configs, unknown or duplicated keys, shapes
+// outside the options and invalid colors
+func (schema Schema) Validate(configs []model.Config) []string {
+	errors := make([]string, 0)
+
+	if len(configs) > schema.MaxConfigs {
+		return append(errors, fmt.Sprintf("Mask should have at most %v configs", schema.MaxConfigs))
+	}
+
+	shapes := make(map[string]map[string]bool)
+	for _, shape := range schema.Shapes {
+		shapes[shape.Key] = make(map[string]bool)
+		for _, option := range shape.Options {
+			shapes[shape.Key][option] = true
+		}
+	}
+
+	colors := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, color := range schema.Colors {
+		colors[color.Key] = true
+		names[color.NameKey] = true
+	}
+
+	found := make(map[string]bool)
+	for _, config := range configs {
+		if found[config.Key] {
+			errors = append(errors, fmt.Sprintf("Mask config %v is duplicated", config.Key))
+			continue
+		}
+		found[config.Key] = true
+
+		switch {
+		case shapes[config.Key] != nil:
+			if !shapes[config.Key][config.Value] {
+				errors = append(errors, fmt.Sprintf("Mask config %v should be one of the shape options", config.Key))
+			}
+		case colors[config.Key]:
+			if !hexColor.MatchString(config.Value) {
+				errors = append(errors, fmt.Sprintf("Mask config %v should be a #RRGGBB color", config.Key))
+			}
+		case names[config.Key]:
+			if len(config.Value) > maxColorNameLength {
+				errors = append(errors, fmt.Sprintf("Mask config %v length should be less or equal to %v characters", config.Key, maxColorNameLength))
+			}
+		default:
+			errors = append(errors, fmt.Sprintf("Mask config %v is unknown", config.Key))
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,94 @@
+package mask
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/setup"
+)
+
+func TestMain(m *testing.M) {
+	setup.SetupMaskCatalogueFromFolder("../metadata/mask")
+	os.Exit(m.Run())
+}
+
+func TestCurrentSchema(t *testing.T) {
+	schema := CurrentSchema()
+	assert.Equal(t, len(model.MaskShapes()), len(schema.Shapes))
+	assert.Equal(t, len(model.MaskColors()), len(schema.Colors))
+	assert.Equal(t, len(model.NMSColors()), len(schema.Palette))
+	assert.Equal(t, len(model.MaskShapes())+2*len(model.MaskColors()), schema.MaxConfigs)
+	assert.Equal(t, "mask.primary.color.name", schema.Colors[0].NameKey)
+
+	// the version changes with the shapes
+	assert.Equal(t, schema.Version, CurrentSchema().Version)
+
+	catalogue := model.GetMaskCatalogue()
+	defer model.SetMaskCatalogue(catalogue)
+	model.SetMaskCatalogue(withShape(catalogue, "eyes.shape", "olho0003.png"))
+	assert.NotEqual(t, schema.Version, CurrentSchema().Version)
+}
+
+// copies the catalogue with a new item of the shape key
+func withShape(catalogue model.MaskCatalogue, key string, name string) model.MaskCatalogue {
+	shapes := make(map[string][]model.MaskItem)
+	for current, items := range catalogue.Shapes {
+		shapes[current] = items
+	}
+	shapes[key] = append(append([]model.MaskItem{}, shapes[key]...), model.MaskItem{Name: name})
+	catalogue.Shapes = shapes
+	return catalogue
+}
+
+func TestSchemaWhileCatalogueChanges(t *testing.T) {
+	catalogue := model.GetMaskCatalogue()
+	defer model.SetMaskCatalogue(catalogue)
+	changed := withShape(catalogue, "eyes.shape", "olho0003.png")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if i%2 == 0 {
+				model.SetMaskCatalogue(changed)
+			} else {
+				model.SetMaskCatalogue(catalogue)
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		schema := CurrentSchema()
+		assert.Equal(t, len(schema.Shapes)+2*len(schema.Colors), schema.MaxConfigs)
+		assert.True(t, validShape("eyes.shape", model.MaskShapes()["eyes.shape"][0]))
+	}
+	<-done
+}
+
+func TestValidate(t *testing.T) {
+	schema := CurrentSchema()
+
+	assert.Empty(t, schema.Validate(model.RandomConfig()))
+	assert.Empty(t, schema.Validate([]model.Config{}))
+
+	errors := schema.Validate([]model.Config{
+		{Key: "mask.shape", Value: "../../etc/passwd"},
+		{Key: "mask.primary.color", Value: "red"},
+		{Key: "mask.primary.color.name", Value: "a very long color name that is not accepted"},
+		{Key: "junk", Value: "junk"},
+		{Key: "eyes.color", Value: "#00FF00"},
+		{Key: "eyes.color", Value: "#00FF00"},
+	})
+	assert.Equal(t, []string{
+		"Mask config mask.shape should be one of the shape options",
+		"Mask config mask.primary.color should be a #RRGGBB color",
+		"Mask config mask.primary.color.name length should be less or equal to 40 characters",
+		"Mask config junk is unknown",
+		"Mask config eyes.color is duplicated",
+	}, errors)
+
+	junk := make([]model.Config, schema.MaxConfigs+1)
+	assert.Equal(t, 1, len(schema.Validate(junk)))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/mask"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+// every shape of the catalogue is a white pixel
+func setupMaskAssets(t *testing.T) string {
+	folder, err := ioutil.TempDir("", "mask-assets")
+	assert.Nil(t, err)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	var buffer bytes.Buffer
+	assert.Nil(t, png.Encode(&buffer, img))
+
+	for _, options := range model.MaskShapes() {
+		for _, option := range options {
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(folder, option), buffer.Bytes(), 0644))
+		}
+	}
+	return folder
+}
+
+func TestLuchadorMaskImage(t *testing.T) {
+	folder := setupMaskAssets(t)
+	defer os.RemoveAll(folder)
+
+	previous := maskRenderer
+	defer func() { maskRenderer = previous }()
+	maskRenderer = mask.NewRenderer(folder)
+
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	luchador := GetLuchador(t)
+	assert.NotZero(t, luchador.ID)
+
+	path := fmt.Sprintf("/public/luchador/%v/mask", luchador.ID)
+	w := test.PerformRequestNoAuth(router, "GET", path+".png", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"`+mask.Hash(luchador.Configs)+`"`, w.Header().Get("ETag"))
+
+	_, err := png.Decode(w.Body)
+	assert.Nil(t, err)
+
+	w = test.PerformRequestNoAuth(router, "GET", path+".svg", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+
+	// the browser cache is validated by the hash of the configs
+	req, _ := http.NewRequest("GET", path+".png", nil)
+	req.Header.Set("If-None-Match", `"`+mask.Hash(luchador.Configs)+`"`)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+
+	// the mask is not rendered without the images of the shapes
+	maskRenderer = mask.NewRenderer(filepath.Join(folder, "missing"))
+	router = createRouter(test.API_KEY, "true", auth.SessionAllwaysValid, auth.SessionAllwaysValid)
+	w = test.PerformRequestNoAuth(router, "GET", path+".png", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "GET", "/public/luchador/9999/mask.png", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = test.PerformRequestNoAuth(router, "GET", "/public/luchador/0/mask.svg", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func TestStudentMastery(t *testing.T) {
+	classroom := SetupClassroomRoster(t)
+	defer ds.DB.Close()
+
+	ds.AddSkill(&model.Skill{Name: "Loops"})
+
+	url := fmt.Sprintf("/dashboard/classroom/students/%v", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	var students []model.StudentResponse
+	json.Unmarshal(w.Body.Bytes(), &students)
+	assert.Equal(t, 1, len(students))
+
+	url = fmt.Sprintf("/dashboard/student/%v/mastery", students[0].StudentID)
+	w = test.PerformRequestNoAuth(router, "GET", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var mastery model.StudentMastery
+	json.Unmarshal(w.Body.Bytes(), &mastery)
+	assert.Equal(t, students[0].UserID, mastery.UserID)
+	assert.Equal(t, 1, len(mastery.Gaps))
+
+	// only the classroom owner can see the student mastery
+	w = test.PerformRequest(router, "GET", url, "", "alice")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/mastery", "", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &mastery)
+	assert.Equal(t, students[0].StudentID, mastery.StudentID)
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/routes/media"
+	"gitlab.com/robolucha/robolucha-api/storage"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+// red 4x4 png image
+const testImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAQAAAAECAIAAAAmkwkpAAAAEElEQVR4nGP4z8AARwzEcQCukw/x0F8jngAAAABJRU5ErkJggg=="
+
+// encodes a blue 2x2 png image as base64
+func testPNG(t *testing.T) string {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.NRGBA{B: 255, A: 255}}, image.ZP, draw.Src)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, png.Encode(&buffer, img))
+	return base64.StdEncoding.EncodeToString(buffer.Bytes())
+}
+
+func addTestMedia(t *testing.T, fileName string, base64Data string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"fileName":"%v","base64Data":"%v"}`, fileName, base64Data)
+	return test.PerformRequestNoAuth(router, "POST", "/private/media", body)
+}
+
+func TestAddMediaLocalStore(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := addTestMedia(t, "red square.png", testImage)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var media model.Media
+	json.Unmarshal(w.Body.Bytes(), &media)
+	assert.NotZero(t, media.ID)
+	assert.Equal(t, "red square.png", media.FileName)
+	assert.Regexp(t, `^/public/media-file/1/.+-red_square\.png$`, media.URL)
+	assert.Regexp(t, `^/public/media-file/1/.+-thumbnail-red_square\.png$`, media.Thumbnail)
+
+	// the files are served by the api
+	w = test.PerformRequestNoAuth(router, "GET", media.URL, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = test.PerformRequestNoAuth(router, "GET", media.Thumbnail, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "GET", "/public/media-file/1/missing.png", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// invalid images are rejected without saving the media
+	w = addTestMedia(t, "a.png", "data:image/png;base64,bm90IGFuIGltYWdl")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = addTestMedia(t, "a.png", "%%%")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var count int
+	ds.DB.Model(&model.Media{}).Count(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestAddMediaExtensionFromContent(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	// the file name extension sent by the client is not used in the stored files
+	for _, fileName := range []string{"page.html", "drawing.svg", "no extension"} {
+		w := addTestMedia(t, fileName, "data:image/png;base64,"+testPNG(t))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var media model.Media
+		json.Unmarshal(w.Body.Bytes(), &media)
+		assert.Equal(t, fileName, media.FileName)
+		assert.True(t, strings.HasSuffix(media.URL, ".png"), media.URL)
+
+		w = test.PerformRequestNoAuth(router, "GET", media.URL, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+		ds.DB.Unscoped().Delete(&media)
+	}
+}
+
+func TestAddMediaMetadataAndRenditions(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var media model.Media
+	json.Unmarshal(w.Body.Bytes(), &media)
+	assert.Equal(t, "image/png", media.ContentType)
+	assert.Equal(t, 4, media.Width)
+	assert.Equal(t, 4, media.Height)
+	assert.NotZero(t, media.Size)
+	assert.Len(t, media.Hash, 64)
+
+	assert.Equal(t, 3, len(media.Renditions))
+	for n, name := range []string{"thumbnail", "preview", "texture"} {
+		assert.Equal(t, name, media.Renditions[n].Name)
+		assert.Equal(t, 4, media.Renditions[n].Width)
+		assert.NotZero(t, media.Renditions[n].Size)
+	}
+
+	// the same image is not saved again
+	w = addTestMedia(t, "copy.png", testImage)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var copy model.Media
+	json.Unmarshal(w.Body.Bytes(), &copy)
+	assert.Equal(t, media.ID, copy.ID)
+	assert.Equal(t, 3, len(copy.Renditions))
+}
+
+func TestAddMediaLimits(t *testing.T) {
+	defer func() { mediaConfig = media.DefaultConfig() }()
+
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.MaxPixels = 15
+	SetupClassroom(t)
+	w := addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	ds.DB.Close()
+
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.MaxBytes = 50
+	SetupClassroom(t)
+	w = addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	ds.DB.Close()
+
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.UserQuotaBytes = 100
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	ds.AddMedia(&model.Media{UserID: 1, Size: 50})
+	w = addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMediaLibrary(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	var red, blue model.Media
+	json.Unmarshal(addTestMedia(t, "red.png", testImage).Body.Bytes(), &red)
+	ds.AddMedia(&model.Media{UserID: 1, FileName: "blue sky.png"})
+	ds.AddMedia(&model.Media{UserID: 2, FileName: "other user.png"})
+
+	var library []model.Media
+	w := test.PerformRequestNoAuth(router, "GET", "/private/media", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &library)
+	assert.Equal(t, 2, len(library))
+
+	w = test.PerformRequestNoAuth(router, "GET", "/private/media?search=SKY", "")
+	json.Unmarshal(w.Body.Bytes(), &library)
+	assert.Equal(t, 1, len(library))
+	blue = library[0]
+	assert.Equal(t, "blue sky.png", blue.FileName)
+
+	// rename
+	w = test.PerformRequestNoAuth(router, "PUT", fmt.Sprintf("/private/media/%v", red.ID), `{"fileName":"lava.png"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "lava.png", ds.FindMedia(red.ID).FileName)
+
+	w = test.PerformRequestNoAuth(router, "PUT", fmt.Sprintf("/private/media/%v", red.ID), `{"fileName":""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// media of other users are not found
+	w = test.PerformRequestNoAuth(router, "PUT", "/private/media/3", `{"fileName":"mine.png"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = test.PerformRequestNoAuth(router, "DELETE", "/private/media/3", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// delete removes the files
+	w = test.PerformRequestNoAuth(router, "DELETE", fmt.Sprintf("/private/media/%v", red.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, ds.FindMedia(red.ID))
+
+	w = test.PerformRequestNoAuth(router, "GET", red.URL, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = test.PerformRequestNoAuth(router, "GET", red.Thumbnail, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the same image can be uploaded again
+	w = addTestMedia(t, "red.png", testImage)
+	var uploaded model.Media
+	json.Unmarshal(w.Body.Bytes(), &uploaded)
+	assert.NotEqual(t, red.ID, uploaded.ID)
+}
+
+func TestMediaAttach(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	mine := ds.CreateGameDefinition(&model.GameDefinition{
+		Name:                 "mine",
+		OwnerUserID:          1,
+		NarrativeDefinitions: []model.NarrativeDefinition{{Event: "start", Text: "hello"}},
+	})
+	other := ds.CreateGameDefinition(&model.GameDefinition{Name: "other", OwnerUserID: 2})
+	narrativeID := ds.FindGameDefinition(mine.ID).NarrativeDefinitions[0].ID
+
+	var red model.Media
+	json.Unmarshal(addTestMedia(t, "red.png", testImage).Body.Bytes(), &red)
+	path := fmt.Sprintf("/private/media/%v", red.ID)
+
+	w := test.PerformRequestNoAuth(router, "POST", path+"/attach", fmt.Sprintf(`{"gameDefinitionID":%v}`, mine.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var attached model.Media
+	json.Unmarshal(w.Body.Bytes(), &attached)
+	assert.NotEqual(t, red.ID, attached.ID)
+	assert.Equal(t, red.ID, attached.SourceMediaID)
+	assert.Equal(t, red.URL, attached.URL)
+	assert.Equal(t, 3, len(attached.Renditions))
+	assert.Equal(t, attached.ID, ds.FindGameDefinition(mine.ID).Media.ID)
+
+	w = test.PerformRequestNoAuth(router, "POST", path+"/attach", fmt.Sprintf(`{"narrativeDefinitionID":%v}`, narrativeID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, red.URL, ds.FindGameDefinition(mine.ID).NarrativeDefinitions[0].Media.URL)
+
+	// the copies share the files and dont use the quota
+	assert.Equal(t, red.Size+red.Renditions[0].Size+red.Renditions[1].Size+red.Renditions[2].Size,
+		ds.FindMediaUsageByUser(1))
+
+	// the library only lists the uploads
+	var library []model.Media
+	w = test.PerformRequestNoAuth(router, "GET", "/private/media", "")
+	json.Unmarshal(w.Body.Bytes(), &library)
+	assert.Equal(t, 1, len(library))
+
+	// attaching again replaces the media of the game definition
+	var second model.Media
+	json.Unmarshal(addTestMedia(t, "other.png", "data:image/png;base64,"+testPNG(t)).Body.Bytes(), &second)
+	w = test.PerformRequestNoAuth(router, "POST", fmt.Sprintf("/private/media/%v/attach", second.ID), fmt.Sprintf(`{"gameDefinitionID":%v}`, mine.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, second.URL, ds.FindGameDefinition(mine.ID).Media.URL)
+
+	var count int
+	ds.DB.Model(&model.Media{}).Where("game_definition_id = ?", mine.ID).Count(&count)
+	assert.Equal(t, 1, count)
+
+	// referenced media cant be deleted
+	w = test.PerformRequestNoAuth(router, "DELETE", path, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", path+"/detach", fmt.Sprintf(`{"narrativeDefinitionID":%v}`, narrativeID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, ds.FindGameDefinition(mine.ID).NarrativeDefinitions[0].Media.ID)
+
+	w = test.PerformRequestNoAuth(router, "DELETE", path, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the test user can edit any map, the owner is checked without map.edit.any
+	handler := media.NewRequestHandler(ds, publisher)
+	_, err := handler.Attach(1, second.ID, model.MediaAttachRequest{GameDefinitionID: other.ID}, false)
+	assert.NotNil(t, err)
+	_, err = handler.Attach(1, second.ID, model.MediaAttachRequest{GameDefinitionID: mine.ID}, false)
+	assert.Nil(t, err)
+
+	// invalid targets
+	path = fmt.Sprintf("/private/media/%v/attach", second.ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"gameDefinitionID":9999}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = test.PerformRequestNoAuth(router, "POST", path, `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = test.PerformRequestNoAuth(router, "POST", path, fmt.Sprintf(`{"gameDefinitionID":%v,"narrativeDefinitionID":%v}`, mine.ID, narrativeID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func uploadTestMedia(t *testing.T, fields map[string]string, fileName string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	if data != nil {
+		part, err := writer.CreateFormFile("file", fileName)
+		assert.Nil(t, err)
+		part.Write(data)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/private/media-upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func testImageData(t *testing.T) []byte {
+	data, err := base64.StdEncoding.DecodeString(testImage[strings.Index(testImage, ",")+1:])
+	assert.Nil(t, err)
+	return data
+}
+
+func TestUploadMediaMultipart(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := uploadTestMedia(t, nil, "red square.png", testImageData(t))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var media model.Media
+	json.Unmarshal(w.Body.Bytes(), &media)
+	assert.Equal(t, "red square.png", media.FileName)
+	assert.Equal(t, "image/png", media.ContentType)
+	assert.Equal(t, 3, len(media.Renditions))
+
+	w = test.PerformRequestNoAuth(router, "GET", media.URL, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the fileName field replaces the name of the file
+	blue, _ := base64.StdEncoding.DecodeString(testPNG(t))
+	w = uploadTestMedia(t, map[string]string{"fileName": "blue.png"}, "blue-upload.png", blue)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &media)
+	assert.Equal(t, "blue.png", media.FileName)
+
+	w = uploadTestMedia(t, map[string]string{"fileName": "empty.png"}, "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload", `{"fileName":"a.png"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = uploadTestMedia(t, nil, "a.png", []byte("not an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestUploadMediaMultipartLimit(t *testing.T) {
+	defer func() { mediaConfig = media.DefaultConfig() }()
+
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.MaxBytes = 50
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := uploadTestMedia(t, nil, "red.png", testImageData(t))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var count int
+	ds.DB.Model(&model.Media{}).Count(&count)
+	assert.Equal(t, 0, count)
+}
+
+func sendTestChunk(t *testing.T, id uint, offset int, data []byte) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/private/media-upload/session/%v?offset=%v", id, offset)
+	req := httptest.NewRequest("PUT", path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadMediaResumable(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	data := testImageData(t)
+	body := fmt.Sprintf(`{"fileName":"red.png","size":%v}`, len(data))
+	w := test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var upload model.MediaUpload
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	assert.NotZero(t, upload.ID)
+	assert.Equal(t, int64(len(data)), upload.Size)
+	assert.Zero(t, upload.Received)
+
+	w = sendTestChunk(t, upload.ID, 0, data[:10])
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	assert.Equal(t, int64(10), upload.Received)
+	assert.Zero(t, upload.MediaID)
+
+	// the offset must be the received bytes
+	w = sendTestChunk(t, upload.ID, 5, data[5:])
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// the progress is the offset to resume the upload
+	w = test.PerformRequestNoAuth(router, "GET", fmt.Sprintf("/private/media-upload/session/%v", upload.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	assert.Equal(t, int64(10), upload.Received)
+
+	// the chunks cant be larger than the file
+	w = sendTestChunk(t, upload.ID, 10, append(data[10:], 0))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = sendTestChunk(t, upload.ID, 10, data[10:])
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	assert.Equal(t, int64(len(data)), upload.Received)
+	assert.NotZero(t, upload.MediaID)
+	assert.Equal(t, "red.png", upload.Media.FileName)
+	assert.Equal(t, 3, len(upload.Media.Renditions))
+
+	w = sendTestChunk(t, upload.ID, len(data), data)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "GET", fmt.Sprintf("/private/media-upload/session/%v", upload.ID), "")
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	assert.Equal(t, upload.MediaID, upload.Media.ID)
+
+	// invalid uploads
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"red.png","size":0}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"red.png","size":999999999}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	w = test.PerformRequestNoAuth(router, "GET", "/private/media-upload/session/999", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUploadMediaResumableLimits(t *testing.T) {
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.UserQuotaBytes = 100
+	mediaConfig.MaxOpenUploads = 2
+	SetupClassroom(t)
+	defer ds.DB.Close()
+	defer func() { mediaConfig = media.DefaultConfig() }()
+
+	// the open uploads count as used by the quota
+	w := test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"a.png","size":60}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"b.png","size":60}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"b.png","size":20}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"c.png","size":10}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// the expired uploads of all the users are removed
+	other := ds.AddMediaUpload(&model.MediaUpload{UserID: 2, FileName: "other.png", Size: 10})
+	ds.DB.Model(&model.MediaUpload{}).UpdateColumn("updated_at", time.Now().Add(-48*time.Hour))
+	media.RemoveExpiredUploads()
+	assert.Nil(t, ds.FindMediaUpload(2, other.ID))
+	assert.Equal(t, 0, len(ds.FindOpenMediaUploads(1)))
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media-upload/session", `{"fileName":"c.png","size":10}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// fails the puts after the first ones
+type failingStore struct {
+	storage.MediaStore
+	puts int
+}
+
+func (s *failingStore) Put(key string, content io.Reader, contentType string) (string, error) {
+	if s.puts == 0 {
+		return "", errors.New("store unavailable")
+	}
+	s.puts--
+	return s.MediaStore.Put(key, content, contentType)
+}
+
+func countMediaFiles(t *testing.T) int {
+	count := 0
+	filepath.Walk(test.MEDIA_FOLDER, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestAddMediaRenditionFailure(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	// the original and the first rendition are saved before the store fails
+	mediaStore = &failingStore{MediaStore: mediaStore, puts: 2}
+	router = createRouter(test.API_KEY, "true", auth.SessionAllwaysValid, auth.SessionAllwaysValid)
+
+	w := addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 0, countMediaFiles(t))
+	assert.Zero(t, ds.FindMediaUsageByUser(1))
+}
+
+func TestAddMediaQuotaRenditions(t *testing.T) {
+	// the original fits in the quota, the renditions don't
+	mediaConfig = media.DefaultConfig()
+	mediaConfig.UserQuotaBytes = int64(len(testImageData(t))) + 10
+	SetupClassroom(t)
+	defer ds.DB.Close()
+	defer func() { mediaConfig = media.DefaultConfig() }()
+
+	w := addTestMedia(t, "red.png", testImage)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 0, countMediaFiles(t))
+	assert.Zero(t, ds.FindMediaUsageByUser(1))
+}
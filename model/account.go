@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+const ACCOUNT_DELETION_ANONYMIZE = "anonymize"
+const ACCOUNT_DELETION_DELETE = "delete"
+
+const ACCOUNT_DELETION_PENDING = "pending"
+const ACCOUNT_DELETION_REJECTED = "rejected"
+const ACCOUNT_DELETION_COMPLETED = "completed"
+
+// AccountDeletionRequest definition, requests from students enrolled in a classroom
+// wait for the approval of one of their teachers, other requests are completed immediately
+type AccountDeletionRequest struct {
+	ID               uint       `gorm:"primary_key" json:"id"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"-"`
+	DeletedAt        *time.Time `json:"-" faker:"-"`
+	UserID           uint       `json:"userID"`
+	Username         string     `json:"username"`
+	Mode             string     `json:"mode"`
+	Status           string     `json:"status"`
+	ReviewedByUserID uint       `json:"reviewedByUserID,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewedAt"`
+	CompletedAt      *time.Time `json:"completedAt"`
+}
+
+// AccountDeletionRequestBody definition, mode is anonymize or delete
+type AccountDeletionRequestBody struct {
+	Mode string `json:"mode"`
+}
+
+// AccountExport definition, all the data stored for the user
+type AccountExport struct {
+	ExportedAt            time.Time                `json:"exportedAt"`
+	User                  User                     `json:"user"`
+	Settings              UserSetting              `json:"settings"`
+	Level                 UserLevel                `json:"level"`
+	LevelChanges          []UserLevelChange        `json:"levelChanges"`
+	Sessions              []Session                `json:"sessions"`
+	Classrooms            []Classroom              `json:"classrooms"`
+	OwnedClassrooms       []Classroom              `json:"ownedClassrooms"`
+	AssignmentEvaluations []AssignmentEvaluation   `json:"assignmentEvaluations"`
+	Luchadors             []GameComponent          `json:"luchadors"`
+	CodeHistory           []CodeHistory            `json:"codeHistory"`
+	MatchScores           []MatchScore             `json:"matchScores"`
+	MatchEvents           []MatchEvent             `json:"matchEvents"`
+	Media                 []Media                  `json:"media"`
+	DeletionRequests      []AccountDeletionRequest `json:"deletionRequests"`
+	ModerationFlags       []ModerationFlag         `json:"moderationFlags"`
+	ModerationReports     []ModerationReport       `json:"moderationReports"`
+	PageEvents            []PageEvent              `json:"pageEvents"`
+}
@@ -4,12 +4,13 @@ import "time"
 
 // LearningObjective definition
 type LearningObjective struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"-"`
-	UpdatedAt time.Time  `json:"-"`
-	DeletedAt *time.Time `json:"-" faker:"-"`
-	Name      string     `json:"name" gorm:"not null;unique_index"`
-	Skills    []Skill    `gorm:"many2many:learningobjective_skills" json:"skills"`
+	ID          uint       `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time  `json:"-"`
+	UpdatedAt   time.Time  `json:"-"`
+	DeletedAt   *time.Time `json:"-" faker:"-"`
+	Name        string     `json:"name" gorm:"not null;unique_index"`
+	Skills      []Skill    `gorm:"many2many:learningobjective_skills" json:"skills"`
+	OwnerUserID uint       `json:"ownerUserID"`
 }
 
 // Skill definition
@@ -20,6 +21,7 @@ type Skill struct {
 	DeletedAt   *time.Time `json:"-" faker:"-"`
 	Name        string     `json:"name" gorm:"not null;unique_index"`
 	Description string     `gorm:"size:125000" json:"description"`
+	OwnerUserID uint       `json:"ownerUserID"`
 }
 
 // LevelGroup definition
@@ -76,18 +78,21 @@ type Activity struct {
 	GameDefinition   *GameDefinition `json:"gameDefinition"`
 	SourceURL        string          `json:"sourceURL"`
 	SourceName       string          `json:"sourceName"`
+	OwnerUserID      uint            `json:"ownerUserID"`
 }
 
 // Assignment definition
 type Assignment struct {
-	ID         uint       `gorm:"primary_key" json:"id"`
-	CreatedAt  time.Time  `json:"-"`
-	UpdatedAt  time.Time  `json:"-"`
-	DeletedAt  *time.Time `json:"-" faker:"-"`
-	TimeStart  time.Time  `json:"timeStart"`
-	TimeEnd    time.Time  `json:"timeEnd"`
-	Students   []Student  `gorm:"many2many:assignment_student;"`
-	Activities []Activity `gorm:"many2many:assignment_activity;"`
+	ID          uint       `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time  `json:"-"`
+	UpdatedAt   time.Time  `json:"-"`
+	DeletedAt   *time.Time `json:"-" faker:"-"`
+	Name        string     `json:"name"`
+	Description string     `gorm:"size:125000" json:"description"`
+	TimeStart   time.Time  `json:"timeStart"`
+	TimeEnd     time.Time  `json:"timeEnd"`
+	Students    []Student  `gorm:"many2many:assignment_student;"`
+	Activities  []Activity `gorm:"many2many:assignment_activity;"`
 
 }
 
@@ -115,3 +120,33 @@ type AssignmentGrade struct {
 	Skill                  Skill      `json:"skill"`
 	AssignmentEvaluationID uint       `json:"assignmentEvaluationID"`
 }
+
+// StudentMastery definition, mastery levels of a student and the skills not mastered yet
+type StudentMastery struct {
+	UserID             uint                       `json:"userID"`
+	StudentID          uint                       `json:"studentID"`
+	Skills             []SkillMastery             `json:"skills"`
+	LearningObjectives []LearningObjectiveMastery `json:"learningObjectives"`
+	Gaps               []SkillMastery             `json:"gaps"`
+}
+
+// SkillMastery definition, Score uses the same scale of the grades
+type SkillMastery struct {
+	SkillID      uint       `json:"skillID"`
+	Name         string     `json:"name"`
+	Score        float32    `json:"score"`
+	Grade        *Grade     `json:"grade"`
+	Evidences    uint       `json:"evidences"`
+	LastEvidence *time.Time `json:"lastEvidence"`
+	Mastered     bool       `json:"mastered"`
+}
+
+// LearningObjectiveMastery definition, Score is the average of its skills
+type LearningObjectiveMastery struct {
+	LearningObjectiveID uint    `json:"learningObjectiveID"`
+	Name                string  `json:"name"`
+	Score               float32 `json:"score"`
+	Grade               *Grade  `json:"grade"`
+	MasteredSkills      uint    `json:"masteredSkills"`
+	TotalSkills         uint    `json:"totalSkills"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import "sort"
+
+// DefaultLoadoutID the game definition of the default codes of the luchador
+const DefaultLoadoutID = 0
+
+// Loadout definition, the codes of the luchador used in the game definition, events
+// without codes for the game definition use the default codes
+type Loadout struct {
+	LuchadorID         uint   `json:"luchadorID"`
+	GameDefinitionID   uint   `json:"gameDefinitionID"`
+	GameDefinitionName string `json:"gameDefinitionName,omitempty"`
+	Codes              []Code `json:"codes"`
+}
+
+// LoadoutCopyRequest definition
+type LoadoutCopyRequest struct {
+	FromGameDefinitionID uint `json:"fromGameDefinitionID"`
+}
+
+// LoadoutCodes definition, the code of each event for the game definition or the default
+// code of the event, sorted by event
+func LoadoutCodes(codes []Code, gameDefinitionID uint) []Code {
+	byEvent := make(map[string]Code)
+	for _, code := range codes {
+		if code.GameDefinitionID == DefaultLoadoutID {
+			if _, found := byEvent[code.Event]; !found {
+				byEvent[code.Event] = code
+			}
+		}
+	}
+	for _, code := range codes {
+		if code.GameDefinitionID == gameDefinitionID {
+			byEvent[code.Event] = code
+		}
+	}
+
+	result := make([]Code, 0, len(byEvent))
+	for _, code := range byEvent {
+		result = append(result, code)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Event < result[j].Event
+	})
+
+	return result
+}
@@ -2,138 +2,85 @@ package model
 
 import (
 	"math/rand"
+	"strings"
 	"time"
 )
 
-var maskShape = []string{
-	"segunda_cor0001.png",
-	"segunda_cor0002.png",
-	"segunda_cor0003.png",
-	"segunda_cor0004.png",
-	"segunda_cor0005.png",
-	"segunda_cor0006.png",
-	"segunda_cor0007.png",
-	"segunda_cor0008.png",
-	"segunda_cor0009.png",
-	"segunda_cor0010.png",
-	"segunda_cor0011.png",
-	"segunda_cor0012.png",
-	"segunda_cor0013.png",
-	"segunda_cor0014.png",
-	"segunda_cor0015.png"}
-
-var ornamentTop = []string{
-	"ornamento_cima0001.png",
-	"ornamento_cima0002.png",
-	"ornamento_cima0003.png",
-	"ornamento_cima0004.png",
-	"ornamento_cima0005.png",
-	"ornamento_cima0006.png",
-	"ornamento_cima0007.png",
-	"ornamento_cima0008.png",
-	"ornamento_cima0009.png",
-	"ornamento_cima0010.png",
-	"ornamento_cima0011.png"}
-
-var ornamentBottom = []string{
-	"ornamento_baixo0001.png",
-	"ornamento_baixo0002.png",
-	"ornamento_baixo0003.png",
-	"ornamento_baixo0004.png",
-	"ornamento_baixo0005.png",
-	"ornamento_baixo0006.png",
-	"ornamento_baixo0007.png",
-	"ornamento_baixo0008.png",
-	"ornamento_baixo0009.png",
-	"ornamento_baixo0010.png"}
-
-var face = []string{
-	"rosto0001.png",
-	"rosto0002.png",
-	"rosto0003.png",
-	"rosto0004.png",
-	"rosto0005.png",
-	"rosto0006.png",
-	"rosto0007.png",
-	"rosto0008.png",
-	"rosto0009.png",
-	"rosto0010.png"}
-
-var mouth = []string{
-	"boca0001.png",
-	"boca0002.png",
-	"boca0003.png",
-	"boca0004.png",
-	"boca0005.png",
-	"boca0006.png",
-	"boca0007.png",
-	"boca0008.png",
-	"boca0009.png",
-	"boca0010.png",
-	"boca0011.png",
-	"boca0012.png",
-	"boca0013.png",
-	"boca0014.png",
-	"boca0015.png",
-	"boca0016.png",
-	"boca0017.png",
-	"boca0018.png",
-	"boca0019.png",
-	"boca0020.png"}
-
-var eyes = []string{
-	"olho0001.png",
-	"olho0002.png"}
-
-var MaskColors = []string{
-	"mask.primary.color",
-	"mask.secondary.color",
-	"mask.decoration.top.color",
-	"mask.decoration.bottom.color",
-	"eyes.color",
-	"feet.color",
-	"wrist.color",
-	"ankle.color",
-	"skin.color"}
-
-var MaskShapes = map[string][]string{
-	"mask.shape":                   maskShape,
-	"mask.decoration.top.shape":    ornamentTop,
-	"mask.decoration.bottom.shape": ornamentBottom,
-	"face.shape":                   face,
-	"mouth.shape":                  mouth,
-	"eyes.shape":                   eyes,
+// MaskColors the config keys of the mask colors, see SetMaskCatalogue, the result should not be changed
+func MaskColors() []string {
+	maskCatalogueMutex.RLock()
+	defer maskCatalogueMutex.RUnlock()
+	return maskColors
 }
 
-// RandomConfig creates a random Luchador Config
+// MaskShapes the options of the mask shape config keys, see SetMaskCatalogue, the result should not be changed
+func MaskShapes() map[string][]string {
+	maskCatalogueMutex.RLock()
+	defer maskCatalogueMutex.RUnlock()
+	return maskShapes
+}
+
+// RandomConfig creates a random Luchador Config with the items unlocked for everyone
 func RandomConfig() []Config {
+	return RandomConfigForLevel(0)
+}
+
+// RandomConfigForLevel creates a random Luchador Config with the items unlocked at the level
+func RandomConfigForLevel(level uint) []Config {
 	rand.Seed(time.Now().UnixNano())
 	list := []Config{}
-
-	for _, color := range MaskColors {
-		randomizedColor := NMSColor{"Black", "#000000"} //placeholder
-		randomizedColor = randomColor()
-		list = add2ConfigList(list, color, randomizedColor.hex)
-		list = add2ConfigList(list, color+".name", randomizedColor.name)
+	catalogue := GetMaskCatalogue()
+
+	colors := unlockedItems(catalogue.Colors, level)
+	if len(colors) > 0 {
+		for _, color := range catalogue.ColorKeys {
+			randomizedColor := colors[random(len(colors))]
+			list = add2ConfigList(list, color, randomizedColor.Hex)
+			list = add2ConfigList(list, color+".name", randomizedColor.Name)
+		}
 	}
 
-	for shape, options := range MaskShapes {
-		list = add2ConfigList(list, shape, randomString(options))
+	for shape, options := range catalogue.Shapes {
+		unlocked := unlockedItems(options, level)
+		if len(unlocked) > 0 {
+			list = add2ConfigList(list, shape, unlocked[random(len(unlocked))].Name)
+		}
 	}
 
 	return list
 }
 
+// RandomName creates a random Luchador name with the default word list
 func RandomName(list []Config) string {
+	return RandomNameForLocale(list, "")
+}
+
+// RandomNameForLocale creates a random Luchador name with the word list of the locale,
+// or the default word list when the locale doesn't have one
+func RandomNameForLocale(list []Config, locale string) string {
 	var primaryColor = getFromConfigList(list, "mask.primary.color.name").Value
-	var nounList = []string{
-		"Abismo", "Comando", "Perro", "Cabeza", "Gato", "Toro", "Chupacabra", "Taco", "Soldado", "Huracán", "Rey", "Pirata",
-	} //change to a JSON file?
-	var adjectiveList = []string{
-		"Grande", "Insano", "Fuerte", "Afortunado", "Ligero", "Muerto", "Peligroso", "Furioso", "Terrible",
-	} //change to a JSON file?
-
-	return "El " + nounList[random(len(nounList))] + " " + primaryColor + " " + adjectiveList[random(len(adjectiveList))]
+	words := GetMaskCatalogue().NameWords(locale)
+	if len(words.Nouns) == 0 || len(words.Adjectives) == 0 {
+		return strings.TrimSpace(primaryColor)
+	}
+
+	name := strings.NewReplacer(
+		"{noun}", randomString(words.Nouns),
+		"{color}", primaryColor,
+		"{adjective}", randomString(words.Adjectives),
+	).Replace(words.Template)
+
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func unlockedItems(items []MaskItem, level uint) []MaskItem {
+	result := make([]MaskItem, 0, len(items))
+	for _, item := range items {
+		if item.MinLevel <= level {
+			result = append(result, item)
+		}
+	}
+	return result
 }
 
 func getFromConfigList(list []Config, key string) Config {
@@ -154,10 +101,6 @@ func randomString(list []string) string {
 	return list[random(len(list))]
 }
 
-func randomColor() NMSColor {
-	return NMSCOLORS[random(len(NMSCOLORS))]
-}
-
 func random(max int) int {
 	return rand.Intn(max)
 }
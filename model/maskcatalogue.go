@@ -0,0 +1,124 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaskNameLocale the locale of the name word list used when the locale doesn't have one
+const DefaultMaskNameLocale = "default"
+
+// MaskItem definition, a shape or a color of the mask, locked for the users below MinLevel
+type MaskItem struct {
+	Name     string `json:"name"`
+	Hex      string `json:"hex,omitempty"`
+	MinLevel uint   `json:"minLevel"`
+}
+
+// MaskNameWords definition, the words of the random luchador names, the Template
+// replaces {noun}, {color} and {adjective}
+type MaskNameWords struct {
+	Template   string   `json:"template"`
+	Nouns      []string `json:"nouns"`
+	Adjectives []string `json:"adjectives"`
+}
+
+// MaskCatalogue definition, the shapes and colors of the luchador masks by config key
+// and the name word lists by locale
+type MaskCatalogue struct {
+	ColorKeys []string                 `json:"colorKeys"`
+	Shapes    map[string][]MaskItem    `json:"shapes"`
+	Colors    []MaskItem               `json:"colors"`
+	Names     map[string]MaskNameWords `json:"names"`
+}
+
+var maskCatalogue = MaskCatalogue{
+	ColorKeys: []string{},
+	Shapes:    map[string][]MaskItem{},
+	Colors:    []MaskItem{},
+	Names:     map[string]MaskNameWords{},
+}
+
+// the options derived from the catalogue, replaced with it and never changed
+var maskColors = []string{}
+var maskShapes = map[string][]string{}
+var nmsColors = []NMSColor{}
+var maskCatalogueMutex sync.RWMutex
+
+// GetMaskCatalogue definition
+func GetMaskCatalogue() MaskCatalogue {
+	maskCatalogueMutex.RLock()
+	defer maskCatalogueMutex.RUnlock()
+	return maskCatalogue
+}
+
+// SetMaskCatalogue replaces the catalogue, MaskColors, MaskShapes and NMSColors
+// return all the items of the catalogue
+func SetMaskCatalogue(catalogue MaskCatalogue) {
+	colors := append([]string{}, catalogue.ColorKeys...)
+
+	shapes := make(map[string][]string)
+	for key, items := range catalogue.Shapes {
+		shapes[key] = make([]string, 0, len(items))
+		for _, item := range items {
+			shapes[key] = append(shapes[key], item.Name)
+		}
+	}
+
+	palette := make([]NMSColor, 0, len(catalogue.Colors))
+	for _, color := range catalogue.Colors {
+		palette = append(palette, NMSColor{name: color.Name, hex: color.Hex})
+	}
+
+	maskCatalogueMutex.Lock()
+	defer maskCatalogueMutex.Unlock()
+
+	maskCatalogue = catalogue
+	maskColors = colors
+	maskShapes = shapes
+	nmsColors = palette
+}
+
+// NameWords definition, the word list of the locale or the default list
+func (catalogue MaskCatalogue) NameWords(locale string) MaskNameWords {
+	if words, found := catalogue.Names[locale]; found {
+		return words
+	}
+	return catalogue.Names[DefaultMaskNameLocale]
+}
+
+// NameLocales definition, the sorted locales with name word lists
+func (catalogue MaskCatalogue) NameLocales() []string {
+	result := make([]string, 0, len(catalogue.Names))
+	for locale := range catalogue.Names {
+		result = append(result, locale)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// MinLevel definition, the level unlocking the value of the config key, colors are found
+// by the hex code, false when the value is not in the catalogue
+func (catalogue MaskCatalogue) MinLevel(key string, value string) (uint, bool) {
+	if items, found := catalogue.Shapes[key]; found {
+		for _, item := range items {
+			if item.Name == value {
+				return item.MinLevel, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, colorKey := range catalogue.ColorKeys {
+		if colorKey != key {
+			continue
+		}
+		for _, item := range catalogue.Colors {
+			if strings.EqualFold(item.Hex, value) {
+				return item.MinLevel, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -1,50 +1,165 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
 
-// User definition
+// User definition, the name and email fields are synced from the identity claims on login,
+// the display name and avatar are chosen by the user
 type User struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"-"`
-	UpdatedAt time.Time  `json:"-"`
-	DeletedAt *time.Time `json:"-" faker:"-"`
-	Username  string     `json:"username"`
+	ID                uint       `gorm:"primary_key" json:"id"`
+	CreatedAt         time.Time  `json:"-"`
+	UpdatedAt         time.Time  `json:"-"`
+	DeletedAt         *time.Time `json:"-" faker:"-"`
+	Username          string     `json:"username"`
+	Name              string     `json:"name"`
+	FirstName         string     `json:"firstName"`
+	LastName          string     `json:"lastName"`
+	Email             string     `json:"email"`
+	EmailVerified     bool       `json:"emailVerified"`
+	DisplayName       string     `json:"displayName"`
+	AvatarMediaID     uint       `json:"avatarMediaID,omitempty"`
+	AvatarURL         string     `json:"avatarURL"`
+	ProfileVisibility string     `json:"profileVisibility"`
+	Locale            string     `json:"locale"`
+	SuspendedAt       *time.Time `json:"suspendedAt,omitempty"`
+	SuspensionReason  string     `json:"suspensionReason,omitempty"`
+}
+
+// classmates only see the username of private profiles, empty visibility is private
+const PROFILE_VISIBILITY_PRIVATE = "private"
+const PROFILE_VISIBILITY_CLASSMATES = "classmates"
+
+// UserProfileRequest definition
+type UserProfileRequest struct {
+	DisplayName       string `json:"displayName"`
+	AvatarMediaID     uint   `json:"avatarMediaID"`
+	ProfileVisibility string `json:"profileVisibility"`
+	Locale            string `json:"locale"`
+}
+
+// ClassmateProfile definition, the profile fields are empty when the profile is private
+type ClassmateProfile struct {
+	UserID      uint   `json:"userID"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	AvatarURL   string `json:"avatarURL"`
 }
 
 // UserDetails definition
 type UserDetails struct {
-	User       *User       `json:"user"`
-	Classrooms []Classroom `json:"classrooms"`
-	Roles      []string    `json:"roles"`
-	Settings   UserSetting `json:"settings"`
-	Level      UserLevel   `json:"level"`
+	User        *User       `json:"user"`
+	Classrooms  []Classroom `json:"classrooms"`
+	Roles       []string    `json:"roles"`
+	Permissions []string    `json:"permissions"`
+	Settings    UserSetting `json:"settings"`
+	Level       UserLevel   `json:"level"`
 }
 
-// Session definition
+// Session definition, created on the first request with a token,
+// the user and roles are cached until the token expires or the session is revoked
 type Session struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"-"`
-	UpdatedAt time.Time  `json:"-"`
-	DeletedAt *time.Time `json:"-" faker:"-"`
-	UUID      string     `json:"UUID"`
-	UserID    uint       `json:"userID"`
+	ID         uint       `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"-"`
+	DeletedAt  *time.Time `json:"-" faker:"-"`
+	UUID       string     `gorm:"unique_index" json:"UUID"`
+	UserID     uint       `json:"userID"`
+	TokenHash  string     `gorm:"unique_index" json:"-"`
+	Roles      string     `json:"roles"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	UserAgent  string     `json:"userAgent"`
+	RemoteAddr string     `json:"remoteAddr"`
+}
+
+// RoleList returns the cached roles
+func (session *Session) RoleList() []string {
+	result := make([]string, 0)
+	for _, role := range strings.Split(session.Roles, ",") {
+		if role != "" {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+// APIKey definition, only the sha256 hash of the key is stored,
+// Prefix identifies the key in the logs and in the admin pages
+type APIKey struct {
+	ID                uint       `gorm:"primary_key" json:"id"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"-"`
+	DeletedAt         *time.Time `json:"-" faker:"-"`
+	Name              string     `gorm:"unique_index" json:"name"`
+	Prefix            string     `json:"prefix"`
+	KeyHash           string     `gorm:"unique_index" json:"-"`
+	PreviousKeyHash   string     `json:"-"`
+	PreviousExpiresAt *time.Time `json:"previousExpiresAt"`
+	Scopes            string     `json:"scopes"`
+	ExpiresAt         *time.Time `json:"expiresAt"`
+	LastUsedAt        *time.Time `json:"lastUsedAt"`
+	RevokedAt         *time.Time `json:"revokedAt"`
+	CreatedByUserID   uint       `json:"createdByUserID"`
+}
+
+const APIKEY_SCOPE_RUNNER = "runner"
+const APIKEY_SCOPE_METADATA = "metadata"
+const APIKEY_SCOPE_ANALYTICS = "analytics"
+
+// APIKeyScopes lists the valid scopes
+var APIKeyScopes = []string{APIKEY_SCOPE_RUNNER, APIKEY_SCOPE_METADATA, APIKEY_SCOPE_ANALYTICS}
+
+// HasScope checks if the key was issued with the scope
+func (key *APIKey) HasScope(scope string) bool {
+	for _, value := range strings.Split(key.Scopes, ",") {
+		if value == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRequest definition
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// APIKeyResponse definition, Key is only returned when the key is issued or rotated
+type APIKeyResponse struct {
+	APIKey *APIKey `json:"apiKey"`
+	Key    string  `json:"key"`
+}
+
+// DevLoginRequest definition
+type DevLoginRequest struct {
+	Username string `json:"username"`
+}
+
+// DevLoginResponse definition
+type DevLoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 // UserSetting definition
 type UserSetting struct {
-	ID              uint       `gorm:"primary_key" json:"id"`
-	CreatedAt       time.Time  `json:"-"`
-	UpdatedAt       time.Time  `json:"-"`
-	DeletedAt       *time.Time `json:"-" faker:"-"`
-	UserID          uint       `json:"userID"`
-	VisitedMainPage bool       `json:"visitedMainPage"`
-	VisitedMaskPage bool       `json:"visitedMaskPage"`
-	PlayedTutorial  bool       `json:"playedTutorial"`
+	ID                uint       `gorm:"primary_key" json:"id"`
+	CreatedAt         time.Time  `json:"-"`
+	UpdatedAt         time.Time  `json:"-"`
+	DeletedAt         *time.Time `json:"-" faker:"-"`
+	UserID            uint       `json:"userID"`
+	VisitedMainPage   bool       `json:"visitedMainPage"`
+	VisitedMaskPage   bool       `json:"visitedMaskPage"`
+	PlayedTutorial    bool       `json:"playedTutorial"`
+	DefaultLuchadorID uint       `json:"defaultLuchadorID"`
 }
 
 // UserLevel definition
@@ -57,6 +172,30 @@ type UserLevel struct {
 	Level     uint       `json:"level"`
 }
 
+// level change reasons
+const LEVEL_CHANGE_MATCH = "match"
+const LEVEL_CHANGE_TEACHER = "teacher"
+
+// the highest level set by the teachers, the minimum level of the advanced level group
+const MAX_USER_LEVEL = 100
+
+// UserLevelChange definition, audit log of the changes in UserLevel
+type UserLevelChange struct {
+	ID              uint      `gorm:"primary_key" json:"id"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UserID          uint      `json:"userID"`
+	PreviousLevel   uint      `json:"previousLevel"`
+	Level           uint      `json:"level"`
+	Reason          string    `json:"reason"`
+	MatchID         uint      `json:"matchID,omitempty"`
+	ChangedByUserID uint      `json:"changedByUserID,omitempty"`
+}
+
+// StudentLevelRequest definition
+type StudentLevelRequest struct {
+	Level uint `json:"level"`
+}
+
 // ActiveMatch definition, describes the result of findActiveMatches
 // mixing Tutorial and PVP matches
 type ActiveMatch struct {
@@ -74,6 +213,7 @@ type ActiveMatch struct {
 type PlayRequest struct {
 	AvailableMatchID uint `json:"availableMatchID"`
 	TeamID           uint `json:"teamID"`
+	LuchadorID       uint `json:"luchadorID,omitempty"`
 }
 
 // GameDefinition definition
@@ -126,9 +266,11 @@ type GameDefinition struct {
 	UnblockLevel                  uint                  `json:"unblockLevel"`
 	OwnerUserID                   uint                  `json:"ownerUserID"`
 	NextGamedefinitionID          uint                  `json:"nextGamedefinitionID"`
+	Hidden                        bool                  `gorm:"not null;default:false" json:"hidden"`
 	TeamDefinition                TeamDefinition        `json:"teamDefinition"`
 	Media                         Media                 `json:"media"`
 	NarrativeDefinitions          []NarrativeDefinition `json:"narrativeDefinitions"`
+	ProgressionRules              []ProgressionRule     `json:"progressionRules" faker:"-"`
 	GameComponents                []GameComponent       `json:"gameComponents"`
 	SceneComponents               []SceneComponent      `json:"sceneComponents"`
 	Codes                         []Code                `gorm:"many2many:gamedefinition_codes" json:"codes"`
@@ -148,22 +290,99 @@ type NarrativeDefinition struct {
 	SortOrder        uint       `json:"sortOrder"`
 }
 
+// progression rule types
+const PROGRESSION_RULE_MIN_SCORE = "min-score"
+const PROGRESSION_RULE_MIN_KILLS = "min-kills"
+const PROGRESSION_RULE_WINNING_TEAM = "winning-team"
+const PROGRESSION_RULE_NARRATIVE_EVENT = "narrative-event"
+
+// ProgressionRule definition, a participant reaches the UnblockLevel of the
+// game definition only when all the rules are satisfied
+type ProgressionRule struct {
+	ID               uint       `gorm:"primary_key" json:"id"`
+	CreatedAt        time.Time  `json:"-"`
+	UpdatedAt        time.Time  `json:"-"`
+	DeletedAt        *time.Time `json:"-" faker:"-"`
+	GameDefinitionID uint       `json:"gameDefinition,omitempty" faker:"-"`
+	Type             string     `json:"type"`
+	Value            int        `json:"value"`
+	Event            string     `json:"event"`
+}
+
 type MediaRequest struct {
 	FileName   string `json:"fileName"`
 	Base64Data string `json:"base64Data"`
 }
 
+// MediaUpdateRequest definition
+type MediaUpdateRequest struct {
+	FileName string `json:"fileName"`
+}
+
+// MediaUploadRequest definition, starts a resumable upload of the file size in bytes
+type MediaUploadRequest struct {
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+}
+
+// MediaUpload definition, the chunks are appended until Received is the Size of the
+// file and the media is created
+type MediaUpload struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time  `json:"-"`
+	UpdatedAt time.Time  `json:"-"`
+	DeletedAt *time.Time `json:"-" faker:"-"`
+	UserID    uint       `gorm:"index" json:"userID"`
+	FileName  string     `json:"fileName"`
+	Size      int64      `json:"size"`
+	Received  int64      `json:"received"`
+	MediaID   uint       `json:"mediaID,omitempty"`
+	Media     *Media     `gorm:"-" json:"media,omitempty"`
+}
+
+// MediaAttachRequest definition, either the game definition or the narrative definition
+type MediaAttachRequest struct {
+	GameDefinitionID      uint `json:"gameDefinitionID"`
+	NarrativeDefinitionID uint `json:"narrativeDefinitionID"`
+}
+
+// Media definition, the uploads of the user are the media library, attaching a media
+// to a game definition or a narrative creates a copy sharing the files with SourceMediaID
 type Media struct {
-	ID                    uint       `gorm:"primary_key" json:"id"`
-	CreatedAt             time.Time  `json:"-"`
-	UpdatedAt             time.Time  `json:"-"`
-	DeletedAt             *time.Time `json:"-" faker:"-"`
-	GameDefinitionID      uint       `json:"gameDefinition,omitempty" faker:"-"`
-	NarrativeDefinitionID uint       `json:"narrativeDefinition,omitempty" faker:"-"`
-	UserID                uint       `json:"userID,omitempty"`
-	FileName              string     `json:"fileName"`
-	URL                   string     `json:"url"`
-	Thumbnail             string     `json:"thumbnail"`
+	ID                    uint             `gorm:"primary_key" json:"id"`
+	CreatedAt             time.Time        `json:"-"`
+	UpdatedAt             time.Time        `json:"-"`
+	DeletedAt             *time.Time       `json:"-" faker:"-"`
+	GameDefinitionID      uint             `json:"gameDefinition,omitempty" faker:"-"`
+	NarrativeDefinitionID uint             `json:"narrativeDefinition,omitempty" faker:"-"`
+	UserID                uint             `json:"userID,omitempty"`
+	FileName              string           `json:"fileName"`
+	URL                   string           `json:"url"`
+	Thumbnail             string           `json:"thumbnail"`
+	ContentType           string           `json:"contentType"`
+	Size                  int64            `json:"size"`
+	Width                 int              `json:"width"`
+	Height                int              `json:"height"`
+	Hash                  string           `gorm:"index" json:"hash"`
+	Key                   string           `json:"-" faker:"-"`
+	SourceMediaID         uint             `gorm:"index" json:"sourceMediaID,omitempty" faker:"-"`
+	Renditions            []MediaRendition `json:"renditions" faker:"-"`
+}
+
+// MediaRendition definition, a resized copy of the media image
+type MediaRendition struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time  `json:"-"`
+	UpdatedAt   time.Time  `json:"-"`
+	DeletedAt   *time.Time `json:"-" faker:"-"`
+	MediaID     uint       `json:"mediaID"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url"`
+	ContentType string     `json:"contentType"`
+	Size        int64      `json:"size"`
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Key         string     `json:"-" faker:"-"`
 }
 
 type TeamDefinition struct {
@@ -378,6 +597,17 @@ type MatchScore struct {
 	Score      int        `json:"score"`
 }
 
+// MatchEvent definition, narrative events reached by a luchador during the match
+type MatchEvent struct {
+	ID         uint       `gorm:"primary_key" json:"id,omitempty"`
+	CreatedAt  time.Time  `json:"-"`
+	UpdatedAt  time.Time  `json:"-"`
+	DeletedAt  *time.Time `json:"-" faker:"-"`
+	MatchID    uint       `json:"matchID"`
+	LuchadorID uint       `json:"luchadorID"`
+	Event      string     `json:"event"`
+}
+
 // MatchMetric definition
 type MatchMetric struct {
 	ID               uint       `gorm:"primary_key" json:"id,omitempty"`
@@ -399,6 +629,7 @@ type Classroom struct {
 	Name       string     `json:"name"`
 	AccessCode string     `json:"accessCode" gorm:"not null;unique_index"`
 	OwnerID    uint       `json:"ownerID,omitempty"`
+	Locale     string     `json:"locale"`
 	Students   []Student  `gorm:"many2many:classroom_students" json:"students"`
 }
 
@@ -414,9 +645,57 @@ type Student struct {
 
 // StudentResponse definition
 type StudentResponse struct {
-	StudentID uint   `json:"studentID"`
-	UserID    uint   `json:"userID"`
-	Username  string `json:"username"`
+	StudentID   uint   `json:"studentID"`
+	UserID      uint   `json:"userID"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+}
+
+// RosterImportResponse definition
+type RosterImportResponse struct {
+	DryRun          bool                `json:"dryRun"`
+	Enrolled        []string            `json:"enrolled"`
+	AlreadyEnrolled []string            `json:"alreadyEnrolled"`
+	Invalid         []RosterImportError `json:"invalid"`
+}
+
+// RosterImportError definition
+type RosterImportError struct {
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+}
+
+// ClassroomReport definition, progress of all the students in a classroom
+type ClassroomReport struct {
+	ClassroomID uint              `json:"classroomID"`
+	Name        string            `json:"name"`
+	Students    []StudentProgress `json:"students"`
+}
+
+// StudentProgress definition
+type StudentProgress struct {
+	StudentID          uint                 `json:"studentID"`
+	UserID             uint                 `json:"userID"`
+	Username           string               `json:"username"`
+	Level              uint                 `json:"level"`
+	PlayedTutorial     bool                 `json:"playedTutorial"`
+	TutorialsCompleted uint                 `json:"tutorialsCompleted"`
+	MatchesPlayed      uint                 `json:"matchesPlayed"`
+	RecentMatches      []StudentMatchResult `json:"recentMatches"`
+	CodeVersions       uint                 `json:"codeVersions"`
+	LastCodeUpdate     *time.Time           `json:"lastCodeUpdate"`
+	LastSeen           *time.Time           `json:"lastSeen"`
+}
+
+// StudentMatchResult definition
+type StudentMatchResult struct {
+	MatchID            uint      `json:"matchID"`
+	GameDefinitionID   uint      `json:"gameDefinitionID"`
+	GameDefinitionName string    `json:"gameDefinitionName"`
+	TimeStart          time.Time `json:"timeStart"`
+	Kills              int       `json:"kills"`
+	Deaths             int       `json:"deaths"`
+	Score              int       `json:"score"`
 }
 
 // AvailableMatch definition
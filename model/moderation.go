@@ -0,0 +1,100 @@
+package model
+
+import "time"
+
+const MODERATION_FLAG_PENDING = "pending"
+const MODERATION_FLAG_CONFIRMED = "confirmed"
+const MODERATION_FLAG_DISMISSED = "dismissed"
+
+// content types checked by the moderation
+const MODERATION_CONTENT_LUCHADOR = "luchador"
+const MODERATION_CONTENT_PROFILE = "profile"
+const MODERATION_CONTENT_CLASSROOM = "classroom"
+const MODERATION_CONTENT_GAMEDEFINITION = "gamedefinition"
+const MODERATION_CONTENT_MEDIA = "media"
+const MODERATION_CONTENT_SKILL = "skill"
+const MODERATION_CONTENT_LEARNING_OBJECTIVE = "learning-objective"
+const MODERATION_CONTENT_ACTIVITY = "activity"
+const MODERATION_CONTENT_ASSIGNMENT = "assignment"
+
+// ModerationFlag definition, free text rejected by the moderation waiting for review,
+// ContentID is empty when the content was being created
+type ModerationFlag struct {
+	ID               uint       `gorm:"primary_key" json:"id"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"-"`
+	DeletedAt        *time.Time `json:"-" faker:"-"`
+	UserID           uint       `json:"userID"`
+	ContentType      string     `json:"contentType"`
+	ContentID        uint       `json:"contentID,omitempty"`
+	Field            string     `json:"field"`
+	Text             string     `gorm:"size:125000" json:"text"`
+	Locale           string     `json:"locale"`
+	Matches          string     `json:"matches"`
+	Status           string     `json:"status"`
+	ReviewedByUserID uint       `json:"reviewedByUserID,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewedAt"`
+	Note             string     `json:"note"`
+}
+
+// ModerationAllowedWord definition, words allowed by the reviewers on top of the allow-list files,
+// an empty locale allows the word for all the locales
+type ModerationAllowedWord struct {
+	ID              uint       `gorm:"primary_key" json:"id"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"-"`
+	DeletedAt       *time.Time `json:"-" faker:"-"`
+	Word            string     `json:"word"`
+	Locale          string     `json:"locale"`
+	CreatedByUserID uint       `json:"createdByUserID"`
+}
+
+// ModerationReviewRequest definition, allowMatches adds the matched words
+// to the allow-list when a flag is dismissed
+type ModerationReviewRequest struct {
+	Note         string `json:"note"`
+	AllowMatches bool   `json:"allowMatches"`
+}
+
+const MODERATION_REPORT_PENDING = "pending"
+const MODERATION_REPORT_RESOLVED = "resolved"
+const MODERATION_REPORT_DISMISSED = "dismissed"
+
+// actions taken by the moderators when a report is resolved, an empty action
+// resolves the report without changing the content
+const MODERATION_ACTION_RENAME_LUCHADOR = "rename-luchador"
+const MODERATION_ACTION_HIDE_MAP = "hide-map"
+const MODERATION_ACTION_SUSPEND_USER = "suspend-user"
+
+// ModerationReport definition, content reported by a user waiting for a moderator,
+// ReportedUserID is the owner of the content
+type ModerationReport struct {
+	ID               uint       `gorm:"primary_key" json:"id"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"-"`
+	DeletedAt        *time.Time `json:"-" faker:"-"`
+	ReporterUserID   uint       `json:"reporterUserID"`
+	ContentType      string     `json:"contentType"`
+	ContentID        uint       `json:"contentID"`
+	ReportedUserID   uint       `json:"reportedUserID"`
+	Reason           string     `gorm:"size:2000" json:"reason"`
+	Status           string     `json:"status"`
+	Action           string     `json:"action"`
+	ReviewedByUserID uint       `json:"reviewedByUserID,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewedAt"`
+	Note             string     `json:"note"`
+}
+
+// ModerationReportRequest definition, contentType is luchador, gamedefinition or classroom
+type ModerationReportRequest struct {
+	ContentType string `json:"contentType"`
+	ContentID   uint   `json:"contentID"`
+	Reason      string `json:"reason"`
+}
+
+// ModerationActionRequest definition, name is the new luchador name when the action is rename-luchador
+type ModerationActionRequest struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	Note   string `json:"note"`
+}
@@ -6,109 +6,19 @@ type NMSColor struct {
 	hex  string
 }
 
-var VINE_TOMATO string = "#EF5026"
-var EGGPLANT string = "#3C3755"
-var RED_PEPPER string = "#C52728"
-var PURPLE_CABBAGE string = "#8E4980"
-var BROCCOLI string = "#2C401B"
-var BRUSSELS_SPROUTS string = "#86A23E"
-var POTATO string = "#D79851"
-var RHUBARB string = "#C15866"
-var APPLE string = "#E5493D"
-var OKRA string = "#5E7434"
-var JICAMA string = "#CB726E"
-var FENNEL string = "#CBC7A1"
-var LEMON string = "#FAF599"
-var APRICOT string = "#FFBE30"
-var AVOCADO string = "#C9C561"
-var BLUEBERRY string = "#353535"
-var SOUR_CHERRY string = "#AF1317"
-var BEET string = "#D73C2A"
-var ASPERAGUS string = "#C6BD62"
-var WATERCRESS string = "#9FB43B"
-var TANGERINE string = "#FAA21D"
-var BANANA string = "#FDDF6F"
-var SPRING_ONION string = "#702C23"
-var PEAR string = "#C8C846"
-var CHARD string = "#547838"
-var FIG string = "#68281F"
-var GOLDEN_KIWI string = "#F6D063"
-var YELLOW_BELLPEPPER string = "#FDC647"
-var SQUASH_BLOSSOM string = "#ECCC55"
-var RED_KIDNEY_BEAN string = "#CB9274"
-var SQUASH string = "#FBE70E"
-var MUSCAT_GRAPES string = "#AD709A"
-var RED_BELLPEPPER string = "#D12728"
-var CARROT string = "#E75F25"
-var GREEN_CABBAGE string = "#B4C346"
-var PINEAPPLE string = "#E2A528"
-var DRAGON_FRUIT string = "#C9A62E"
-var ZUCCHINI string = "#93A13F"
-var KALE string = "#47642E"
-var CUCUMBER string = "#456033"
-var PEACH string = "#F47747"
-var FIDDLEHEAD string = "#597A41"
-var PURPLE_CAULIFLOWER string = "#753C67"
-var CARAMBOLA string = "#E1B828"
-var PAPAYA string = "#EFAE20"
-var LONG_BEAN string = "#8A9E6D"
-var STRAWBERRY string = "#D62D28"
-var TURNIP string = "#B64885"
-var RADISH string = "#B32225"
-var ARTICHOKE string = "#879C3D"
-var BUTTER_SQUASH string = "#ECB481"
-var MANGOSTEEN string = "#9A1C1F"
+// Name of the color
+func (color NMSColor) Name() string {
+	return color.name
+}
+
+// Hex code of the color as "#RRGGBB"
+func (color NMSColor) Hex() string {
+	return color.hex
+}
 
-var NMSCOLORS = []NMSColor{
-	{name: "Vine Tomato", hex: VINE_TOMATO},
-	{name: "Eggplant", hex: EGGPLANT},
-	{name: "Red Pepper", hex: RED_PEPPER},
-	{name: "Purple Cabbage", hex: PURPLE_CABBAGE},
-	{name: "Broccoli", hex: BROCCOLI},
-	{name: "Brussel Sprouts", hex: BRUSSELS_SPROUTS},
-	{name: "Potato", hex: POTATO},
-	{name: "Rhubarb", hex: RHUBARB},
-	{name: "Apple", hex: APPLE},
-	{name: "Okra", hex: OKRA},
-	{name: "Jicama", hex: JICAMA},
-	{name: "Fennel", hex: FENNEL},
-	{name: "Lemon", hex: LEMON},
-	{name: "Apricot", hex: APRICOT},
-	{name: "Avocado", hex: AVOCADO},
-	{name: "Blueberry", hex: BLUEBERRY},
-	{name: "Sour Cherry", hex: SOUR_CHERRY},
-	{name: "Beet", hex: BEET},
-	{name: "Asparagus", hex: ASPERAGUS},
-	{name: "Watercress", hex: WATERCRESS},
-	{name: "Tangerine", hex: TANGERINE},
-	{name: "Banana", hex: BANANA},
-	{name: "Spring Onion", hex: SPRING_ONION},
-	{name: "Pear", hex: PEAR},
-	{name: "Chard", hex: CHARD},
-	{name: "Fig", hex: FIG},
-	{name: "Golden Kiwi", hex: GOLDEN_KIWI},
-	{name: "Yellow Bellpepper", hex: YELLOW_BELLPEPPER},
-	{name: "Squash Blossom", hex: SQUASH_BLOSSOM},
-	{name: "Red Kidney Bean", hex: RED_KIDNEY_BEAN},
-	{name: "Squash", hex: SQUASH},
-	{name: "Muscat Grapes", hex: MUSCAT_GRAPES},
-	{name: "Red Bellpepper", hex: RED_BELLPEPPER},
-	{name: "Carrot", hex: CARROT},
-	{name: "Green Cabbage", hex: GREEN_CABBAGE},
-	{name: "Pineapple", hex: PINEAPPLE},
-	{name: "Dragon Fruit", hex: DRAGON_FRUIT},
-	{name: "Zucchini", hex: ZUCCHINI},
-	{name: "Kale", hex: KALE},
-	{name: "Cucumber", hex: CUCUMBER},
-	{name: "Peach", hex: PEACH},
-	{name: "Fiddlehead", hex: FIDDLEHEAD},
-	{name: "Purple Cauliflower", hex: PURPLE_CAULIFLOWER},
-	{name: "Carambola", hex: CARAMBOLA},
-	{name: "Papaya", hex: PAPAYA},
-	{name: "Long Bean", hex: LONG_BEAN},
-	{name: "Strawberry", hex: STRAWBERRY},
-	{name: "Turnip", hex: TURNIP},
-	{name: "Radish", hex: RADISH},
-	{name: "Artichoke", hex: ARTICHOKE},
-	{name: "Butter Squash", hex: BUTTER_SQUASH},
-	{name: "Mangosteen", hex: MANGOSTEEN}}
+// NMSColors the colors of the mask catalogue, see SetMaskCatalogue, the result should not be changed
+func NMSColors() []NMSColor {
+	maskCatalogueMutex.RLock()
+	defer maskCatalogueMutex.RUnlock()
+	return nmsColors
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+var progressionRuleTypes = []string{
+	PROGRESSION_RULE_MIN_SCORE,
+	PROGRESSION_RULE_MIN_KILLS,
+	PROGRESSION_RULE_WINNING_TEAM,
+	PROGRESSION_RULE_NARRATIVE_EVENT,
+}
+
+// ProgressionRuleErrors definition, rules with unknown types are never satisfied and would
+// block the level up of all the participants of the game definition
+func ProgressionRuleErrors(rules []ProgressionRule) []string {
+	result := make([]string, 0)
+	for i, rule := range rules {
+		if !validProgressionRuleType(rule.Type) {
+			result = append(result, fmt.Sprintf("progressionRules[%v].type should be one of %v, found '%v'",
+				i, strings.Join(progressionRuleTypes, ", "), rule.Type))
+		} else if rule.Type == PROGRESSION_RULE_NARRATIVE_EVENT && strings.TrimSpace(rule.Event) == "" {
+			result = append(result, fmt.Sprintf("progressionRules[%v].event is required for %v", i, rule.Type))
+		}
+	}
+	return result
+}
+
+func validProgressionRuleType(ruleType string) bool {
+	for _, valid := range progressionRuleTypes {
+		if ruleType == valid {
+			return true
+		}
+	}
+	return false
+}
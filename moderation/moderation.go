@@ -0,0 +1,421 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// word lists are read from <metadata>/badword, lists in the root folder are used for all
+// the locales and lists in <metadata>/badword/locale/<locale> only for that locale and
+// the locales that include it
+const (
+	badWordFolder = "badword"
+	localeFolder  = "locale"
+	configFile    = "config.json"
+)
+
+const reloadIntervalEnv = "MODERATION_RELOAD_SECONDS"
+const defaultReloadInterval = time.Minute
+
+// localeConfig definition, the config.json of the locale folder, the substitutions of
+// the language are added to the default substitutions
+type localeConfig struct {
+	Substitutions map[string]string `json:"substitutions"`
+	Include       []string          `json:"include"`
+}
+
+// locale definition, the word lists and normalization rules of a language
+type locale struct {
+	name    string
+	list    *wordList
+	rules   *rules
+	include []string
+	// allow entries by the locale of the list and reviewed words by locale,
+	// normalized with the rules of this locale as the tokens it checks
+	allowed  map[string]map[string]bool
+	reviewed map[string]map[string]bool
+}
+
+// Service definition, checks free text against the word lists of the locale
+type Service struct {
+	mutex     sync.RWMutex
+	folder    string
+	global    *locale
+	locales   map[string]*locale
+	reviewed  []model.ModerationAllowedWord
+	signature string
+}
+
+var service = NewService("")
+
+// NewService creates a service reading the word lists from the metadata folder
+func NewService(folderName string) *Service {
+	return &Service{
+		folder:  folderName,
+		global:  &locale{list: &wordList{}, rules: defaultRules},
+		locales: make(map[string]*locale),
+	}
+}
+
+// Default returns the service used by the routes
+func Default() *Service {
+	return service
+}
+
+// Setup loads the word lists of the default service from the metadata folder
+func Setup(folderName string) {
+	service.mutex.Lock()
+	service.folder = folderName
+	service.mutex.Unlock()
+
+	service.Reload()
+}
+
+// ContainsBadWord checks the text with the default service
+func ContainsBadWord(text string, locale string) bool {
+	return len(service.Check(text, locale)) > 0
+}
+
+// Reload reads all the word lists again
+func (s *Service) Reload() {
+	s.mutex.RLock()
+	root := filepath.Join(s.folder, badWordFolder)
+	s.mutex.RUnlock()
+
+	signature := listSignature(root)
+	global := &locale{list: loadWordList(root), rules: defaultRules}
+	locales := make(map[string]*locale)
+
+	for _, name := range readLocaleFolders(filepath.Join(root, localeFolder)) {
+		loaded := loadLocale(filepath.Join(root, localeFolder, name))
+		loaded.name = normalizeLocale(name)
+		locales[loaded.name] = loaded
+	}
+
+	all := allLocales(global, locales)
+	for _, current := range all {
+		current.setAllowed(all)
+	}
+
+	s.mutex.Lock()
+	for _, current := range all {
+		current.setReviewed(s.reviewed)
+	}
+	s.global = global
+	s.locales = locales
+	s.signature = signature
+	s.mutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"folder":    root,
+		"words":     len(global.list.words),
+		"fragments": len(global.list.fragments),
+		"locales":   len(locales),
+	}).Info("Moderation word lists loaded")
+}
+
+// ReloadIfChanged reloads the word lists when any list file was added, removed or changed
+func (s *Service) ReloadIfChanged() bool {
+	s.mutex.RLock()
+	root := filepath.Join(s.folder, badWordFolder)
+	current := s.signature
+	s.mutex.RUnlock()
+
+	if listSignature(root) == current {
+		return false
+	}
+
+	s.Reload()
+	return true
+}
+
+// Watch checks the word list files for changes until stop is closed
+func (s *Service) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.ReloadIfChanged()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// WatchFromEnv starts watching the default service lists, MODERATION_RELOAD_SECONDS
+// sets the interval and 0 disables the hot reload
+func WatchFromEnv() {
+	interval := defaultReloadInterval
+	if value := os.Getenv(reloadIntervalEnv); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"value": value,
+				"error": err,
+			}).Error("Invalid moderation reload interval, using default")
+		} else {
+			interval = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if interval <= 0 {
+		log.Info("Moderation word lists hot reload disabled")
+		return
+	}
+
+	go service.Watch(interval, nil)
+}
+
+// SetReviewedWords replaces the words allowed by the reviewers
+func (s *Service) SetReviewedWords(words []model.ModerationAllowedWord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reviewed = words
+	for _, current := range allLocales(s.global, s.locales) {
+		current.setReviewed(words)
+	}
+}
+
+// Locales returns the sorted names of the locales with word lists
+func (s *Service) Locales() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]string, 0, len(s.locales))
+	for name := range s.locales {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SupportsLocale checks if there are word lists for the locale
+func (s *Service) SupportsLocale(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, found := s.locales[normalizeLocale(name)]
+	return found
+}
+
+// the global lists, the lists of the locale and the lists of the locales it includes,
+// all the lists are used when the locale is empty or unknown
+func (s *Service) selectLocales(name string) []*locale {
+	result := []*locale{s.global}
+
+	selected, found := s.locales[name]
+	if !found {
+		names := make([]string, 0, len(s.locales))
+		for name := range s.locales {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			result = append(result, s.locales[name])
+		}
+		return result
+	}
+
+	result = append(result, selected)
+	for _, include := range selected.include {
+		if included, found := s.locales[normalizeLocale(include)]; found && included != selected {
+			result = append(result, included)
+		}
+	}
+	return result
+}
+
+// Check returns the normalized tokens of the text matching the word lists, each list
+// is checked with the normalization rules of its locale
+func (s *Service) Check(text string, name string) []string {
+	name = normalizeLocale(name)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	selected := s.selectLocales(name)
+
+	found := make(map[string]bool)
+	for _, current := range selected {
+		for _, token := range strings.Split(current.rules.normalize(text), " ") {
+			if token == "" || found[token] || current.isAllowed(token, name, selected) {
+				continue
+			}
+
+			for _, variant := range variants(token) {
+				if current.list.match(variant) != "" {
+					found[token] = true
+					break
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(found))
+	for token := range found {
+		result = append(result, token)
+	}
+	sort.Strings(result)
+
+	if len(result) > 0 {
+		log.WithFields(log.Fields{
+			"text":    text,
+			"locale":  name,
+			"matches": result,
+		}).Info("Moderation check failed")
+	}
+
+	return result
+}
+
+// the token is normalized with the rules of the locale, it is allowed by the allow lists of
+// the selected locales and by the words reviewed for all the locales or the checked one
+func (l *locale) isAllowed(token string, name string, selected []*locale) bool {
+	for _, current := range selected {
+		if l.allowed[current.name][token] {
+			return true
+		}
+	}
+	return l.reviewed[""][token] || (name != "" && l.reviewed[name][token])
+}
+
+func (l *locale) setAllowed(all []*locale) {
+	l.allowed = make(map[string]map[string]bool)
+	for _, current := range all {
+		l.allowed[current.name] = l.normalizeEntries(current.list.allowed)
+	}
+}
+
+func (l *locale) setReviewed(words []model.ModerationAllowedWord) {
+	entries := make(map[string][]string)
+	for _, word := range words {
+		name := normalizeLocale(word.Locale)
+		entries[name] = append(entries[name], word.Word)
+	}
+
+	l.reviewed = make(map[string]map[string]bool)
+	for name, words := range entries {
+		l.reviewed[name] = l.normalizeEntries(words)
+	}
+}
+
+func (l *locale) normalizeEntries(entries []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, entry := range entries {
+		if normalized := strings.TrimSpace(l.rules.normalize(entry)); normalized != "" {
+			result[normalized] = true
+		}
+	}
+	return result
+}
+
+func allLocales(global *locale, locales map[string]*locale) []*locale {
+	result := []*locale{global}
+	for _, current := range locales {
+		result = append(result, current)
+	}
+	return result
+}
+
+// loads the word lists and the config.json of the locale folder, the default
+// substitutions are used when there is no config and the words of the lists are
+// written as the text after the substitutions
+func loadLocale(folderName string) *locale {
+	result := &locale{list: loadWordList(folderName), rules: defaultRules}
+
+	bytes, err := ioutil.ReadFile(filepath.Join(folderName, configFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithFields(log.Fields{
+				"folderName": folderName,
+				"error":      err,
+			}).Error("Error reading locale config")
+		}
+		return result
+	}
+
+	var config localeConfig
+	err = json.Unmarshal(bytes, &config)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"folderName": folderName,
+			"error":      err,
+		}).Error("Error parsing locale config, using default rules")
+		return result
+	}
+
+	if len(config.Substitutions) > 0 {
+		substitutions := make(map[string]string)
+		for from, to := range defaultSubstitutions {
+			substitutions[from] = to
+		}
+		for from, to := range config.Substitutions {
+			substitutions[from] = to
+		}
+		result.rules = newRules(substitutions)
+		result.list.substitute(result.rules)
+	}
+	result.include = config.Include
+	return result
+}
+
+// the locale folders are named by the language as "pt", region variants use the same lists
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if position := strings.IndexAny(locale, "-_"); position >= 0 {
+		locale = locale[:position]
+	}
+	return locale
+}
+
+func readLocaleFolders(folderName string) []string {
+	result := make([]string, 0)
+
+	files, err := ioutil.ReadDir(folderName)
+	if err != nil {
+		return result
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			result = append(result, file.Name())
+		}
+	}
+	return result
+}
+
+// name, size and modification time of all the list files
+func listSignature(root string) string {
+	var builder strings.Builder
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		fmt.Fprintf(&builder, "%v:%v:%v;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+
+	return builder.String()
+}
+
+// LoadReviewedWords loads the words allowed by the reviewers into the default service
+func LoadReviewedWords(ds *datasource.DataSource) {
+	service.SetReviewedWords(ds.FindAllModerationAllowedWords())
+}
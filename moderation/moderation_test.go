@@ -0,0 +1,171 @@
+package moderation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+func writeList(t *testing.T, folder string, name string, content string) {
+	err := os.MkdirAll(folder, 0755)
+	assert.Nil(t, err)
+	err = ioutil.WriteFile(filepath.Join(folder, name), []byte(content), 0644)
+	assert.Nil(t, err)
+}
+
+func setupLists(t *testing.T) (string, *Service) {
+	folder, err := ioutil.TempDir("", "moderation")
+	assert.Nil(t, err)
+
+	root := filepath.Join(folder, badWordFolder)
+	writeList(t, filepath.Join(root, wordFolder), "list.txt", "ass\n")
+	writeList(t, filepath.Join(root, fragmentFolder), "list.txt", "seu merda\nbunda")
+	writeList(t, filepath.Join(root, allowFolder), "list.txt", "class\n")
+	writeList(t, filepath.Join(root, localeFolder, "es", wordFolder), "list.txt", "tonto\ncabrón\n")
+	writeList(t, filepath.Join(root, localeFolder, "es", allowFolder), "list.txt", "p4ss\n")
+	writeList(t, filepath.Join(root, localeFolder, "es"), configFile, `{"substitutions": {"0": "o", "4": "a"}, "include": ["en"]}`)
+	writeList(t, filepath.Join(root, localeFolder, "en", wordFolder), "list.txt", "jerk\n")
+	writeList(t, filepath.Join(root, localeFolder, "pt", wordFolder), "list.txt", "bobo\n")
+
+	service := NewService(folder)
+	service.Reload()
+	return folder, service
+}
+
+func TestCheckGlobalLists(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	assert.Equal(t, []string{"ass"}, service.Check("Ass", ""))
+	assert.Equal(t, []string{"nabunda"}, service.Check("na nabunda", ""))
+	assert.Equal(t, []string{"ass", "bunda"}, service.Check("bunda @$$", "pt-BR"))
+	assert.Empty(t, service.Check("the best", ""))
+}
+
+func TestCheckAllowList(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	assert.Empty(t, service.Check("class", ""))
+	assert.Equal(t, []string{"glass"}, service.Check("glass", ""))
+
+	service.SetReviewedWords([]model.ModerationAllowedWord{{Word: "Glass"}, {Word: "grass", Locale: "en"}})
+	assert.Empty(t, service.Check("glass", ""))
+	assert.Empty(t, service.Check("grass", "en-US"))
+	assert.Equal(t, []string{"grass"}, service.Check("grass", "pt"))
+}
+
+func TestCheckAllowListLocaleRules(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	// the es rules replace 4 with a, allowed words are normalized with the same rules as the tokens
+	assert.Empty(t, service.Check("p4ss", "es"))
+	assert.Empty(t, service.Check("pass", "es"))
+	assert.Equal(t, []string{"pass"}, service.Check("pass", "en"))
+
+	assert.Equal(t, []string{"grass"}, service.Check("gr4ss", "es"))
+	service.SetReviewedWords([]model.ModerationAllowedWord{{Word: "gr4ss", Locale: "es"}})
+	assert.Empty(t, service.Check("gr4ss", "es"))
+	assert.Empty(t, service.Check("grass", "es"))
+
+	// reviewed words are kept when the lists are reloaded
+	service.Reload()
+	assert.Empty(t, service.Check("gr4ss", "es"))
+}
+
+func TestCheckLocaleLists(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	assert.Equal(t, []string{"en", "es", "pt"}, service.Locales())
+	assert.True(t, service.SupportsLocale("es-AR"))
+	assert.False(t, service.SupportsLocale("fr"))
+
+	// empty and unknown locales use all the lists
+	assert.Equal(t, []string{"tonto"}, service.Check("tonto", ""))
+	assert.Equal(t, []string{"bobo"}, service.Check("bobo", "fr"))
+
+	assert.Empty(t, service.Check("tonto", "pt"))
+	assert.Equal(t, []string{"tonto"}, service.Check("tonto", "es"))
+	assert.Equal(t, []string{"tonto"}, service.Check("t0nt0", "es-AR"))
+	assert.Equal(t, []string{"cabron"}, service.Check("Cabrón", "es"))
+
+	// es includes the en lists, en does not include es
+	assert.Equal(t, []string{"jerk"}, service.Check("jerk", "es"))
+	assert.Empty(t, service.Check("tonto", "en"))
+	assert.Empty(t, service.Check("bobo", "es"))
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "cabron", defaultRules.normalize("CABRÓN"))
+	assert.Equal(t, "puta", defaultRules.normalize("рutа"))
+	assert.Equal(t, "bunda", defaultRules.normalize("ｂｕｎｄａ"))
+	assert.Equal(t, "ass hole", defaultRules.normalize("@$$ h0le."))
+	assert.Equal(t, "a", newRules(map[string]string{"4": "a"}).normalize("4"))
+
+	assert.Equal(t, "fodase", normalizeEntry("foda-se"))
+	assert.Equal(t, "anus", normalizeEntry("ânus"))
+	assert.Equal(t, "", normalizeEntry("a2m"))
+
+	assert.Equal(t, []string{"fuuuck", "fuuck", "fuck"}, variants("fuuuck"))
+	assert.Equal(t, []string{"assss", "ass", "as"}, variants("assss"))
+	assert.Equal(t, []string{"test"}, variants("test"))
+}
+
+func TestCheckLocaleSubstitutions(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	root := filepath.Join(folder, badWordFolder, localeFolder, "pt")
+	writeList(t, filepath.Join(root, fragmentFolder), "list.txt", "xoxota\ncu\n")
+	writeList(t, filepath.Join(root, allowFolder), "list.txt", "cuidado\n")
+	writeList(t, root, configFile, `{"substitutions": {"x": "ch", "k": "c"}}`)
+	service.Reload()
+
+	// the words of the list and the text are compared after the substitutions
+	assert.Equal(t, []string{"chochota"}, service.Check("chochota", "pt"))
+	assert.Equal(t, []string{"chochota"}, service.Check("x0x0ta", "pt"))
+	assert.Equal(t, []string{"curto"}, service.Check("kurto", "pt"))
+	assert.Empty(t, service.Check("kuidado", "pt"))
+
+	// the other locales keep the default substitutions
+	assert.Empty(t, service.Check("chochota", "es"))
+}
+
+func TestNewRulesOrder(t *testing.T) {
+	rules := newRules(map[string]string{"p": "b", "ph": "f", "0": "o"})
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "fobo", rules.normalize("ph0p0"))
+	}
+}
+
+func TestReloadIfChanged(t *testing.T) {
+	folder, service := setupLists(t)
+	defer os.RemoveAll(folder)
+
+	assert.False(t, service.ReloadIfChanged())
+	assert.Empty(t, service.Check("bozo", ""))
+
+	root := filepath.Join(folder, badWordFolder)
+	writeList(t, filepath.Join(root, wordFolder), "extra.txt", "bozo\n")
+
+	assert.True(t, service.ReloadIfChanged())
+	assert.Equal(t, []string{"bozo"}, service.Check("bozo", ""))
+
+	err := os.Remove(filepath.Join(root, wordFolder, "extra.txt"))
+	assert.Nil(t, err)
+
+	stop := make(chan struct{})
+	go service.Watch(10*time.Millisecond, stop)
+	defer close(stop)
+
+	assert.Eventually(t, func() bool {
+		return len(service.Check("bozo", "")) == 0
+	}, time.Second, 10*time.Millisecond)
+}
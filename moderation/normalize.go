@@ -0,0 +1,155 @@
+package moderation
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// characters used to disguise words in all the languages, the config.json of the
+// locale adds the substitutions of the language
+var defaultSubstitutions = map[string]string{
+	"3": "e",
+	"1": "i",
+	"4": "a",
+	"@": "a",
+	"$": "s",
+	"&": "e",
+	"!": "i",
+	"5": "s",
+	"0": "o",
+	"7": "t",
+	"9": "g",
+}
+
+// latin letters with diacritics folded to the base letter
+var accents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a', 'ă': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c', 'č': 'c',
+	'ď': 'd', 'đ': 'd',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e',
+	'ğ': 'g',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ī': 'i', 'į': 'i', 'ı': 'i',
+	'ł': 'l', 'ľ': 'l',
+	'ñ': 'n', 'ń': 'n', 'ň': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o', 'ő': 'o',
+	'ř': 'r',
+	'ś': 's', 'š': 's', 'ş': 's', 'ß': 's',
+	'ť': 't', 'ţ': 't',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u', 'ů': 'u', 'ű': 'u', 'ų': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
+
+// cyrillic and greek letters that look like latin letters
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'с': 'c', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'ѕ': 's', 'т': 't', 'у': 'y', 'х': 'x',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// rules definition, how the text is normalized before checking the word lists of a locale
+type rules struct {
+	substitutions *strings.Replacer
+}
+
+// the longest substitutions are replaced first, "ph" before "p"
+func newRules(substitutions map[string]string) *rules {
+	keys := make([]string, 0, len(substitutions))
+	for from := range substitutions {
+		keys = append(keys, from)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	pairs := make([]string, 0, len(substitutions)*2)
+	for _, from := range keys {
+		pairs = append(pairs, from, substitutions[from])
+	}
+	return &rules{substitutions: strings.NewReplacer(pairs...)}
+}
+
+var defaultRules = newRules(defaultSubstitutions)
+
+// normalize folds accents, confusable and fullwidth characters, replaces the characters
+// used to disguise words and removes everything but the latin letters and spaces
+func (r *rules) normalize(sentence string) string {
+	sentence = r.substitutions.Replace(fold(sentence))
+
+	var builder strings.Builder
+	for _, c := range sentence {
+		if (c >= 'a' && c <= 'z') || c == ' ' {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
+
+// list entries are only folded, entries still written with digits or symbols as "a55"
+// are skipped as the text is checked after the substitutions
+func normalizeEntry(entry string) string {
+	var builder strings.Builder
+	for _, c := range fold(strings.TrimSpace(entry)) {
+		switch {
+		case (c >= 'a' && c <= 'z') || c == ' ':
+			builder.WriteRune(c)
+		case c == '-' || c == '_' || c == '\'':
+		default:
+			return ""
+		}
+	}
+	return builder.String()
+}
+
+func fold(sentence string) string {
+	var builder strings.Builder
+	for _, c := range strings.ToLower(sentence) {
+		// fullwidth forms as "ａ" are shifted to ascii
+		if c >= '！' && c <= '～' {
+			c = unicode.ToLower(c - 0xFEE0)
+		}
+		if folded, found := accents[c]; found {
+			c = folded
+		} else if folded, found := confusables[c]; found {
+			c = folded
+		} else if unicode.IsSpace(c) {
+			c = ' '
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// variants of the token with repeated letters squeezed, "fuuuck" is checked as "fuuck" and "fuck"
+func variants(token string) []string {
+	result := []string{token}
+	for _, max := range []int{2, 1} {
+		squeezed := squeeze(token, max)
+		if squeezed != result[len(result)-1] {
+			result = append(result, squeezed)
+		}
+	}
+	return result
+}
+
+// keeps at most max repetitions of the same letter
+func squeeze(token string, max int) string {
+	var result []byte
+	count := 0
+	for i := 0; i < len(token); i++ {
+		if i > 0 && token[i] == token[i-1] {
+			count++
+		} else {
+			count = 1
+		}
+		if count <= max {
+			result = append(result, token[i])
+		}
+	}
+	return string(result)
+}
@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// Field definition, a free text value of the content
+type Field struct {
+	Name string
+	Text string
+}
+
+// Content definition, the free text fields of a record created or updated by a user,
+// ID is empty when the record is being created
+type Content struct {
+	Type   string
+	ID     uint
+	UserID uint
+	Locale string
+	Fields []Field
+}
+
+// Inspect checks all the fields of the content with the default service, each field
+// with inappropriate language is added to the review queue and returned
+func Inspect(ds *datasource.DataSource, content Content) []model.ModerationFlag {
+	result := make([]model.ModerationFlag, 0)
+
+	for _, field := range content.Fields {
+		matches := service.Check(field.Text, content.Locale)
+		if len(matches) == 0 {
+			continue
+		}
+
+		flag := ds.AddModerationFlag(&model.ModerationFlag{
+			UserID:      content.UserID,
+			ContentType: content.Type,
+			ContentID:   content.ID,
+			Field:       field.Name,
+			Text:        field.Text,
+			Locale:      normalizeLocale(content.Locale),
+			Matches:     strings.Join(matches, ","),
+			Status:      model.MODERATION_FLAG_PENDING,
+		})
+		result = append(result, *flag)
+	}
+
+	return result
+}
+
+// Locale selects the locale used to check the content of the user, the locale of the user
+// profile, the locale of the first classroom of the user or the Accept-Language header
+func Locale(ds *datasource.DataSource, c *gin.Context, user *model.User) string {
+	if user != nil {
+		if user.Locale != "" {
+			return normalizeLocale(user.Locale)
+		}
+		if locale := ds.FindClassroomLocaleByStudent(user.ID); locale != "" {
+			return normalizeLocale(locale)
+		}
+	}
+
+	return RequestLocale(c)
+}
+
+// RequestLocale reads the preferred language from the Accept-Language header
+func RequestLocale(c *gin.Context) string {
+	header := c.GetHeader("Accept-Language")
+	first := strings.Split(header, ",")[0]
+	tag := strings.Split(first, ";")[0]
+	if tag == "*" {
+		return ""
+	}
+	return normalizeLocale(tag)
+}
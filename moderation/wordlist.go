@@ -0,0 +1,147 @@
+package moderation
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// folders inside a word list folder
+const (
+	wordFolder     = "word"
+	fragmentFolder = "fragment"
+	allowFolder    = "allow"
+)
+
+// wordList definition, words and fragments match a whole token or its start or end,
+// allowed entries are kept as written and normalized with the rules of each locale
+type wordList struct {
+	words     map[string]bool
+	fragments map[string]bool
+	allowed   []string
+}
+
+// loads the word, fragment and allow folders, missing folders are empty lists
+func loadWordList(folderName string) *wordList {
+	list := &wordList{
+		words:     createWordMap(filepath.Join(folderName, wordFolder)),
+		fragments: createWordMap(filepath.Join(folderName, fragmentFolder)),
+		allowed:   readEntries(filepath.Join(folderName, allowFolder)),
+	}
+
+	for key := range list.words {
+		delete(list.fragments, key)
+	}
+
+	return list
+}
+
+func createWordMap(folderName string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range readEntries(folderName) {
+		if normalized := normalizeEntry(word); normalized != "" {
+			result[normalized] = true
+		}
+	}
+	return result
+}
+
+// reads the entries of all the files in the folder
+func readEntries(folderName string) []string {
+	result := make([]string, 0)
+
+	files, err := ioutil.ReadDir(folderName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithFields(log.Fields{
+				"folderName": folderName,
+				"error":      err,
+			}).Error("Error loading word list files")
+		}
+		return result
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		fullPath := filepath.Join(folderName, file.Name())
+		log.WithFields(log.Fields{
+			"filename": fullPath,
+		}).Debug("Loading word list")
+
+		result = append(result, createListFromFile(fullPath)...)
+	}
+	return result
+}
+
+func createListFromFile(fileName string) []string {
+	var result []string
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"filename": fileName,
+			"error":    err,
+		}).Error("Error opening word list file")
+		return result
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// substitute writes the words and fragments as the text after the substitutions of the
+// rules, "xoxota" is matched as "chochota" when the rules replace "x" by "ch"
+func (list *wordList) substitute(r *rules) {
+	for _, entries := range []*map[string]bool{&list.words, &list.fragments} {
+		substituted := make(map[string]bool)
+		for entry := range *entries {
+			substituted[r.substitutions.Replace(entry)] = true
+		}
+		*entries = substituted
+	}
+}
+
+// match returns the list word found in the token, or an empty string
+func (list *wordList) match(token string) string {
+	if list.fragments[token] || list.words[token] {
+		return token
+	}
+
+	// starts or ends with fragment
+	for word := range list.fragments {
+		if matchesEdge(token, word) {
+			return word
+		}
+	}
+
+	// starts or ends with word
+	for word := range list.words {
+		if matchesEdge(token, word) {
+			return word
+		}
+	}
+
+	return ""
+}
+
+func matchesEdge(token string, word string) bool {
+	wordNoSpace := strings.ReplaceAll(word, " ", "")
+	if wordNoSpace == "" {
+		return false
+	}
+	return strings.HasPrefix(token, wordNoSpace) || strings.HasSuffix(token, wordNoSpace)
+}
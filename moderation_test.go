@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func findModerationFlags(t *testing.T, status string) []model.ModerationFlag {
+	w := test.PerformRequestNoAuth(router, "GET", "/dashboard/moderation/flag?status="+status, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result []model.ModerationFlag
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestModerationFlagReview(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+	defer moderation.Default().SetReviewedWords(nil)
+
+	w := test.PerformRequestNoAuth(router, "POST", "/private/classroom", `{"name":"Bunda"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	flags := findModerationFlags(t, model.MODERATION_FLAG_PENDING)
+	assert.Equal(t, 1, len(flags))
+	assert.Equal(t, model.MODERATION_CONTENT_CLASSROOM, flags[0].ContentType)
+	assert.Equal(t, "name", flags[0].Field)
+	assert.Equal(t, "bunda", flags[0].Matches)
+
+	// dismissing the flag with allowMatches allows the text
+	path := fmt.Sprintf("/dashboard/moderation/flag/%v/dismiss", flags[0].ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"allowMatches":true,"note":"town name"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", path, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/classroom", `{"name":"Bunda"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Empty(t, findModerationFlags(t, model.MODERATION_FLAG_PENDING))
+	dismissed := findModerationFlags(t, model.MODERATION_FLAG_DISMISSED)
+	assert.Equal(t, 1, len(dismissed))
+	assert.Equal(t, "town name", dismissed[0].Note)
+	assert.NotNil(t, dismissed[0].ReviewedAt)
+
+	w = test.PerformRequestNoAuth(router, "GET", "/dashboard/moderation/flag?status=unknown", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestModerationAppliedToFreeText(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequestNoAuth(router, "POST", "/dashboard/skill", `{"name":"loops","description":"seu merda"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/mapeditor",
+		`{"name":"arena","narrativeDefinitions":[{"text":"hello"},{"text":"na bunda"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/media", `{"fileName":"ass.png"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/classroom", `{"name":"Class 5A"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	flags := findModerationFlags(t, model.MODERATION_FLAG_PENDING)
+	assert.Equal(t, 3, len(flags))
+	assert.Equal(t, "description", flags[0].Field)
+	assert.Equal(t, "narrativeDefinitions[1].text", flags[1].Field)
+	assert.Equal(t, "fileName", flags[2].Field)
+
+	path := fmt.Sprintf("/dashboard/moderation/flag/%v/confirm", flags[0].ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(findModerationFlags(t, model.MODERATION_FLAG_CONFIRMED)))
+}
+
+func TestModerationLocaleSelection(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequestNoAuth(router, "POST", "/private/classroom", `{"name":"Robótica","locale":"fr"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/private/classroom", `{"name":"Robótica","locale":"es"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var classroom model.Classroom
+	json.Unmarshal(w.Body.Bytes(), &classroom)
+	assert.Equal(t, "es", classroom.Locale)
+
+	// the profile locale is used first
+	w = test.PerformRequestNoAuth(router, "PUT", "/private/user/profile", `{"displayName":"pendejo","locale":"en"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "en", ds.FindUserByID(1).Locale)
+
+	w = test.PerformRequestNoAuth(router, "PUT", "/private/user/profile", `{"displayName":"pendejo","locale":"es"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// then the locale of the classroom of the student
+	url := fmt.Sprintf("/private/join-classroom/%v", classroom.AccessCode)
+	w = test.PerformRequest(router, "POST", url, "", "student")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "PUT", "/private/user/profile", `{"displayName":"pendejo"}`, "student")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// then the Accept-Language header
+	request, _ := http.NewRequest("PUT", "/private/user/profile", strings.NewReader(`{"displayName":"pendejo"}`))
+	request.Header.Set("Authorization", "visitor")
+	request.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	flags := findModerationFlags(t, model.MODERATION_FLAG_PENDING)
+	assert.Equal(t, 2, len(flags))
+	assert.Equal(t, "es", flags[0].Locale)
+	assert.Equal(t, "es", flags[1].Locale)
+}
+
+func findModerationReports(t *testing.T, status string) []model.ModerationReport {
+	w := test.PerformRequestNoAuth(router, "GET", "/dashboard/moderation/report?status="+status, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result []model.ModerationReport
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func addModerationReport(contentType string, contentID uint, authorization string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"contentType":"%v","contentID":%v,"reason":"offensive"}`, contentType, contentID)
+	return test.PerformRequest(router, "POST", "/private/moderation/report", body, authorization)
+}
+
+func TestModerationReportRenameLuchador(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequest(router, "GET", "/private/luchador", "", "maria")
+	assert.Equal(t, http.StatusOK, w.Code)
+	luchador := ds.FindLuchador(ds.FindUserByUsername("maria"))
+
+	w = addModerationReport(model.MODERATION_CONTENT_LUCHADOR, luchador.ID, "maria")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = addModerationReport("match", luchador.ID, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = addModerationReport(model.MODERATION_CONTENT_LUCHADOR, 999, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the same user reporting again does not create a new report
+	w = addModerationReport(model.MODERATION_CONTENT_LUCHADOR, luchador.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = addModerationReport(model.MODERATION_CONTENT_LUCHADOR, luchador.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	reports := findModerationReports(t, model.MODERATION_REPORT_PENDING)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, luchador.UserID, reports[0].ReportedUserID)
+	assert.Equal(t, "offensive", reports[0].Reason)
+
+	path := fmt.Sprintf("/dashboard/moderation/report/%v/resolve", reports[0].ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"action":"hide-map"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the new name has the same validations of the luchador update
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"action":"rename-luchador","name":"ab"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"action":"rename-luchador","name":"Renamed Luchador"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Renamed Luchador", ds.FindLuchadorByID(luchador.ID).Name)
+
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"action":"rename-luchador","name":"Other Name"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	resolved := findModerationReports(t, model.MODERATION_REPORT_RESOLVED)
+	assert.Equal(t, 1, len(resolved))
+	assert.Equal(t, model.MODERATION_ACTION_RENAME_LUCHADOR, resolved[0].Action)
+}
+
+func TestModerationReportHideMap(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	maria := ds.CreateUser("maria")
+	gameDefinition := ds.CreateGameDefinition(&model.GameDefinition{Name: "arena", OwnerUserID: maria.ID})
+	ds.CreateAvailableMatchIfDontExist(gameDefinition.ID, "arena")
+
+	assert.Equal(t, 1, len(*ds.FindPublicAvailableMatch()))
+
+	w := addModerationReport(model.MODERATION_CONTENT_GAMEDEFINITION, gameDefinition.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report model.ModerationReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	path := fmt.Sprintf("/dashboard/moderation/report/%v/resolve", report.ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, `{"action":"hide-map"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, *ds.FindPublicAvailableMatch())
+
+	path = fmt.Sprintf("/dashboard/moderation/map/%v/unhide", gameDefinition.ID)
+	w = test.PerformRequestNoAuth(router, "POST", path, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(*ds.FindPublicAvailableMatch()))
+
+	w = test.PerformRequestNoAuth(router, "POST", "/dashboard/moderation/map/999/unhide", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestModerationReportClassroom(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	owner := ds.CreateUser("teacher")
+	classroom := ds.AddClassroom(&model.Classroom{Name: "classroom", OwnerID: owner.ID})
+
+	w := addModerationReport(model.MODERATION_CONTENT_CLASSROOM, 999, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, findModerationReports(t, model.MODERATION_REPORT_PENDING))
+
+	w = addModerationReport(model.MODERATION_CONTENT_CLASSROOM, classroom.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	reports := findModerationReports(t, model.MODERATION_REPORT_PENDING)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, owner.ID, reports[0].ReportedUserID)
+}
+
+func TestModerationReportSuspendUser(t *testing.T) {
+	SetupSession(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	maria := devLoginToken(t, "maria")
+	admin := devLoginToken(t, "admin")
+
+	w := test.PerformRequest(router, "GET", "/private/luchador", "", maria)
+	assert.Equal(t, http.StatusOK, w.Code)
+	user := ds.FindUserByUsername("maria")
+	luchador := ds.FindLuchador(user)
+
+	w = addModerationReport(model.MODERATION_CONTENT_LUCHADOR, luchador.ID, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report model.ModerationReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	path := fmt.Sprintf("/dashboard/moderation/report/%v/resolve", report.ID)
+	w = test.PerformRequest(router, "POST", path, `{"action":"suspend-user","note":"abusive names"}`, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abusive names", ds.FindUserByID(user.ID).SuspensionReason)
+
+	// the current and the new sessions of the user are rejected
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	maria = devLoginToken(t, "maria")
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	path = fmt.Sprintf("/dashboard/moderation/user/%v/unsuspend", user.ID)
+	w = test.PerformRequest(router, "POST", path, "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestModerationAssignment(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+
+	w := test.PerformRequestNoAuth(router, "POST", "/dashboard/assignment", `{"name":"loops","description":"seu merda"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	flags := findModerationFlags(t, model.MODERATION_FLAG_PENDING)
+	assert.Equal(t, 1, len(flags))
+	assert.Equal(t, model.MODERATION_CONTENT_ASSIGNMENT, flags[0].ContentType)
+	assert.Equal(t, "description", flags[0].Field)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/dashboard/assignment", "null")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = test.PerformRequestNoAuth(router, "POST", "/dashboard/assignment", `{"name":"Loops","description":"for and while"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var assignment model.Assignment
+	json.Unmarshal(w.Body.Bytes(), &assignment)
+	assert.Equal(t, "Loops", assignment.Name)
+	assert.Equal(t, "Loops", ds.FindAssignmentById(assignment.ID).Name)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func SetupProfile(t *testing.T) {
+	SetupClassroom(t)
+
+	users := []auth.JWTUser{
+		{Username: "maria", Name: "Maria Silva", FirstName: "Maria", LastName: "Silva",
+			Email: "maria@robolucha.com", EmailVerified: true},
+		{Username: "joao", Name: "Joao Souza", FirstName: "Joao"},
+		{Username: "other"},
+	}
+	provider, err := auth.NewDevProvider(users)
+	assert.Nil(t, err)
+	auth.EnableDevProvider(provider)
+
+	router = createRouter(test.API_KEY, "true", auth.SessionIsValid, auth.SessionIsValid)
+}
+
+func updateProfile(token string, request model.UserProfileRequest) (int, model.User) {
+	body, _ := json.Marshal(request)
+	w := test.PerformRequest(router, "PUT", "/private/user/profile", string(body), token)
+
+	var user model.User
+	json.Unmarshal(w.Body.Bytes(), &user)
+	return w.Code, user
+}
+
+func TestProfileSyncAndUpdate(t *testing.T) {
+	SetupProfile(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	maria := devLoginToken(t, "maria")
+
+	w := test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var details model.UserDetails
+	json.Unmarshal(w.Body.Bytes(), &details)
+	assert.Equal(t, "Maria Silva", details.User.Name)
+	assert.Equal(t, "Silva", details.User.LastName)
+	assert.Equal(t, "maria@robolucha.com", details.User.Email)
+	assert.True(t, details.User.EmailVerified)
+	assert.Equal(t, "Maria", details.User.DisplayName)
+
+	other := ds.CreateUser("someone")
+	otherMedia := model.Media{UserID: other.ID, URL: "http://media/other.png"}
+	ds.DB.Create(&otherMedia)
+	media := model.Media{UserID: details.User.ID, URL: "http://media/maria.png"}
+	ds.DB.Create(&media)
+
+	invalid := []model.UserProfileRequest{
+		{DisplayName: "a display name that is longer than forty characters"},
+		{DisplayName: "Maria", ProfileVisibility: "everyone"},
+		{DisplayName: "Maria", AvatarMediaID: otherMedia.ID},
+		{DisplayName: "Maria", AvatarMediaID: 999},
+	}
+	for _, request := range invalid {
+		code, _ := updateProfile(maria, request)
+		assert.Equal(t, http.StatusBadRequest, code, request)
+	}
+
+	code, user := updateProfile(maria, model.UserProfileRequest{
+		DisplayName:       " Mari ",
+		AvatarMediaID:     media.ID,
+		ProfileVisibility: model.PROFILE_VISIBILITY_CLASSMATES,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Mari", user.DisplayName)
+	assert.Equal(t, media.URL, user.AvatarURL)
+
+	// the chosen display name is kept on the next login
+	maria = devLoginToken(t, "maria")
+	test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, "Mari", ds.FindUserByUsername("maria").DisplayName)
+}
+
+func TestClassmates(t *testing.T) {
+	SetupProfile(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	maria := devLoginToken(t, "maria")
+	joao := devLoginToken(t, "joao")
+	other := devLoginToken(t, "other")
+	test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	test.PerformRequest(router, "GET", "/private/get-user", "", joao)
+	test.PerformRequest(router, "GET", "/private/get-user", "", other)
+
+	teacher := ds.CreateUser("teacher")
+	classroom := ds.AddClassroom(&model.Classroom{Name: "A", OwnerID: teacher.ID})
+	ds.JoinClassroom(ds.FindUserByUsername("maria"), classroom.AccessCode)
+	ds.JoinClassroom(ds.FindUserByUsername("joao"), classroom.AccessCode)
+
+	code, _ := updateProfile(maria, model.UserProfileRequest{
+		DisplayName:       "Mari",
+		ProfileVisibility: model.PROFILE_VISIBILITY_CLASSMATES,
+	})
+	assert.Equal(t, http.StatusOK, code)
+
+	url := fmt.Sprintf("/private/classroom/%v/classmates", classroom.ID)
+	w := test.PerformRequest(router, "GET", url, "", joao)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var classmates []model.ClassmateProfile
+	json.Unmarshal(w.Body.Bytes(), &classmates)
+	assert.Equal(t, 2, len(classmates))
+
+	profiles := make(map[string]model.ClassmateProfile)
+	for _, profile := range classmates {
+		profiles[profile.Username] = profile
+	}
+	assert.Equal(t, "Mari", profiles["maria"].DisplayName)
+	// joao profile is private by default
+	assert.Equal(t, "", profiles["joao"].DisplayName)
+	assert.NotContains(t, w.Body.String(), "maria@robolucha.com")
+
+	w = test.PerformRequest(router, "GET", url, "", other)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/classroom/999/classmates", "", maria)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
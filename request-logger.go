@@ -4,31 +4,66 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
+// only the beginning of large bodies is logged, as the base64 images of the media
+const maxLoggedBody = 4 * 1024
+
 // From
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		buf, _ := ioutil.ReadAll(c.Request.Body)
-		rdr1 := ioutil.NopCloser(bytes.NewBuffer(buf))
-		rdr2 := ioutil.NopCloser(bytes.NewBuffer(buf)) //We have to create a new Buffer, because rdr1 will be read.
+		if c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		// binary uploads are not read in memory to be logged
+		if !loggableContentType(c.ContentType()) {
+			log.WithFields(log.Fields{
+				"contentType":   c.ContentType(),
+				"contentLength": c.Request.ContentLength,
+			}).Debug("request body not logged")
+
+			c.Next()
+			return
+		}
+
+		buf, _ := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBody+1))
+		body := string(buf)
+		if len(buf) > maxLoggedBody {
+			body = string(buf[:maxLoggedBody]) + "..."
+		}
 
 		log.WithFields(log.Fields{
-			"body": readBody(rdr1),
+			"body": body,
 		}).Debug("request body")
 
-		c.Request.Body = rdr2
+		// the logged bytes are read again before the rest of the body
+		c.Request.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(buf), c.Request.Body),
+			Closer: c.Request.Body,
+		}
 		c.Next()
 	}
 }
 
-func readBody(reader io.Reader) string {
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(reader)
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
 
-	s := buf.String()
-	return s
+func loggableContentType(contentType string) bool {
+	switch {
+	case contentType == "",
+		contentType == "application/x-www-form-urlencoded",
+		strings.HasPrefix(contentType, "text/"),
+		strings.HasSuffix(contentType, "json"),
+		strings.HasSuffix(contentType, "xml"):
+		return true
+	}
+	return false
 }
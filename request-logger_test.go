@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLoggerKeepsBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logger := gin.New()
+	logger.Use(RequestLogger())
+
+	var received string
+	logger.POST("/echo", func(c *gin.Context) {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		received = string(body)
+		c.Status(http.StatusOK)
+	})
+
+	for _, contentType := range []string{"application/json", "multipart/form-data; boundary=x", "image/png"} {
+		body := strings.Repeat("a", maxLoggedBody*3)
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		logger.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, body, received, contentType)
+	}
+}
+
+func TestLoggableContentType(t *testing.T) {
+	assert.True(t, loggableContentType(""))
+	assert.True(t, loggableContentType("application/json"))
+	assert.True(t, loggableContentType("text/plain"))
+	assert.False(t, loggableContentType("multipart/form-data"))
+	assert.False(t, loggableContentType("application/octet-stream"))
+	assert.False(t, loggableContentType("image/png"))
+}
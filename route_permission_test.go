@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+const dashboardUserRole = "dashboard_user"
+
+// uses the Authorization header as the comma separated list of roles
+func sessionWithRoles(ds *datasource.DataSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles := make([]string, 0)
+		for _, role := range strings.Split(c.GetHeader("Authorization"), ",") {
+			if role != "" {
+				roles = append(roles, role)
+			}
+		}
+
+		user := ds.CreateUser("test")
+		level := ds.FindUserLevelByUserID(user.ID)
+		c.Set("userDetails", model.UserDetails{
+			User:        user,
+			Roles:       roles,
+			Permissions: auth.PermissionsForRoles(roles),
+			Level:       *level,
+		})
+	}
+}
+
+type routePermission struct {
+	method string
+	path   string
+	body   string
+	role   string
+}
+
+func TestRoutePermissions(t *testing.T) {
+	SetupClassroom(t)
+	defer ds.DB.Close()
+	router = createRouter(test.API_KEY, "true", sessionWithRoles, sessionWithRoles)
+
+	routes := []routePermission{
+		{"GET", "/dashboard/get-user", "", dashboardUserRole},
+		{"GET", "/dashboard/classroom", "", dashboardUserRole},
+		{"POST", "/dashboard/classroom", `{"name":"A"}`, dashboardUserRole},
+		{"GET", "/dashboard/classroom/report/1", "", dashboardUserRole},
+		{"GET", "/dashboard/student/1/mastery", "", dashboardUserRole},
+		{"GET", "/dashboard/assignment", "", dashboardUserRole},
+		{"POST", "/dashboard/skill", `{"name":"loops"}`, dashboardUserRole},
+		{"POST", "/private/classroom", `{"name":"B"}`, dashboardUserRole},
+	}
+
+	for _, route := range routes {
+		w := test.PerformRequest(router, route.method, route.path, route.body, "")
+		assert.Equal(t, http.StatusForbidden, w.Code, route.method+" "+route.path)
+
+		w = test.PerformRequest(router, route.method, route.path, route.body, route.role)
+		assert.NotEqual(t, http.StatusForbidden, w.Code, route.method+" "+route.path)
+	}
+
+	// system editors can access the dashboard but dont manage classrooms
+	w := test.PerformRequest(router, "POST", "/dashboard/classroom", `{"name":"C"}`, auth.SystemEditorRole)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/skill", "", auth.SystemEditorRole)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// only system editors review the moderation queue
+	w = test.PerformRequest(router, "GET", "/dashboard/moderation/flag", "", dashboardUserRole)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/moderation/flag", "", auth.SystemEditorRole)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/moderation/report", "", dashboardUserRole)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/dashboard/moderation/report", "", auth.SystemEditorRole)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// routes without permission are available to any user
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
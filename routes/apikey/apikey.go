@@ -0,0 +1,293 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/pubsub"
+)
+
+// time the previous key is still accepted after a rotation when graceMinutes is not informed
+const defaultRotationGrace = time.Hour
+const maxRotationGrace = 7 * 24 * time.Hour
+
+var (
+	errNotFound   = errors.New("api key DOES NOT exist")
+	errNameExists = errors.New("api key already EXISTS with this name")
+	errRevoked    = errors.New("api key is revoked")
+	errInvalid    = errors.New("invalid api key")
+)
+
+// Init receive database and message queue objects
+func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
+	requestHandler = NewRequestHandler(_ds, _publisher)
+
+	return &Router{ds: _ds,
+		publisher: _publisher,
+	}
+}
+
+// RequestHandler definition
+type RequestHandler struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// NewRequestHandler creates a new request handler
+func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher) *RequestHandler {
+	handler := RequestHandler{
+		ds:        _ds,
+		publisher: _publisher,
+	}
+
+	return &handler
+}
+
+var requestHandler *RequestHandler
+
+// Router definition
+type Router struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// Setup definition
+func (router *Router) Setup(group *gin.RouterGroup) {
+	admin := auth.RequirePermission(auth.PermissionAPIKeyAdmin)
+
+	group.GET("/api-key", admin, getAPIKeys)
+	group.POST("/api-key", admin, addAPIKey)
+	group.POST("/api-key/:id/rotate", admin, rotateAPIKey)
+	group.DELETE("/api-key/:id", admin, revokeAPIKey)
+}
+
+// maps the api key errors to the response status
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNameExists), errors.Is(err, errRevoked):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func abortWithError(c *gin.Context, err error, context string) {
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Info("Invalid request on " + context)
+	c.AbortWithStatusJSON(statusFromError(err), err.Error())
+}
+
+// getAPIKeys godoc
+// @Summary find all api keys, the keys are never returned only its prefix
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.APIKey
+// @Security ApiKeyAuth
+// @Router /dashboard/api-key [get]
+func getAPIKeys(c *gin.Context) {
+	result := requestHandler.ds.FindAllAPIKeys()
+	c.JSON(http.StatusOK, result)
+}
+
+// addAPIKey godoc
+// @Summary issue an api key for an internal service, the key is only returned in this response
+// @Accept json
+// @Produce json
+// @Param request body model.APIKeyRequest true "APIKeyRequest"
+// @Success 200 {object} model.APIKeyResponse
+// @Security ApiKeyAuth
+// @Router /dashboard/api-key [post]
+func addAPIKey(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var request model.APIKeyRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on addAPIKey")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result, err := requestHandler.Issue(user.User.ID, request)
+	if err != nil {
+		abortWithError(c, err, "addAPIKey")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// rotateAPIKey godoc
+// @Summary replace the api key, the previous key is accepted during graceMinutes (default 60)
+// @Accept json
+// @Produce json
+// @Param id path int true "APIKey id"
+// @Param graceMinutes query int false "minutes the previous key is still valid"
+// @Success 200 {object} model.APIKeyResponse
+// @Security ApiKeyAuth
+// @Router /dashboard/api-key/{id}/rotate [post]
+func rotateAPIKey(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "rotateAPIKey")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	grace := defaultRotationGrace
+	if value := c.Query("graceMinutes"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 || time.Duration(minutes)*time.Minute > maxRotationGrace {
+			log.WithFields(log.Fields{
+				"graceMinutes": value,
+			}).Info("Invalid graceMinutes on rotateAPIKey")
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		grace = time.Duration(minutes) * time.Minute
+	}
+
+	result, err := requestHandler.Rotate(id, grace)
+	if err != nil {
+		abortWithError(c, err, "rotateAPIKey")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// revokeAPIKey godoc
+// @Summary revoke the api key, requests using it are rejected immediately
+// @Accept json
+// @Produce json
+// @Param id path int true "APIKey id"
+// @Success 200 {object} model.APIKey
+// @Security ApiKeyAuth
+// @Router /dashboard/api-key/{id} [delete]
+func revokeAPIKey(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "revokeAPIKey")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result, err := requestHandler.Revoke(id)
+	if err != nil {
+		abortWithError(c, err, "revokeAPIKey")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Issue definition
+func (handler *RequestHandler) Issue(userID uint, request model.APIKeyRequest) (*model.APIKeyResponse, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	scopes, err := validateScopes(request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiresAt should be in the future", errInvalid)
+	}
+
+	if handler.ds.FindAPIKeyByName(name) != nil {
+		return nil, errNameExists
+	}
+
+	raw, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := handler.ds.CreateAPIKey(&model.APIKey{
+		Name:            name,
+		Prefix:          prefix,
+		KeyHash:         hash,
+		Scopes:          strings.Join(scopes, ","),
+		ExpiresAt:       request.ExpiresAt,
+		CreatedByUserID: userID,
+	})
+
+	return &model.APIKeyResponse{APIKey: key, Key: raw}, nil
+}
+
+// Rotate definition
+func (handler *RequestHandler) Rotate(id uint, grace time.Duration) (*model.APIKeyResponse, error) {
+	key := handler.ds.FindAPIKey(id)
+	if key == nil {
+		return nil, errNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil, errRevoked
+	}
+
+	raw, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key = handler.ds.RotateAPIKey(key, hash, prefix, grace)
+	return &model.APIKeyResponse{APIKey: key, Key: raw}, nil
+}
+
+// Revoke definition
+func (handler *RequestHandler) Revoke(id uint) (*model.APIKey, error) {
+	key := handler.ds.FindAPIKey(id)
+	if key == nil {
+		return nil, errNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil, errRevoked
+	}
+
+	return handler.ds.RevokeAPIKey(key), nil
+}
+
+// scopes must be known and are returned without duplicates
+func validateScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0)
+	found := make(map[string]bool)
+
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !validScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope '%v'", errInvalid, scope)
+		}
+		if !found[scope] {
+			found[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", errInvalid)
+	}
+
+	return result, nil
+}
+
+func validScope(scope string) bool {
+	for _, value := range model.APIKeyScopes {
+		if value == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,697 @@
+package learning
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
+)
+
+var (
+	errNotFound   = errors.New("record DOES NOT exist")
+	errNotOwner   = errors.New("current user DOES NOT OWN this record")
+	errNameExists = errors.New("record already EXISTS with this name")
+	errInUse      = errors.New("record is in use")
+	errInvalid    = errors.New("invalid record")
+)
+
+// maps the catalogue errors to the response status
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, errNameExists), errors.Is(err, errInUse):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func abortWithError(c *gin.Context, err error, context string) {
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Info("Invalid request on " + context)
+	c.AbortWithStatusJSON(statusFromError(err), err.Error())
+}
+
+// checks the free text fields, flagged fields are added to the moderation review queue
+func checkText(c *gin.Context, userID uint, contentType string, id uint, fields ...moderation.Field) error {
+	flags := moderation.Inspect(requestHandler.ds, moderation.Content{
+		Type:   contentType,
+		ID:     id,
+		UserID: userID,
+		Locale: moderation.Locale(requestHandler.ds, c, httphelper.UserFromContext(c)),
+		Fields: fields,
+	})
+	if len(flags) > 0 {
+		return fmt.Errorf("%w: %v contains inappropriate language", errInvalid, flags[0].Field)
+	}
+	return nil
+}
+
+// getSkills godoc
+// @Summary find all skills
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.Skill
+// @Security ApiKeyAuth
+// @Router /dashboard/skill [get]
+func getSkills(c *gin.Context) {
+	result := requestHandler.ds.FindAllSkills()
+	c.JSON(http.StatusOK, result)
+}
+
+// addSkill godoc
+// @Summary add a skill owned by the current user
+// @Accept json
+// @Produce json
+// @Param request body model.Skill true "Skill"
+// @Success 200 {object} model.Skill
+// @Security ApiKeyAuth
+// @Router /dashboard/skill [post]
+func addSkill(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var skill *model.Skill
+	err := c.BindJSON(&skill)
+	if err != nil || skill == nil {
+		log.Info("Invalid body content on addSkill")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_SKILL, 0,
+		moderation.Field{Name: "name", Text: skill.Name},
+		moderation.Field{Name: "description", Text: skill.Description})
+	if err != nil {
+		abortWithError(c, err, "addSkill")
+		return
+	}
+
+	result, err := requestHandler.AddSkill(user.User.ID, skill)
+	if err != nil {
+		abortWithError(c, err, "addSkill")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// updateSkill godoc
+// @Summary update a skill
+// @Accept json
+// @Produce json
+// @Param id path int true "Skill id"
+// @Param request body model.Skill true "Skill"
+// @Success 200 {object} model.Skill
+// @Security ApiKeyAuth
+// @Router /dashboard/skill/{id} [put]
+func updateSkill(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var skill *model.Skill
+	id, err := httphelper.GetIntegerParam(c, "id", "updateSkill")
+	if err == nil {
+		err = c.BindJSON(&skill)
+	}
+	if err != nil || skill == nil {
+		log.Info("Invalid body content on updateSkill")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	skill.ID = id
+
+	// dont check ownership when user can edit any record
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_SKILL, skill.ID,
+		moderation.Field{Name: "name", Text: skill.Name},
+		moderation.Field{Name: "description", Text: skill.Description})
+	if err != nil {
+		abortWithError(c, err, "updateSkill")
+		return
+	}
+
+	result, err := requestHandler.UpdateSkill(user.User.ID, skill, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "updateSkill")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteSkill godoc
+// @Summary delete a skill not used by learning objectives or activities
+// @Accept json
+// @Produce json
+// @Param id path int true "Skill id"
+// @Success 200 {integer} int
+// @Security ApiKeyAuth
+// @Router /dashboard/skill/{id} [delete]
+func deleteSkill(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "deleteSkill")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = requestHandler.DeleteSkill(user.User.ID, id, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "deleteSkill")
+		return
+	}
+
+	c.JSON(http.StatusOK, id)
+}
+
+// getLearningObjectives godoc
+// @Summary find all learning objectives with its skills
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.LearningObjective
+// @Security ApiKeyAuth
+// @Router /dashboard/learning-objective [get]
+func getLearningObjectives(c *gin.Context) {
+	result := requestHandler.ds.FindAllLearningObjectives()
+	c.JSON(http.StatusOK, result)
+}
+
+// getLearningObjective godoc
+// @Summary find a learning objective
+// @Accept json
+// @Produce json
+// @Param id path int true "LearningObjective id"
+// @Success 200 {object} model.LearningObjective
+// @Security ApiKeyAuth
+// @Router /dashboard/learning-objective/{id} [get]
+func getLearningObjective(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "getLearningObjective")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result := requestHandler.ds.FindLearningObjective(id)
+	if result == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// addLearningObjective godoc
+// @Summary add a learning objective owned by the current user, skills are linked by id
+// @Accept json
+// @Produce json
+// @Param request body model.LearningObjective true "LearningObjective"
+// @Success 200 {object} model.LearningObjective
+// @Security ApiKeyAuth
+// @Router /dashboard/learning-objective [post]
+func addLearningObjective(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var objective *model.LearningObjective
+	err := c.BindJSON(&objective)
+	if err != nil || objective == nil {
+		log.Info("Invalid body content on addLearningObjective")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_LEARNING_OBJECTIVE, 0,
+		moderation.Field{Name: "name", Text: objective.Name})
+	if err != nil {
+		abortWithError(c, err, "addLearningObjective")
+		return
+	}
+
+	result, err := requestHandler.AddLearningObjective(user.User.ID, objective)
+	if err != nil {
+		abortWithError(c, err, "addLearningObjective")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// updateLearningObjective godoc
+// @Summary update a learning objective, skills are linked by id
+// @Accept json
+// @Produce json
+// @Param id path int true "LearningObjective id"
+// @Param request body model.LearningObjective true "LearningObjective"
+// @Success 200 {object} model.LearningObjective
+// @Security ApiKeyAuth
+// @Router /dashboard/learning-objective/{id} [put]
+func updateLearningObjective(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var objective *model.LearningObjective
+	id, err := httphelper.GetIntegerParam(c, "id", "updateLearningObjective")
+	if err == nil {
+		err = c.BindJSON(&objective)
+	}
+	if err != nil || objective == nil {
+		log.Info("Invalid body content on updateLearningObjective")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	objective.ID = id
+
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_LEARNING_OBJECTIVE, objective.ID,
+		moderation.Field{Name: "name", Text: objective.Name})
+	if err != nil {
+		abortWithError(c, err, "updateLearningObjective")
+		return
+	}
+
+	result, err := requestHandler.UpdateLearningObjective(user.User.ID, objective, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "updateLearningObjective")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteLearningObjective godoc
+// @Summary delete a learning objective, its skills are kept
+// @Accept json
+// @Produce json
+// @Param id path int true "LearningObjective id"
+// @Success 200 {integer} int
+// @Security ApiKeyAuth
+// @Router /dashboard/learning-objective/{id} [delete]
+func deleteLearningObjective(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "deleteLearningObjective")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = requestHandler.DeleteLearningObjective(user.User.ID, id, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "deleteLearningObjective")
+		return
+	}
+
+	c.JSON(http.StatusOK, id)
+}
+
+// getActivityByID godoc
+// @Summary find an activity
+// @Accept json
+// @Produce json
+// @Param id path int true "Activity id"
+// @Success 200 {object} model.Activity
+// @Security ApiKeyAuth
+// @Router /dashboard/activity/{id} [get]
+func getActivityByID(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "getActivityByID")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result := requestHandler.ds.FindActivity(id)
+	if result == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// addActivity godoc
+// @Summary add an activity owned by the current user, skills are linked by id
+// @Accept json
+// @Produce json
+// @Param request body model.Activity true "Activity"
+// @Success 200 {object} model.Activity
+// @Security ApiKeyAuth
+// @Router /dashboard/activity [post]
+func addActivity(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var activity *model.Activity
+	err := c.BindJSON(&activity)
+	if err != nil || activity == nil {
+		log.Info("Invalid body content on addActivity")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_ACTIVITY, 0,
+		moderation.Field{Name: "name", Text: activity.Name},
+		moderation.Field{Name: "description", Text: activity.Description},
+		moderation.Field{Name: "sourceName", Text: activity.SourceName})
+	if err != nil {
+		abortWithError(c, err, "addActivity")
+		return
+	}
+
+	result, err := requestHandler.AddActivity(user.User.ID, activity)
+	if err != nil {
+		abortWithError(c, err, "addActivity")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// updateActivity godoc
+// @Summary update an activity, skills are linked by id
+// @Accept json
+// @Produce json
+// @Param id path int true "Activity id"
+// @Param request body model.Activity true "Activity"
+// @Success 200 {object} model.Activity
+// @Security ApiKeyAuth
+// @Router /dashboard/activity/{id} [put]
+func updateActivity(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	var activity *model.Activity
+	id, err := httphelper.GetIntegerParam(c, "id", "updateActivity")
+	if err == nil {
+		err = c.BindJSON(&activity)
+	}
+	if err != nil || activity == nil {
+		log.Info("Invalid body content on updateActivity")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	activity.ID = id
+
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_ACTIVITY, activity.ID,
+		moderation.Field{Name: "name", Text: activity.Name},
+		moderation.Field{Name: "description", Text: activity.Description},
+		moderation.Field{Name: "sourceName", Text: activity.SourceName})
+	if err != nil {
+		abortWithError(c, err, "updateActivity")
+		return
+	}
+
+	result, err := requestHandler.UpdateActivity(user.User.ID, activity, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "updateActivity")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteActivity godoc
+// @Summary delete an activity not used by assignments
+// @Accept json
+// @Produce json
+// @Param id path int true "Activity id"
+// @Success 200 {integer} int
+// @Security ApiKeyAuth
+// @Router /dashboard/activity/{id} [delete]
+func deleteActivity(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "deleteActivity")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionLearningEditAny)
+
+	err = requestHandler.DeleteActivity(user.User.ID, id, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "deleteActivity")
+		return
+	}
+
+	c.JSON(http.StatusOK, id)
+}
+
+// records loaded from metadata files have no owner and can only be changed with learning.edit.any
+func checkOwnerShip(ownerUserID uint, userID uint, skipCheckOwnerShip bool) error {
+	if !skipCheckOwnerShip && ownerUserID != userID {
+		log.WithFields(log.Fields{
+			"ownerUserID": ownerUserID,
+			"userID":      userID,
+		}).Info("current user dont OWNS this record, cant be changed")
+		return errNotOwner
+	}
+	return nil
+}
+
+// loads the skills by id, unknown skills are rejected
+func (handler *RequestHandler) findSkills(skills []model.Skill) ([]model.Skill, error) {
+	result := make([]model.Skill, 0)
+	for _, skill := range skills {
+		found := handler.ds.FindSkill(skill.ID)
+		if found == nil {
+			return nil, fmt.Errorf("%w: skill %v DOES NOT exist", errInvalid, skill.ID)
+		}
+		result = append(result, *found)
+	}
+	return result, nil
+}
+
+// AddSkill godoc
+func (handler *RequestHandler) AddSkill(userID uint, skill *model.Skill) (*model.Skill, error) {
+	skill.Name = strings.TrimSpace(skill.Name)
+	if skill.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	if handler.ds.FindSkillByName(skill.Name) != nil {
+		return nil, errNameExists
+	}
+
+	skill.OwnerUserID = userID
+	result := handler.ds.AddSkill(skill)
+	if result == nil {
+		return nil, errNameExists
+	}
+	return result, nil
+}
+
+// UpdateSkill godoc
+func (handler *RequestHandler) UpdateSkill(userID uint, skill *model.Skill, skipCheckOwnerShip bool) (*model.Skill, error) {
+	found := handler.ds.FindSkill(skill.ID)
+	if found == nil {
+		return nil, errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return nil, err
+	}
+
+	skill.Name = strings.TrimSpace(skill.Name)
+	if skill.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	foundByName := handler.ds.FindSkillByName(skill.Name)
+	if foundByName != nil && foundByName.ID != skill.ID {
+		return nil, errNameExists
+	}
+
+	return handler.ds.UpdateSkill(skill), nil
+}
+
+// DeleteSkill godoc
+func (handler *RequestHandler) DeleteSkill(userID uint, id uint, skipCheckOwnerShip bool) error {
+	found := handler.ds.FindSkill(id)
+	if found == nil {
+		return errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return err
+	}
+
+	if handler.ds.SkillInUse(id) {
+		return errInUse
+	}
+
+	handler.ds.DeleteSkill(id)
+	return nil
+}
+
+// AddLearningObjective godoc
+func (handler *RequestHandler) AddLearningObjective(userID uint, objective *model.LearningObjective) (*model.LearningObjective, error) {
+	objective.Name = strings.TrimSpace(objective.Name)
+	if objective.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	if handler.ds.FindLearningObjectiveByName(objective.Name) != nil {
+		return nil, errNameExists
+	}
+
+	skills, err := handler.findSkills(objective.Skills)
+	if err != nil {
+		return nil, err
+	}
+
+	objective.Skills = skills
+	objective.OwnerUserID = userID
+	result := handler.ds.AddLearningObjective(objective)
+	if result == nil {
+		return nil, errNameExists
+	}
+	return result, nil
+}
+
+// UpdateLearningObjective godoc
+func (handler *RequestHandler) UpdateLearningObjective(userID uint, objective *model.LearningObjective, skipCheckOwnerShip bool) (*model.LearningObjective, error) {
+	found := handler.ds.FindLearningObjective(objective.ID)
+	if found == nil {
+		return nil, errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return nil, err
+	}
+
+	objective.Name = strings.TrimSpace(objective.Name)
+	if objective.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	foundByName := handler.ds.FindLearningObjectiveByName(objective.Name)
+	if foundByName != nil && foundByName.ID != objective.ID {
+		return nil, errNameExists
+	}
+
+	skills, err := handler.findSkills(objective.Skills)
+	if err != nil {
+		return nil, err
+	}
+
+	objective.Skills = skills
+	return handler.ds.UpdateLearningObjective(objective), nil
+}
+
+// DeleteLearningObjective godoc
+func (handler *RequestHandler) DeleteLearningObjective(userID uint, id uint, skipCheckOwnerShip bool) error {
+	found := handler.ds.FindLearningObjective(id)
+	if found == nil {
+		return errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return err
+	}
+
+	handler.ds.DeleteLearningObjective(id)
+	return nil
+}
+
+// validates the activity fields and replace the skills by the stored ones
+func (handler *RequestHandler) validateActivity(activity *model.Activity) error {
+	activity.Name = strings.TrimSpace(activity.Name)
+	if activity.Name == "" {
+		return fmt.Errorf("%w: name is required", errInvalid)
+	}
+
+	if handler.ds.FindGameDefinition(activity.GameDefinitionID) == nil {
+		return fmt.Errorf("%w: gamedefinition %v DOES NOT exist", errInvalid, activity.GameDefinitionID)
+	}
+
+	activity.SourceURL = strings.TrimSpace(activity.SourceURL)
+	if activity.SourceURL != "" {
+		source, err := url.ParseRequestURI(activity.SourceURL)
+		if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+			return fmt.Errorf("%w: sourceURL should be a http or https URL", errInvalid)
+		}
+	}
+
+	skills, err := handler.findSkills(activity.Skills)
+	if err != nil {
+		return err
+	}
+
+	activity.Skills = skills
+	return nil
+}
+
+// AddActivity godoc
+func (handler *RequestHandler) AddActivity(userID uint, activity *model.Activity) (*model.Activity, error) {
+	err := handler.validateActivity(activity)
+	if err != nil {
+		return nil, err
+	}
+
+	activity.OwnerUserID = userID
+	result := handler.ds.AddActivity(activity)
+	if result == nil {
+		return nil, fmt.Errorf("%w: activity not saved", errInvalid)
+	}
+	return result, nil
+}
+
+// UpdateActivity godoc
+func (handler *RequestHandler) UpdateActivity(userID uint, activity *model.Activity, skipCheckOwnerShip bool) (*model.Activity, error) {
+	found := handler.ds.FindActivity(activity.ID)
+	if found == nil {
+		return nil, errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.validateActivity(activity)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.ds.UpdateActivity(activity), nil
+}
+
+// DeleteActivity godoc
+func (handler *RequestHandler) DeleteActivity(userID uint, id uint, skipCheckOwnerShip bool) error {
+	found := handler.ds.FindActivity(id)
+	if found == nil {
+		return errNotFound
+	}
+
+	err := checkOwnerShip(found.OwnerUserID, userID, skipCheckOwnerShip)
+	if err != nil {
+		return err
+	}
+
+	if handler.ds.ActivityInUse(id) {
+		return errInUse
+	}
+
+	handler.ds.DeleteActivity(id)
+	return nil
+}
@@ -0,0 +1,123 @@
+package learning
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+var ds *datasource.DataSource
+var handler *RequestHandler
+
+func Setup(t *testing.T) {
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetOutput(os.Stdout)
+	log.SetLevel(log.InfoLevel)
+
+	os.Remove(test.DB_NAME)
+	ds = datasource.NewDataSource(datasource.BuildSQLLiteConfig(test.DB_NAME))
+	handler = NewRequestHandler(ds, &test.MockPublisher{})
+}
+
+func TestSkillCatalogue(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	skill, err := handler.AddSkill(1, &model.Skill{Name: " Loops ", Description: "for and while"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Loops", skill.Name)
+	assert.Equal(t, uint(1), skill.OwnerUserID)
+
+	_, err = handler.AddSkill(2, &model.Skill{Name: "Loops"})
+	assert.True(t, errors.Is(err, errNameExists))
+
+	_, err = handler.UpdateSkill(2, &model.Skill{ID: skill.ID, Name: "Repetition"}, false)
+	assert.True(t, errors.Is(err, errNotOwner))
+
+	updated, err := handler.UpdateSkill(2, &model.Skill{ID: skill.ID, Name: "Repetition"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "Repetition", updated.Name)
+
+	objective, err := handler.AddLearningObjective(1, &model.LearningObjective{
+		Name:   "Coding",
+		Skills: []model.Skill{{ID: skill.ID}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(objective.Skills))
+
+	err = handler.DeleteSkill(1, skill.ID, false)
+	assert.True(t, errors.Is(err, errInUse))
+
+	err = handler.DeleteLearningObjective(1, objective.ID, false)
+	assert.Nil(t, err)
+	assert.Nil(t, ds.FindLearningObjective(objective.ID))
+
+	err = handler.DeleteSkill(1, skill.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(*ds.FindAllSkills()))
+
+	// the names of the deleted records are kept by the unique index
+	_, err = handler.AddSkill(1, &model.Skill{Name: "Repetition"})
+	assert.True(t, errors.Is(err, errNameExists))
+
+	_, err = handler.AddLearningObjective(1, &model.LearningObjective{Name: "Coding"})
+	assert.True(t, errors.Is(err, errNameExists))
+}
+
+func TestActivityCatalogue(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "TestActivityCatalogue"
+	created := ds.CreateGameDefinition(&gd)
+
+	skill, _ := handler.AddSkill(1, &model.Skill{Name: "Conditionals"})
+
+	_, err := handler.AddActivity(1, &model.Activity{Name: "Dodge", GameDefinitionID: created.ID + 1})
+	assert.True(t, errors.Is(err, errInvalid))
+
+	_, err = handler.AddActivity(1, &model.Activity{Name: "Dodge", GameDefinitionID: created.ID, SourceURL: "ftp://example.com"})
+	assert.True(t, errors.Is(err, errInvalid))
+
+	_, err = handler.AddActivity(1, &model.Activity{Name: "Dodge", GameDefinitionID: created.ID, Skills: []model.Skill{{ID: 99}}})
+	assert.True(t, errors.Is(err, errInvalid))
+
+	activity, err := handler.AddActivity(1, &model.Activity{
+		Name:             "Dodge",
+		GameDefinitionID: created.ID,
+		SourceURL:        "https://robolucha.com/activity/dodge",
+		Skills:           []model.Skill{{ID: skill.ID}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), activity.OwnerUserID)
+	assert.Equal(t, "Conditionals", activity.Skills[0].Name)
+
+	activities := *ds.FindAllActivities()
+	assert.Equal(t, 1, len(activities))
+	assert.Equal(t, 1, len(activities[0].Skills))
+
+	updated, err := handler.UpdateActivity(1, &model.Activity{ID: activity.ID, Name: "Dodge bullets", GameDefinitionID: created.ID}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Dodge bullets", updated.Name)
+	assert.Equal(t, 0, len(updated.Skills))
+
+	err = handler.DeleteActivity(2, activity.ID, false)
+	assert.True(t, errors.Is(err, errNotOwner))
+
+	assignment := ds.AddAssignment(&model.Assignment{})
+	ds.UpdateAssignmentActivities(assignment.ID, []uint{activity.ID})
+	err = handler.DeleteActivity(1, activity.ID, false)
+	assert.True(t, errors.Is(err, errInUse))
+
+	ds.DeleteAssignment(assignment.ID)
+	err = handler.DeleteActivity(1, activity.ID, false)
+	assert.Nil(t, err)
+	assert.Nil(t, ds.FindActivity(activity.ID))
+}
@@ -6,8 +6,10 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gitlab.com/robolucha/robolucha-api/httphelper"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 
 	"github.com/gin-gonic/gin"
+	"gitlab.com/robolucha/robolucha-api/auth"
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/pubsub"
 )
@@ -47,13 +49,29 @@ type Router struct {
 
 // Setup definition
 func (router *Router) Setup(group *gin.RouterGroup) {
+	learningEdit := auth.RequirePermission(auth.PermissionLearningEdit)
+	assignmentGrade := auth.RequirePermission(auth.PermissionAssignmentGrade)
+
 	group.GET("/activity", getActivity)
-	group.GET("/assignment", getAssignments)
-	group.GET("/assignment/:id", getAssignment)
-	group.POST("/assignment", addAssignment)
-	group.DELETE("/assignment/:id", delAssignment)
-	group.PATCH("/assignment/:id/students", updateAssignmentStudents)
-	group.PATCH("/assignment/:id/activities", updateAssignmentActivities)
+	group.GET("/activity/:id", getActivityByID)
+	group.POST("/activity", learningEdit, addActivity)
+	group.PUT("/activity/:id", learningEdit, updateActivity)
+	group.DELETE("/activity/:id", learningEdit, deleteActivity)
+	group.GET("/skill", getSkills)
+	group.POST("/skill", learningEdit, addSkill)
+	group.PUT("/skill/:id", learningEdit, updateSkill)
+	group.DELETE("/skill/:id", learningEdit, deleteSkill)
+	group.GET("/learning-objective", getLearningObjectives)
+	group.GET("/learning-objective/:id", getLearningObjective)
+	group.POST("/learning-objective", learningEdit, addLearningObjective)
+	group.PUT("/learning-objective/:id", learningEdit, updateLearningObjective)
+	group.DELETE("/learning-objective/:id", learningEdit, deleteLearningObjective)
+	group.GET("/assignment", assignmentGrade, getAssignments)
+	group.GET("/assignment/:id", assignmentGrade, getAssignment)
+	group.POST("/assignment", assignmentGrade, addAssignment)
+	group.DELETE("/assignment/:id", assignmentGrade, delAssignment)
+	group.PATCH("/assignment/:id/students", assignmentGrade, updateAssignmentStudents)
+	group.PATCH("/assignment/:id/activities", assignmentGrade, updateAssignmentActivities)
 }
 
 // updateAssignmentActivities godoc
@@ -135,15 +153,32 @@ func getAssignment(c *gin.Context) {
 }
 
 // addAssignment godoc
-// @Summary find existing activities
+// @Summary add an assignment, the name and description are checked by the moderation
 // @Accept json
 // @Produce json
-// @Success 200 {array} model.Activity
+// @Param request body model.Assignment true "Assignment"
+// @Success 200 {object} model.Assignment
 // @Security ApiKeyAuth
 // @Router /dashboard/assignments [post]
 func addAssignment(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
 	var assignment *model.Assignment
-	c.BindJSON(&assignment)
+	err := c.BindJSON(&assignment)
+	if err != nil || assignment == nil {
+		log.Info("Invalid body content on addAssignment")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = checkText(c, user.User.ID, model.MODERATION_CONTENT_ASSIGNMENT, 0,
+		moderation.Field{Name: "name", Text: assignment.Name},
+		moderation.Field{Name: "description", Text: assignment.Description})
+	if err != nil {
+		abortWithError(c, err, "addAssignment")
+		return
+	}
+
 	result := requestHandler.ds.AddAssignment(assignment)
 	c.JSON(http.StatusOK, result)
 }
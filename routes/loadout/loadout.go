@@ -0,0 +1,325 @@
+package loadout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/pubsub"
+)
+
+var (
+	errNotFound           = errors.New("luchador not found")
+	errGameDefinition     = errors.New("game definition not found")
+	errSameGameDefinition = errors.New("fromGameDefinitionID should be other game definition")
+	errDefaultReset       = errors.New("the default loadout doesn't have suggested codes")
+)
+
+// Init receive database and message queue objects
+func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
+	requestHandler = NewRequestHandler(_ds, _publisher)
+
+	return &Router{ds: _ds,
+		publisher: _publisher,
+	}
+}
+
+// RequestHandler definition
+type RequestHandler struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// NewRequestHandler creates a new request handler
+func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher) *RequestHandler {
+	handler := RequestHandler{
+		ds:        _ds,
+		publisher: _publisher,
+	}
+
+	return &handler
+}
+
+var requestHandler *RequestHandler
+
+// Router definition
+type Router struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// Setup definition
+func (router *Router) Setup(group *gin.RouterGroup) {
+	group.GET("/luchadors/:id/loadout", getLoadouts)
+	group.GET("/luchadors/:id/loadout/:gameDefinitionID", getLoadout)
+	group.POST("/luchadors/:id/loadout/:gameDefinitionID/copy", copyLoadout)
+	group.POST("/luchadors/:id/loadout/:gameDefinitionID/reset", resetLoadout)
+}
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, errNotFound), errors.Is(err, errGameDefinition):
+		return http.StatusNotFound
+	case errors.Is(err, errSameGameDefinition), errors.Is(err, errDefaultReset):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func abortWithError(c *gin.Context, err error, context string) {
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Info("Invalid request on " + context)
+	c.AbortWithStatusJSON(statusFromError(err), err.Error())
+}
+
+// getLoadouts godoc
+// @Summary find the loadouts of the luchador, the default loadout and the game definitions with codes
+// @Accept json
+// @Produce json
+// @Param id path int true "Luchador ID"
+// @Success 200 {array} model.Loadout
+// @Security ApiKeyAuth
+// @Router /private/luchadors/{id}/loadout [get]
+func getLoadouts(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "getLoadouts")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	user := httphelper.UserFromContext(c)
+	result, err := requestHandler.FindAll(user.ID, id)
+	if err != nil {
+		abortWithError(c, err, "getLoadouts")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getLoadout godoc
+// @Summary find the codes of the luchador used in the game definition
+// @Accept json
+// @Produce json
+// @Param id path int true "Luchador ID"
+// @Param gameDefinitionID path int true "GameDefinition ID, 0 for the default loadout"
+// @Success 200 {object} model.Loadout
+// @Security ApiKeyAuth
+// @Router /private/luchadors/{id}/loadout/{gameDefinitionID} [get]
+func getLoadout(c *gin.Context) {
+	id, gameDefinitionID, ok := loadoutParams(c, "getLoadout")
+	if !ok {
+		return
+	}
+
+	user := httphelper.UserFromContext(c)
+	result, err := requestHandler.Find(user.ID, id, gameDefinitionID)
+	if err != nil {
+		abortWithError(c, err, "getLoadout")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// copyLoadout godoc
+// @Summary copy the codes used in other game definition to the loadout of the game definition
+// @Accept json
+// @Produce json
+// @Param id path int true "Luchador ID"
+// @Param gameDefinitionID path int true "GameDefinition ID, 0 for the default loadout"
+// @Param request body model.LoadoutCopyRequest true "LoadoutCopyRequest"
+// @Success 200 {object} model.Loadout
+// @Security ApiKeyAuth
+// @Router /private/luchadors/{id}/loadout/{gameDefinitionID}/copy [post]
+func copyLoadout(c *gin.Context) {
+	id, gameDefinitionID, ok := loadoutParams(c, "copyLoadout")
+	if !ok {
+		return
+	}
+
+	var request model.LoadoutCopyRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on copyLoadout")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	user := httphelper.UserFromContext(c)
+	result, err := requestHandler.Copy(user.ID, id, request.FromGameDefinitionID, gameDefinitionID)
+	if err != nil {
+		abortWithError(c, err, "copyLoadout")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// resetLoadout godoc
+// @Summary replaces the loadout of the game definition by the suggested codes of the game definition
+// @Accept json
+// @Produce json
+// @Param id path int true "Luchador ID"
+// @Param gameDefinitionID path int true "GameDefinition ID"
+// @Success 200 {object} model.Loadout
+// @Security ApiKeyAuth
+// @Router /private/luchadors/{id}/loadout/{gameDefinitionID}/reset [post]
+func resetLoadout(c *gin.Context) {
+	id, gameDefinitionID, ok := loadoutParams(c, "resetLoadout")
+	if !ok {
+		return
+	}
+
+	user := httphelper.UserFromContext(c)
+	result, err := requestHandler.Reset(user.ID, id, gameDefinitionID)
+	if err != nil {
+		abortWithError(c, err, "resetLoadout")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func loadoutParams(c *gin.Context, context string) (uint, uint, bool) {
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	gameDefinitionID, err := httphelper.GetIntegerParam(c, "gameDefinitionID", context)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return id, gameDefinitionID, true
+}
+
+// FindAll definition, the default loadout is the first, followed by the game definitions
+// with codes of the luchador
+func (handler *RequestHandler) FindAll(userID uint, luchadorID uint) ([]model.Loadout, error) {
+	luchador := handler.ds.FindUserLuchador(userID, luchadorID)
+	if luchador == nil {
+		return nil, errNotFound
+	}
+
+	ids := make([]uint, 0)
+	found := make(map[uint]bool)
+	for _, code := range luchador.Codes {
+		if code.GameDefinitionID != model.DefaultLoadoutID && !found[code.GameDefinitionID] {
+			found[code.GameDefinitionID] = true
+			ids = append(ids, code.GameDefinitionID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	names := handler.ds.FindGameDefinitionNames(ids)
+	result := []model.Loadout{buildLoadout(luchador, model.DefaultLoadoutID, "")}
+	for _, id := range ids {
+		result = append(result, buildLoadout(luchador, id, names[id]))
+	}
+
+	return result, nil
+}
+
+// Find definition, the codes of the luchador used in the game definition
+func (handler *RequestHandler) Find(userID uint, luchadorID uint, gameDefinitionID uint) (*model.Loadout, error) {
+	luchador := handler.ds.FindUserLuchador(userID, luchadorID)
+	if luchador == nil {
+		return nil, errNotFound
+	}
+
+	gameDefinition, err := handler.findGameDefinition(gameDefinitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	loadout := buildLoadout(luchador, gameDefinitionID, gameDefinition.Name)
+	return &loadout, nil
+}
+
+// Copy definition, the codes used in the game definition from are copied to the loadout of to
+func (handler *RequestHandler) Copy(userID uint, luchadorID uint, from uint, to uint) (*model.Loadout, error) {
+	if from == to {
+		return nil, errSameGameDefinition
+	}
+
+	luchador := handler.ds.FindUserLuchador(userID, luchadorID)
+	if luchador == nil {
+		return nil, errNotFound
+	}
+
+	_, err := handler.findGameDefinition(from)
+	if err != nil {
+		return nil, err
+	}
+	gameDefinition, err := handler.findGameDefinition(to)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := model.LoadoutCodes(luchador.Codes, from)
+	return handler.replace(luchador, gameDefinition, codes), nil
+}
+
+// Reset definition, the loadout of the game definition is replaced by its suggested codes
+func (handler *RequestHandler) Reset(userID uint, luchadorID uint, gameDefinitionID uint) (*model.Loadout, error) {
+	if gameDefinitionID == model.DefaultLoadoutID {
+		return nil, errDefaultReset
+	}
+
+	luchador := handler.ds.FindUserLuchador(userID, luchadorID)
+	if luchador == nil {
+		return nil, errNotFound
+	}
+
+	gameDefinition, err := handler.findGameDefinition(gameDefinitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.replace(luchador, gameDefinition, gameDefinition.LuchadorSuggestedCodes), nil
+}
+
+func (handler *RequestHandler) replace(luchador *model.GameComponent, gameDefinition *model.GameDefinition, codes []model.Code) *model.Loadout {
+	luchador = handler.ds.ReplaceLoadout(luchador, gameDefinition.ID, codes)
+
+	channel := fmt.Sprintf("luchador.%v.update", luchador.ID)
+	luchadorUpdateJSON, _ := json.Marshal(luchador)
+	handler.publisher.Publish(channel, string(luchadorUpdateJSON))
+
+	loadout := buildLoadout(luchador, gameDefinition.ID, gameDefinition.Name)
+	return &loadout
+}
+
+// the default loadout is an empty game definition
+func (handler *RequestHandler) findGameDefinition(id uint) (*model.GameDefinition, error) {
+	if id == model.DefaultLoadoutID {
+		return &model.GameDefinition{}, nil
+	}
+
+	gameDefinition := handler.ds.FindGameDefinition(id)
+	if gameDefinition == nil {
+		return nil, errGameDefinition
+	}
+	return gameDefinition, nil
+}
+
+func buildLoadout(luchador *model.GameComponent, gameDefinitionID uint, name string) model.Loadout {
+	return model.Loadout{
+		LuchadorID:         luchador.ID,
+		GameDefinitionID:   gameDefinitionID,
+		GameDefinitionName: name,
+		Codes:              model.LoadoutCodes(luchador.Codes, gameDefinitionID),
+	}
+}
@@ -2,6 +2,7 @@ package mapeditor
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
@@ -11,7 +12,9 @@ import (
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/httphelper"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 	"gitlab.com/robolucha/robolucha-api/pubsub"
+	"gitlab.com/robolucha/robolucha-api/script"
 )
 
 // Init receive database and message queue objects
@@ -66,7 +69,7 @@ func (router *Router) Setup(group *gin.RouterGroup) {
 func getMyGameDefinitions(c *gin.Context) {
 	user := httphelper.UserDetailsFromContext(c)
 
-	if auth.UserBelongsToRole(user, auth.SystemEditorRole) {
+	if auth.UserHasPermission(user, auth.PermissionMapEditAny) {
 		gameDefinitions := requestHandler.FindAll()
 		c.JSON(http.StatusOK, gameDefinitions)
 	} else {
@@ -103,12 +106,24 @@ func addMyGameDefinition(c *gin.Context) {
 	// parse body parameter
 	var gameDefinition *model.GameDefinition
 	err := c.BindJSON(&gameDefinition)
-	if err != nil {
+	if err != nil || gameDefinition == nil {
 		log.Info("Invalid body content on addMyGameDefinition")
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
+	if inappropriateText(c, user.User.ID, 0, gameDefinition) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, errInappropriate.Error())
+		return
+	}
+
+	messages := model.ProgressionRuleErrors(gameDefinition.ProgressionRules)
+	messages = append(messages, scriptErrors(gameDefinition, nil)...)
+	if len(messages) > 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, messages)
+		return
+	}
+
 	err = requestHandler.Add(user.User.ID, gameDefinition)
 	if err != nil {
 		c.AbortWithStatus(http.StatusConflict)
@@ -132,7 +147,7 @@ func updateMyGameDefinition(c *gin.Context) {
 	// parse body parameter
 	var gameDefinition *model.GameDefinition
 	err := c.BindJSON(&gameDefinition)
-	if err != nil {
+	if err != nil || gameDefinition == nil {
 		log.WithFields(log.Fields{
 			"err": err,
 		}).Info("Invalid body content on updateMyGameDefinition")
@@ -140,8 +155,25 @@ func updateMyGameDefinition(c *gin.Context) {
 		return
 	}
 
-	// dont check ownership when user is a system editor
-	skipCheckOwnerShip := auth.UserBelongsToRole(user, auth.SystemEditorRole)
+	if inappropriateText(c, user.User.ID, gameDefinition.ID, gameDefinition) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, errInappropriate.Error())
+		return
+	}
+
+	// only the scripts changed by the user are checked
+	var current *model.GameDefinition
+	if gameDefinition.ID != 0 {
+		current = requestHandler.ds.FindGameDefinition(gameDefinition.ID)
+	}
+	messages := model.ProgressionRuleErrors(gameDefinition.ProgressionRules)
+	messages = append(messages, scriptErrors(gameDefinition, current)...)
+	if len(messages) > 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	// dont check ownership when user can edit any map
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionMapEditAny)
 
 	err = requestHandler.Update(user.User.ID, gameDefinition, skipCheckOwnerShip)
 	if err != nil {
@@ -173,8 +205,8 @@ func updateClassroomMapAvailability(c *gin.Context) {
 		return
 	}
 
-	// dont check ownership when user is a system editor
-	skipCheckOwnerShip := auth.UserBelongsToRole(user, auth.SystemEditorRole)
+	// dont check ownership when user can edit any map
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionMapEditAny)
 
 	err = requestHandler.UpdateAvailability(user.User.ID, availability, skipCheckOwnerShip)
 	if err != nil {
@@ -184,6 +216,71 @@ func updateClassroomMapAvailability(c *gin.Context) {
 	}
 }
 
+var errInappropriate = errors.New("Gamedefinition contains inappropriate language")
+
+// checks the texts shown to the players, flagged texts are added to the moderation review queue
+func inappropriateText(c *gin.Context, userID uint, gameDefinitionID uint, gameDefinition *model.GameDefinition) bool {
+	fields := []moderation.Field{
+		{Name: "name", Text: gameDefinition.Name},
+		{Name: "label", Text: gameDefinition.Label},
+		{Name: "description", Text: gameDefinition.Description},
+	}
+
+	for i, narrative := range gameDefinition.NarrativeDefinitions {
+		fields = append(fields, moderation.Field{Name: fmt.Sprintf("narrativeDefinitions[%v].text", i), Text: narrative.Text})
+	}
+
+	for i, team := range gameDefinition.TeamDefinition.Teams {
+		fields = append(fields, moderation.Field{Name: fmt.Sprintf("teamDefinition.teams[%v].name", i), Text: team.Name})
+	}
+
+	flags := moderation.Inspect(requestHandler.ds, moderation.Content{
+		Type:   model.MODERATION_CONTENT_GAMEDEFINITION,
+		ID:     gameDefinitionID,
+		UserID: userID,
+		Locale: moderation.Locale(requestHandler.ds, c, httphelper.UserFromContext(c)),
+		Fields: fields,
+	})
+	return len(flags) > 0
+}
+
+// the scripts of the game definition, the components and the suggested codes, the messages
+// use the names of the fields as the moderation, the scripts stored in current are not checked
+func scriptErrors(gameDefinition *model.GameDefinition, current *model.GameDefinition) []string {
+	stored := make([]model.Code, 0)
+	if current != nil {
+		stored = gameDefinitionCodes(current)
+	}
+	check := func(label string, codes []model.Code) []string {
+		return script.Check(label, script.Changed(codes, stored))
+	}
+
+	result := check("codes", gameDefinition.Codes)
+	result = append(result, check("suggestedCodes", gameDefinition.LuchadorSuggestedCodes)...)
+
+	for i, component := range gameDefinition.GameComponents {
+		result = append(result, check(fmt.Sprintf("gameComponents[%v].codes", i), component.Codes)...)
+	}
+
+	for i, component := range gameDefinition.SceneComponents {
+		result = append(result, check(fmt.Sprintf("sceneComponents[%v].codes", i), component.Codes)...)
+	}
+
+	return result
+}
+
+func gameDefinitionCodes(gameDefinition *model.GameDefinition) []model.Code {
+	result := append([]model.Code{}, gameDefinition.Codes...)
+	result = append(result, gameDefinition.LuchadorSuggestedCodes...)
+	for _, component := range gameDefinition.GameComponents {
+		result = append(result, component.Codes...)
+	}
+	for _, component := range gameDefinition.SceneComponents {
+		result = append(result, component.Codes...)
+	}
+	return result
+}
+
 // Find godoc
 func (handler *RequestHandler) Find(userID uint) *[]model.GameDefinition {
 	return handler.ds.FindGameDefinitionByOwner(userID)
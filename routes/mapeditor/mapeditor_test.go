@@ -248,3 +248,25 @@ func TestUpdateGameComponentCode(t *testing.T) {
 	assert.Equal(t, "all", code.Event)
 	assert.Equal(t, "--updated", code.Script)
 }
+
+func TestScriptErrors(t *testing.T) {
+	gd := model.BuildDefaultGameDefinition()
+	gd.Codes = []model.Code{{Event: "onStart", Script: "addAmmo(10)"}}
+	gd.LuchadorSuggestedCodes = []model.Code{{Event: "onRepeat", Script: "os.exit()"}}
+	gd.GameComponents = []model.GameComponent{
+		{Name: "otto", Codes: []model.Code{{Event: "onRepeat", Script: "move(10"}}},
+	}
+
+	messages := scriptErrors(&gd, nil)
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "suggestedCodes onRepeat uses os at line 1, it is not available for the luchadors", messages[0])
+	assert.Contains(t, messages[1], "gameComponents[0].codes onRepeat has a syntax error")
+
+	// the scripts already stored are not checked again
+	stored := model.GameDefinition{LuchadorSuggestedCodes: gd.LuchadorSuggestedCodes}
+	assert.Equal(t, messages[1:], scriptErrors(&gd, &stored))
+
+	gd.LuchadorSuggestedCodes = []model.Code{{Event: "onRepeat", Script: "move(10)"}}
+	gd.GameComponents[0].Codes[0].Script = "move(10)"
+	assert.Empty(t, scriptErrors(&gd, nil))
+}
@@ -0,0 +1,135 @@
+package maskimage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/mask"
+	"gitlab.com/robolucha/robolucha-api/pubsub"
+)
+
+// the image of a hash doesn't change, the luchador configs can
+const cacheControl = "public, max-age=3600"
+
+var errNotFound = errors.New("luchador not found")
+
+// Init receive database and message queue objects
+func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
+	requestHandler = NewRequestHandler(_ds, _publisher)
+
+	return &Router{ds: _ds,
+		publisher: _publisher,
+	}
+}
+
+// RequestHandler definition
+type RequestHandler struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+	renderer  *mask.Renderer
+}
+
+// NewRequestHandler creates a new request handler
+func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher) *RequestHandler {
+	handler := RequestHandler{
+		ds:        _ds,
+		publisher: _publisher,
+	}
+
+	return &handler
+}
+
+var requestHandler *RequestHandler
+
+// WithRenderer sets the renderer of the mask images
+func (router *Router) WithRenderer(renderer *mask.Renderer) *Router {
+	requestHandler.renderer = renderer
+	return router
+}
+
+// Router definition
+type Router struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// Setup definition
+func (router *Router) Setup(group *gin.RouterGroup) {
+	group.GET("/luchador/:id/mask.png", getMaskPNG)
+	group.GET("/luchador/:id/mask.svg", getMaskSVG)
+}
+
+// getMaskPNG godoc
+// @Summary luchador mask rendered from the configs as png
+// @Produce png
+// @Param id path int true "Luchador ID"
+// @Success 200 {file} file
+// @Router /public/luchador/{id}/mask.png [get]
+func getMaskPNG(c *gin.Context) {
+	renderMask(c, mask.FormatPNG)
+}
+
+// getMaskSVG godoc
+// @Summary luchador mask rendered from the configs as svg
+// @Produce image/svg+xml
+// @Param id path int true "Luchador ID"
+// @Success 200 {file} file
+// @Router /public/luchador/{id}/mask.svg [get]
+func getMaskSVG(c *gin.Context) {
+	renderMask(c, mask.FormatSVG)
+}
+
+func renderMask(c *gin.Context, format string) {
+	id, err := httphelper.GetIntegerParam(c, "id", "renderMask")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result, err := requestHandler.Render(id, format)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"id":     id,
+			"format": format,
+		}).Info("Invalid request on renderMask")
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNotFound) || errors.Is(err, mask.ErrAssetNotFound) {
+			status = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(status, err.Error())
+		return
+	}
+
+	etag := `"` + result.Hash + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, result.ContentType, result.Data)
+}
+
+// Render definition, the mask of the luchador in the format
+func (handler *RequestHandler) Render(luchadorID uint, format string) (*mask.Image, error) {
+	if handler.renderer == nil {
+		return nil, errors.New("mask renderer not configured")
+	}
+	if luchadorID == 0 {
+		return nil, errNotFound
+	}
+
+	luchador := handler.ds.FindLuchadorByID(luchadorID)
+	if luchador == nil {
+		return nil, errNotFound
+	}
+
+	return handler.renderer.Render(luchador.Configs, format)
+}
@@ -0,0 +1,127 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	maxBytesEnv       = "MEDIA_MAX_BYTES"
+	maxPixelsEnv      = "MEDIA_MAX_PIXELS"
+	userQuotaBytesEnv = "MEDIA_USER_QUOTA_BYTES"
+	renditionsFileEnv = "MEDIA_RENDITIONS_FILE"
+	uploadFolderEnv   = "MEDIA_UPLOAD_FOLDER"
+	maxOpenUploadsEnv = "MEDIA_MAX_OPEN_UPLOADS"
+
+	// RenditionThumbnail is the rendition used as the media thumbnail
+	RenditionThumbnail = "thumbnail"
+)
+
+// image types accepted by the upload and the extension of their files, the type is sniffed
+// from the content
+var allowedContentTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Rendition definition, the image is resized to fit the width and height keeping the
+// aspect ratio, zero is not limited, images smaller than the rendition are not enlarged
+type Rendition struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Config definition, limits of the uploaded images and the renditions created for each image,
+// the chunks of the resumable uploads are kept in the UploadFolder until the upload is completed
+// and each user can have MaxOpenUploads uploads in progress
+type Config struct {
+	MaxBytes       int64
+	MaxPixels      int
+	UserQuotaBytes int64
+	Renditions     []Rendition
+	UploadFolder   string
+	MaxOpenUploads int
+}
+
+// DefaultConfig definition
+func DefaultConfig() Config {
+	return Config{
+		MaxBytes:       5 * 1024 * 1024,
+		MaxPixels:      4096 * 4096,
+		UserQuotaBytes: 100 * 1024 * 1024,
+		Renditions: []Rendition{
+			{Name: RenditionThumbnail, Width: 300},
+			{Name: "preview", Width: 1024, Height: 1024},
+			{Name: "texture", Width: 512, Height: 512},
+		},
+		UploadFolder:   filepath.Join(os.TempDir(), "robolucha-media-upload"),
+		MaxOpenUploads: 5,
+	}
+}
+
+// ConfigFromEnv definition, MEDIA_MAX_BYTES, MEDIA_MAX_PIXELS, MEDIA_USER_QUOTA_BYTES and
+// MEDIA_MAX_OPEN_UPLOADS replace the default limits, MEDIA_RENDITIONS_FILE the renditions as
+// [{"name": "thumbnail", "width": 300}] and MEDIA_UPLOAD_FOLDER the upload folder
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+
+	for env, value := range map[string]*int64{
+		maxBytesEnv:       &config.MaxBytes,
+		userQuotaBytesEnv: &config.UserQuotaBytes,
+	} {
+		if text := os.Getenv(env); text != "" {
+			parsed, err := strconv.ParseInt(text, 10, 64)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("%v should be a positive number", env)
+			}
+			*value = parsed
+		}
+	}
+
+	for env, value := range map[string]*int{
+		maxPixelsEnv:      &config.MaxPixels,
+		maxOpenUploadsEnv: &config.MaxOpenUploads,
+	} {
+		if text := os.Getenv(env); text != "" {
+			parsed, err := strconv.Atoi(text)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("%v should be a positive number", env)
+			}
+			*value = parsed
+		}
+	}
+
+	if folder := os.Getenv(uploadFolderEnv); folder != "" {
+		config.UploadFolder = folder
+	}
+
+	if fileName := os.Getenv(renditionsFileEnv); fileName != "" {
+		bytes, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return config, fmt.Errorf("Error reading renditions file: %v", err)
+		}
+
+		var renditions []Rendition
+		err = json.Unmarshal(bytes, &renditions)
+		if err != nil {
+			return config, fmt.Errorf("Error parsing renditions file: %v", err)
+		}
+
+		for _, rendition := range renditions {
+			if rendition.Name == "" || rendition.Width < 0 || rendition.Height < 0 ||
+				rendition.Width == 0 && rendition.Height == 0 {
+				return config, fmt.Errorf("Invalid rendition %+v, name and width or height are required", rendition)
+			}
+		}
+		config.Renditions = renditions
+	}
+
+	return config, nil
+}
@@ -0,0 +1,275 @@
+package media
+
+import (
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/storage"
+)
+
+// getMediaLibrary godoc
+// @Summary find the media uploaded by the current user, search filters by the file name
+// @Accept json
+// @Produce json
+// @Param search query string false "text in the file name"
+// @Success 200 {array} model.Media
+// @Security ApiKeyAuth
+// @Router /private/media [get]
+func getMediaLibrary(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+	result := requestHandler.ds.FindMediaLibrary(user.ID, c.Query("search"))
+	c.JSON(http.StatusOK, result)
+}
+
+// updateMedia godoc
+// @Summary rename a media of the current user
+// @Accept json
+// @Produce json
+// @Param id path int true "Media id"
+// @Param request body model.MediaUpdateRequest true "MediaUpdateRequest"
+// @Success 200 {object} model.Media
+// @Security ApiKeyAuth
+// @Router /private/media/{id} [put]
+func updateMedia(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "updateMedia")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var request model.MediaUpdateRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on updateMedia")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if inappropriateFileName(c, user.ID, request.FileName) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Media file name contains inappropriate language")
+		return
+	}
+
+	result, err := requestHandler.Rename(user.ID, id, request.FileName)
+	if err != nil {
+		abortWithError(c, err, "updateMedia")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteMedia godoc
+// @Summary delete a media of the current user and its files, media attached to game definitions, narratives or avatars cant be deleted
+// @Accept json
+// @Produce json
+// @Param id path int true "Media id"
+// @Success 200 {string} string
+// @Security ApiKeyAuth
+// @Router /private/media/{id} [delete]
+func deleteMedia(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "deleteMedia")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = requestHandler.Delete(user.ID, id)
+	if err != nil {
+		abortWithError(c, err, "deleteMedia")
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
+
+// attachMedia godoc
+// @Summary use a media of the current user in a game definition or a narrative, replacing its current media
+// @Accept json
+// @Produce json
+// @Param id path int true "Media id"
+// @Param request body model.MediaAttachRequest true "MediaAttachRequest"
+// @Success 200 {object} model.Media
+// @Security ApiKeyAuth
+// @Router /private/media/{id}/attach [post]
+func attachMedia(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	id, target, err := attachParameters(c, "attachMedia")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// dont check ownership when user can edit any map
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionMapEditAny)
+
+	result, err := requestHandler.Attach(user.User.ID, id, target, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "attachMedia")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// detachMedia godoc
+// @Summary remove a media of the current user from a game definition or a narrative
+// @Accept json
+// @Produce json
+// @Param id path int true "Media id"
+// @Param request body model.MediaAttachRequest true "MediaAttachRequest"
+// @Success 200 {object} model.Media
+// @Security ApiKeyAuth
+// @Router /private/media/{id}/detach [post]
+func detachMedia(c *gin.Context) {
+	user := httphelper.UserDetailsFromContext(c)
+
+	id, target, err := attachParameters(c, "detachMedia")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// dont check ownership when user can edit any map
+	skipCheckOwnerShip := auth.UserHasPermission(user, auth.PermissionMapEditAny)
+
+	result, err := requestHandler.Detach(user.User.ID, id, target, skipCheckOwnerShip)
+	if err != nil {
+		abortWithError(c, err, "detachMedia")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func attachParameters(c *gin.Context, context string) (uint, model.MediaAttachRequest, error) {
+	var request model.MediaAttachRequest
+
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		return 0, request, err
+	}
+
+	err = c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on " + context)
+	}
+	return id, request, err
+}
+
+// Rename definition
+func (handler *RequestHandler) Rename(userID uint, id uint, fileName string) (*model.Media, error) {
+	length := utf8.RuneCountInString(fileName)
+	if length == 0 || length > 255 {
+		return nil, errFileName
+	}
+
+	media := handler.ds.FindLibraryMedia(userID, id)
+	if media == nil {
+		return nil, errNotFound
+	}
+
+	return handler.ds.UpdateMediaFileName(media, fileName), nil
+}
+
+// Delete definition, the files are removed from the store after the media is deleted,
+// a file that can't be removed is only logged
+func (handler *RequestHandler) Delete(userID uint, id uint) error {
+	media := handler.ds.FindLibraryMedia(userID, id)
+	if media == nil {
+		return errNotFound
+	}
+
+	if handler.ds.MediaIsReferenced(media) {
+		return errReferenced
+	}
+
+	err := handler.ds.DeleteMedia(media)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errStore, err)
+	}
+
+	keys := []string{media.Key}
+	for _, rendition := range media.Renditions {
+		keys = append(keys, rendition.Key)
+	}
+	storage.DeleteKeys(handler.store, keys)
+
+	return nil
+}
+
+// Attach definition
+func (handler *RequestHandler) Attach(userID uint, id uint, target model.MediaAttachRequest, skipCheckOwnerShip bool) (*model.Media, error) {
+	media, err := handler.findAttachment(userID, id, target, skipCheckOwnerShip)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := handler.ds.AttachMedia(media, target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	return result, nil
+}
+
+// Detach definition, returns the media from the library
+func (handler *RequestHandler) Detach(userID uint, id uint, target model.MediaAttachRequest, skipCheckOwnerShip bool) (*model.Media, error) {
+	media, err := handler.findAttachment(userID, id, target, skipCheckOwnerShip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.ds.DetachMedia(media, target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	return handler.ds.FindLibraryMedia(userID, id), nil
+}
+
+// the media must be in the library of the user and the user must own the game definition
+// of the target unless the ownership check is skipped
+func (handler *RequestHandler) findAttachment(userID uint, id uint, target model.MediaAttachRequest, skipCheckOwnerShip bool) (*model.Media, error) {
+	if (target.GameDefinitionID == 0) == (target.NarrativeDefinitionID == 0) {
+		return nil, errTarget
+	}
+
+	media := handler.ds.FindLibraryMedia(userID, id)
+	if media == nil {
+		return nil, errNotFound
+	}
+
+	gameDefinitionID := target.GameDefinitionID
+	if target.NarrativeDefinitionID != 0 {
+		narrative := handler.ds.FindNarrativeDefinition(target.NarrativeDefinitionID)
+		if narrative == nil || narrative.GameDefinitionID == 0 {
+			return nil, errTargetFound
+		}
+		gameDefinitionID = narrative.GameDefinitionID
+	}
+
+	gameDefinition := handler.ds.FindGameDefinition(gameDefinitionID)
+	if gameDefinition == nil {
+		return nil, errTargetFound
+	}
+
+	if !skipCheckOwnerShip && gameDefinition.OwnerUserID != userID {
+		log.WithFields(log.Fields{
+			"gameDefinition.OwnerUserID": gameDefinition.OwnerUserID,
+			"userID":                     userID,
+		}).Info("current user dont OWNS this gamedefinition, media cant be changed")
+		return nil, errNotOwner
+	}
+
+	return media, nil
+}
@@ -1,31 +1,47 @@
 package media
 
 import (
+	"crypto/sha256"
 	b64 "encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"bytes"
 	"image"
 
-	"github.com/disintegration/imaging"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"gitlab.com/robolucha/robolucha-api/datasource"
 	"gitlab.com/robolucha/robolucha-api/httphelper"
 	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 	"gitlab.com/robolucha/robolucha-api/pubsub"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gitlab.com/robolucha/robolucha-api/storage"
 
 	"github.com/gofrs/uuid"
 )
 
+var (
+	errInvalidImage  = errors.New("base64Data should be a base64 encoded image")
+	errContentType   = errors.New("image should be png, jpeg, gif or webp")
+	errTooLarge      = errors.New("image file is too large")
+	errTooManyPixels = errors.New("image width and height are too large")
+	errQuota         = errors.New("media storage quota exceeded")
+	errStore         = errors.New("error saving media file")
+	errNotFound      = errors.New("media not found")
+	errFileName      = errors.New("fileName length should be between 1 and 255 characters")
+	errReferenced    = errors.New("media is attached to a game definition, a narrative or an avatar")
+	errTarget        = errors.New("gameDefinitionID or narrativeDefinitionID should be informed")
+	errTargetFound   = errors.New("game definition or narrative not found")
+	errNotOwner      = errors.New("current user doesn't own the game definition")
+)
+
 // Init receive database and message queue objects
 func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
 	requestHandler = NewRequestHandler(_ds, _publisher)
@@ -39,6 +55,10 @@ func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
 type RequestHandler struct {
 	ds        *datasource.DataSource
 	publisher pubsub.Publisher
+	store     storage.MediaStore
+	config    Config
+	uploads   uploadLocks
+	sessions  sync.Mutex
 }
 
 // NewRequestHandler creates a new request handler
@@ -46,6 +66,7 @@ func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher)
 	handler := RequestHandler{
 		ds:        _ds,
 		publisher: _publisher,
+		config:    DefaultConfig(),
 	}
 
 	return &handler
@@ -53,6 +74,18 @@ func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher)
 
 var requestHandler *RequestHandler
 
+// WithStore sets where the media files are saved
+func (router *Router) WithStore(store storage.MediaStore) *Router {
+	requestHandler.store = store
+	return router
+}
+
+// WithConfig sets the limits and the renditions of the uploaded images
+func (router *Router) WithConfig(config Config) *Router {
+	requestHandler.config = config
+	return router
+}
+
 // Router definition
 type Router struct {
 	ds        *datasource.DataSource
@@ -61,7 +94,54 @@ type Router struct {
 
 // Setup definition
 func (router *Router) Setup(group *gin.RouterGroup) {
+	group.GET("/media", getMediaLibrary)
 	group.POST("/media", addMedia)
+	group.POST("/media-upload", uploadMedia)
+	group.POST("/media-upload/session", startMediaUpload)
+	group.GET("/media-upload/session/:id", getMediaUpload)
+	group.PUT("/media-upload/session/:id", sendMediaUploadChunk)
+	group.PUT("/media/:id", updateMedia)
+	group.DELETE("/media/:id", deleteMedia)
+	group.POST("/media/:id/attach", attachMedia)
+	group.POST("/media/:id/detach", detachMedia)
+}
+
+// maps the media errors to the response status
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, errContentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errTooLarge), errors.Is(err, errTooManyPixels):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errQuota), errors.Is(err, errNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, errUploadLimit):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errNotFound), errors.Is(err, errTargetFound), errors.Is(err, errUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errReferenced), errors.Is(err, errUploadOffset), errors.Is(err, errUploadCompleted):
+		return http.StatusConflict
+	case errors.Is(err, errStore):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+func abortWithError(c *gin.Context, err error, context string) {
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Info("Invalid request on " + context)
+	c.AbortWithStatusJSON(statusFromError(err), err.Error())
+}
+
+func inappropriateFileName(c *gin.Context, userID uint, fileName string) bool {
+	flags := moderation.Inspect(requestHandler.ds, moderation.Content{
+		Type:   model.MODERATION_CONTENT_MEDIA,
+		UserID: userID,
+		Locale: moderation.Locale(requestHandler.ds, c, httphelper.UserFromContext(c)),
+		Fields: []moderation.Field{{Name: "fileName", Text: fileName}},
+	})
+	return len(flags) > 0
 }
 
 // addMedia godoc
@@ -83,7 +163,20 @@ func addMedia(c *gin.Context) {
 		return
 	}
 
-	response := requestHandler.AddMedia(request, user.ID)
+	if inappropriateFileName(c, user.ID, request.FileName) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Media file name contains inappropriate language")
+		return
+	}
+
+	response, err := requestHandler.AddMedia(request, user.ID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Error on addMedia")
+		c.AbortWithStatusJSON(statusFromError(err), err.Error())
+		return
+	}
+
 	log.WithFields(log.Fields{
 		"response": response,
 	}).Info("addMedia")
@@ -104,132 +197,160 @@ func after(text string, find string) string {
 	return text[pos:len(text)]
 }
 
-// Add godoc
-func (handler *RequestHandler) AddMedia(request *model.MediaRequest, userID uint) model.Media {
+// AddMedia definition, the base64 data of the request is decoded and added as AddMediaData
+func (handler *RequestHandler) AddMedia(request *model.MediaRequest, userID uint) (*model.Media, error) {
+	config := handler.config
 
-	u2, err := uuid.NewV4()
+	// removes "data:image/png;base64," from the beginning of the data,
+	// the size is checked before decoding as base64 uses 4 characters for 3 bytes
+	base64 := after(request.Base64Data, ",")
+	if int64(len(base64)) > (config.MaxBytes+2)/3*4 {
+		return nil, errTooLarge
+	}
+
+	data, err := b64.StdEncoding.DecodeString(base64)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error generating UUID",
-			"err":  err,
-		}).Error("addMedia")
+		return nil, fmt.Errorf("%w: %v", errInvalidImage, err)
 	}
 
-	name := fmt.Sprintf("/tmp/%v-%v", u2, request.FileName)
-	thumbnail := fmt.Sprintf("/tmp/%v-thumb-%v", u2, request.FileName)
+	return handler.AddMediaData(request.FileName, data, userID)
+}
 
-	// removes "data:image/png;base64," from the beginning of the data
-	base64 := after(request.Base64Data, ",")
-	data, _ := b64.StdEncoding.DecodeString(base64)
+// AddMediaData definition, the image is validated and streamed to the store with its renditions,
+// the existing media is returned when the user already uploaded the same image
+func (handler *RequestHandler) AddMediaData(requestFileName string, data []byte, userID uint) (*model.Media, error) {
+	return handler.addMediaContent(requestFileName, bytes.NewReader(data), int64(len(data)), userID)
+}
 
-	img, _, err := image.Decode(bytes.NewReader(data))
-	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error creating image",
-			"err":  err,
-		}).Error("addMedia")
+// the content is read again from the beginning for each step, the files of the resumable
+// uploads are not loaded in memory
+func (handler *RequestHandler) addMediaContent(requestFileName string, content io.ReaderAt, size int64, userID uint) (*model.Media, error) {
+	if handler.store == nil {
+		return nil, fmt.Errorf("%w: media store not configured", errStore)
 	}
+	config := handler.config
 
-	dstImage800 := imaging.Resize(img, 300, 0, imaging.NearestNeighbor)
+	if size == 0 {
+		return nil, errInvalidImage
+	}
+	if size > config.MaxBytes {
+		return nil, errTooLarge
+	}
 
-	err = ioutil.WriteFile(name, data, 0666)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error writing temp image file",
-			"err":  err,
-		}).Error("addMedia")
+	head := make([]byte, 512)
+	read, err := io.ReadFull(fromStart(content, size), head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	contentType := http.DetectContentType(head[:read])
+	extension, allowed := allowedContentTypes[contentType]
+	if !allowed {
+		return nil, fmt.Errorf("%w: %v", errContentType, contentType)
 	}
 
-	err = imaging.Save(dstImage800, thumbnail, imaging.JPEGQuality(85))
+	// the dimensions are checked before decoding the pixels
+	imageConfig, _, err := image.DecodeConfig(fromStart(content, size))
 	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error saving thumbnail temp image file",
-			"err":  err,
-		}).Error("addMedia")
+		return nil, fmt.Errorf("%w: %v", errInvalidImage, err)
+	}
+	if imageConfig.Width*imageConfig.Height > config.MaxPixels {
+		return nil, errTooManyPixels
 	}
 
-	// upload files
-	uploadOriginal, errOriginal := upload(name)
-	if errOriginal != nil {
-		log.WithFields(log.Fields{
-			"step": "error uploading original file",
-			"err":  errOriginal,
-		}).Error("addMedia")
+	sum := sha256.New()
+	_, err = io.Copy(sum, fromStart(content, size))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	hash := hex.EncodeToString(sum.Sum(nil))
+	if existing := handler.ds.FindMediaByHash(userID, hash); existing != nil {
+		return existing, nil
 	}
 
-	uploadThumbnail, errThumb := upload(thumbnail)
-	if errThumb != nil {
-		log.WithFields(log.Fields{
-			"step": "error uploading thumbnail",
-			"err":  errThumb,
-		}).Error("addMedia")
+	if handler.ds.FindMediaUsageByUser(userID)+size > config.UserQuotaBytes {
+		return nil, errQuota
 	}
 
-	err = os.Remove(name)
+	img, _, err := image.Decode(fromStart(content, size))
 	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error removing temp file",
-			"name": name,
-			"err":  err,
-		}).Error("addMedia")
+		return nil, fmt.Errorf("%w: %v", errInvalidImage, err)
 	}
 
-	err = os.Remove(thumbnail)
+	u2, err := uuid.NewV4()
 	if err != nil {
-		log.WithFields(log.Fields{
-			"step": "error removing thumbnail temp file",
-			"name": thumbnail,
-			"err":  err,
-		}).Error("addMedia")
+		return nil, fmt.Errorf("error generating UUID, %v", err)
 	}
 
-	log.WithFields(log.Fields{
-		"step":            "after upload",
-		"uploadThumbnail": uploadThumbnail,
-		"uploadOriginal":  uploadOriginal,
-	}).Info("addMedia")
+	// the extension comes from the detected content type, never from the client file name
+	fileName := cleanFileName(strings.TrimSuffix(filepath.Base(requestFileName), filepath.Ext(requestFileName))) + extension
+	prefix := fmt.Sprintf("%v/%v", userID, u2)
+
+	key := prefix + "-" + fileName
+	url, err := handler.store.Put(key, fromStart(content, size), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
 
-	// upload the file here
 	media := model.Media{
-		UserID:    userID,
-		FileName:  request.FileName,
-		URL:       uploadOriginal.Location,
-		Thumbnail: uploadThumbnail.Location,
+		UserID:      userID,
+		FileName:    requestFileName,
+		URL:         url,
+		ContentType: contentType,
+		Size:        size,
+		Width:       imageConfig.Width,
+		Height:      imageConfig.Height,
+		Hash:        hash,
+		Key:         key,
+		Renditions:  make([]model.MediaRendition, 0, len(config.Renditions)),
 	}
 
-	handler.ds.DB.Create(&media)
-	return media
-}
+	// the files already saved are removed when the media is not added
+	keys := []string{key}
+	total := size
+	for _, rendition := range config.Renditions {
+		result, err := handler.putRendition(prefix, fileName, rendition, img, contentType)
+		if err != nil {
+			storage.DeleteKeys(handler.store, keys)
+			return nil, fmt.Errorf("%w: %v", errStore, err)
+		}
+
+		keys = append(keys, result.Key)
+		total += result.Size
+		media.Renditions = append(media.Renditions, *result)
+		if rendition.Name == RenditionThumbnail {
+			media.Thumbnail = result.URL
+		}
+	}
+
+	// the size of the renditions is only known after they are encoded
+	if handler.ds.FindMediaUsageByUser(userID)+total > config.UserQuotaBytes {
+		storage.DeleteKeys(handler.store, keys)
+		return nil, errQuota
+	}
 
-func upload(fileName string) (*s3manager.UploadOutput, error) {
-	// https://jto.nyc3.digitaloceanspaces.com
-	// The session the S3 Uploader will use
-	endpoint := "nyc3.digitaloceanspaces.com"
-	region := "nyc3"
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint: &endpoint,
-		Region:   &region,
-	}))
+	log.WithFields(log.Fields{
+		"step":       "after upload",
+		"url":        url,
+		"renditions": len(media.Renditions),
+	}).Info("addMedia")
 
-	// Create an uploader with the session and default options
-	uploader := s3manager.NewUploader(sess)
+	return handler.ds.AddMedia(&media), nil
+}
 
-	f, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file %q, %v", fileName, err)
-	}
+func fromStart(content io.ReaderAt, size int64) io.Reader {
+	return io.NewSectionReader(content, 0, size)
+}
 
-	myBucket := "game-robolucha"
-	// Upload the file to S3.
-	result, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(myBucket),
-		Key:    aws.String(fileName),
-		Body:   f,
-		ACL:    aws.String("public-read"),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to upload file, %v", err)
+// keeps only the base name of the file with letters, numbers, "." "-" and "_"
+func cleanFileName(fileName string) string {
+	var builder strings.Builder
+	for _, c := range filepath.Base(fileName) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+			builder.WriteRune(c)
+		default:
+			builder.WriteRune('_')
+		}
 	}
-
-	return result, err
+	return builder.String()
 }
@@ -0,0 +1,84 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"gitlab.com/robolucha/robolucha-api/model"
+
+	// webp images are accepted by the upload
+	_ "golang.org/x/image/webp"
+)
+
+// counts the bytes of the rendition while it is streamed to the store
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += int64(n)
+	return n, err
+}
+
+// jpeg images keep the format, the other formats are saved as png to keep the transparency
+func renditionFormat(contentType string) (imaging.Format, string, string) {
+	if contentType == "image/jpeg" {
+		return imaging.JPEG, "image/jpeg", ".jpg"
+	}
+	return imaging.PNG, "image/png", ".png"
+}
+
+// fits the image in the rendition width and height with Lanczos resampling,
+// images smaller than the rendition are not enlarged
+func resize(img image.Image, rendition Rendition) image.Image {
+	bounds := img.Bounds()
+
+	width, height := rendition.Width, rendition.Height
+	if width == 0 || width > bounds.Dx() {
+		width = bounds.Dx()
+	}
+	if height == 0 || height > bounds.Dy() {
+		height = bounds.Dy()
+	}
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return img
+	}
+	return imaging.Fit(img, width, height, imaging.Lanczos)
+}
+
+// the rendition is streamed to the store while it is encoded
+func (handler *RequestHandler) putRendition(prefix string, fileName string, rendition Rendition, img image.Image, contentType string) (*model.MediaRendition, error) {
+	format, renditionType, extension := renditionFormat(contentType)
+	key := fmt.Sprintf("%v-%v-%v%v", prefix, rendition.Name, strings.TrimSuffix(fileName, filepath.Ext(fileName)), extension)
+
+	resized := resize(img, rendition)
+
+	reader, writer := io.Pipe()
+	counter := countingWriter{writer: writer}
+	go func() {
+		writer.CloseWithError(imaging.Encode(&counter, resized, format, imaging.JPEGQuality(85)))
+	}()
+
+	url, err := handler.store.Put(key, reader, renditionType)
+	reader.CloseWithError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MediaRendition{
+		Name:        rendition.Name,
+		URL:         url,
+		ContentType: renditionType,
+		Size:        counter.count,
+		Width:       resized.Bounds().Dx(),
+		Height:      resized.Bounds().Dy(),
+		Key:         key,
+	}, nil
+}
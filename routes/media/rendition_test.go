@@ -0,0 +1,72 @@
+package media
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2000, 1000))
+
+	resized := resize(img, Rendition{Name: "thumbnail", Width: 300})
+	assert.Equal(t, 300, resized.Bounds().Dx())
+	assert.Equal(t, 150, resized.Bounds().Dy())
+
+	resized = resize(img, Rendition{Name: "texture", Width: 512, Height: 512})
+	assert.Equal(t, 512, resized.Bounds().Dx())
+	assert.Equal(t, 256, resized.Bounds().Dy())
+
+	resized = resize(img, Rendition{Name: "tall", Height: 100})
+	assert.Equal(t, 200, resized.Bounds().Dx())
+	assert.Equal(t, 100, resized.Bounds().Dy())
+
+	// small images are not enlarged
+	small := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	assert.Equal(t, small, resize(small, Rendition{Name: "preview", Width: 1024, Height: 1024}))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	defer os.Unsetenv(maxBytesEnv)
+	defer os.Unsetenv(renditionsFileEnv)
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultConfig(), config)
+
+	os.Setenv(maxBytesEnv, "1024")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1024), config.MaxBytes)
+
+	os.Setenv(maxBytesEnv, "-1")
+	_, err = ConfigFromEnv()
+	assert.NotNil(t, err)
+	os.Unsetenv(maxBytesEnv)
+
+	os.Setenv(maxOpenUploadsEnv, "2")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, config.MaxOpenUploads)
+	os.Unsetenv(maxOpenUploadsEnv)
+
+	folder, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+
+	fileName := filepath.Join(folder, "renditions.json")
+	os.Setenv(renditionsFileEnv, fileName)
+
+	ioutil.WriteFile(fileName, []byte(`[{"name": "thumbnail", "width": 128}]`), 0644)
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []Rendition{{Name: "thumbnail", Width: 128}}, config.Renditions)
+
+	ioutil.WriteFile(fileName, []byte(`[{"name": "thumbnail"}]`), 0644)
+	_, err = ConfigFromEnv()
+	assert.NotNil(t, err)
+}
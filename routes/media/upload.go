@@ -0,0 +1,433 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// room for the multipart boundaries and the other fields of the form
+const multipartOverhead = 64 * 1024
+
+// resumable uploads not completed after the expiration are removed
+const uploadExpiration = 24 * time.Hour
+
+// interval between the removals of the expired uploads
+const uploadCleanupInterval = time.Hour
+
+var (
+	errMultipart        = errors.New("body should be multipart/form-data with the image in the file field")
+	errUploadSize       = errors.New("size should be the number of bytes of the file")
+	errUploadNotFound   = errors.New("upload not found")
+	errUploadOffset     = errors.New("offset should be the received bytes of the upload")
+	errUploadCompleted  = errors.New("upload already completed")
+	errUploadIncomplete = errors.New("upload interrupted, resume from the received bytes")
+	errUploadLimit      = errors.New("too many uploads in progress, complete the open uploads or wait for their expiration")
+)
+
+// uploadMedia godoc
+// @Summary add media from a multipart/form-data upload, the fileName field replaces the name of the file
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "image file"
+// @Param fileName formData string false "file name"
+// @Success 200 {object} model.Media
+// @Security ApiKeyAuth
+// @Router /private/media-upload [post]
+func uploadMedia(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	fileName, data, err := requestHandler.readMultipart(c)
+	if err != nil {
+		abortWithError(c, err, "uploadMedia")
+		return
+	}
+
+	if inappropriateFileName(c, user.ID, fileName) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Media file name contains inappropriate language")
+		return
+	}
+
+	response, err := requestHandler.AddMediaData(fileName, data, user.ID)
+	if err != nil {
+		abortWithError(c, err, "uploadMedia")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"response": response,
+	}).Info("uploadMedia")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// startMediaUpload godoc
+// @Summary start a resumable upload, the chunks are sent to /private/media-upload/session/{id}
+// @Accept json
+// @Produce json
+// @Param request body model.MediaUploadRequest true "MediaUploadRequest"
+// @Success 200 {object} model.MediaUpload
+// @Security ApiKeyAuth
+// @Router /private/media-upload/session [post]
+func startMediaUpload(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	var request model.MediaUploadRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on startMediaUpload")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if inappropriateFileName(c, user.ID, request.FileName) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "Media file name contains inappropriate language")
+		return
+	}
+
+	result, err := requestHandler.StartUpload(user.ID, request)
+	if err != nil {
+		abortWithError(c, err, "startMediaUpload")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getMediaUpload godoc
+// @Summary upload progress, the received bytes are the offset to resume the upload and the media is informed when the upload is completed
+// @Accept json
+// @Produce json
+// @Param id path int true "MediaUpload id"
+// @Success 200 {object} model.MediaUpload
+// @Security ApiKeyAuth
+// @Router /private/media-upload/session/{id} [get]
+func getMediaUpload(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "getMediaUpload")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result := requestHandler.ds.FindMediaUpload(user.ID, id)
+	if result == nil {
+		abortWithError(c, errUploadNotFound, "getMediaUpload")
+		return
+	}
+	if result.MediaID != 0 {
+		result.Media = requestHandler.ds.FindLibraryMedia(user.ID, result.MediaID)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// sendMediaUploadChunk godoc
+// @Summary append the bytes of the body to the upload at the offset, the media is created with the last chunk
+// @Accept octet-stream
+// @Produce json
+// @Param id path int true "MediaUpload id"
+// @Param offset query int true "received bytes of the upload"
+// @Success 200 {object} model.MediaUpload
+// @Security ApiKeyAuth
+// @Router /private/media-upload/session/{id} [put]
+func sendMediaUploadChunk(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "sendMediaUploadChunk")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		abortWithError(c, errUploadOffset, "sendMediaUploadChunk")
+		return
+	}
+
+	result, err := requestHandler.AppendChunk(user.ID, id, offset, c.Request.Body)
+	if err != nil {
+		abortWithError(c, err, "sendMediaUploadChunk")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// the parts are read as they arrive, only the image is kept in memory
+func (handler *RequestHandler) readMultipart(c *gin.Context) (string, []byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, handler.config.MaxBytes+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", errMultipart, err)
+	}
+
+	var fileName, partFileName string
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", errMultipart, err)
+		}
+
+		switch part.FormName() {
+		case "file":
+			partFileName = part.FileName()
+			data, err = readLimited(part, handler.config.MaxBytes)
+		case "fileName":
+			var value []byte
+			value, err = readLimited(part, multipartOverhead)
+			fileName = string(value)
+		}
+		part.Close()
+
+		if errors.Is(err, errTooLarge) {
+			return "", nil, err
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", errMultipart, err)
+		}
+	}
+
+	if data == nil {
+		return "", nil, errMultipart
+	}
+	if fileName == "" {
+		fileName = partFileName
+	}
+	return fileName, data, nil
+}
+
+func readLimited(reader io.Reader, limit int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// uploadLocks definition, one lock for each upload receiving chunks, a slow client only
+// delays the chunks of its own upload
+type uploadLocks struct {
+	mutex sync.Mutex
+	locks map[uint]*uploadLock
+}
+
+type uploadLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock waits for the chunk in progress of the upload, the returned function releases the lock
+func (l *uploadLocks) lock(id uint) func() {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = make(map[uint]*uploadLock)
+	}
+	lock, found := l.locks[id]
+	if !found {
+		lock = &uploadLock{}
+		l.locks[id] = lock
+	}
+	lock.users++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		lock.users--
+		if lock.users == 0 {
+			delete(l.locks, id)
+		}
+	}
+}
+
+func (handler *RequestHandler) uploadFileName(upload *model.MediaUpload) string {
+	return filepath.Join(handler.config.UploadFolder, strconv.FormatUint(uint64(upload.ID), 10))
+}
+
+// StartUpload definition, the size and the quota are checked before receiving the chunks,
+// the sizes of the uploads in progress count as used by the quota
+func (handler *RequestHandler) StartUpload(userID uint, request model.MediaUploadRequest) (*model.MediaUpload, error) {
+	length := utf8.RuneCountInString(request.FileName)
+	if length == 0 || length > 255 {
+		return nil, errFileName
+	}
+	if request.Size <= 0 {
+		return nil, errUploadSize
+	}
+	if request.Size > handler.config.MaxBytes {
+		return nil, errTooLarge
+	}
+
+	// the checks and the new upload cant be interleaved with another start
+	handler.sessions.Lock()
+	defer handler.sessions.Unlock()
+
+	open := handler.ds.FindOpenMediaUploads(userID)
+	if len(open) >= handler.config.MaxOpenUploads {
+		return nil, errUploadLimit
+	}
+
+	reserved := request.Size
+	for _, upload := range open {
+		reserved += upload.Size
+	}
+	if handler.ds.FindMediaUsageByUser(userID)+reserved > handler.config.UserQuotaBytes {
+		return nil, errQuota
+	}
+
+	err := os.MkdirAll(handler.config.UploadFolder, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+
+	return handler.ds.AddMediaUpload(&model.MediaUpload{
+		UserID:   userID,
+		FileName: request.FileName,
+		Size:     request.Size,
+	}), nil
+}
+
+// RemoveExpiredUploads definition, removes the uploads of all the users not completed
+// after the expiration and the chunks received
+func RemoveExpiredUploads() {
+	for _, expired := range requestHandler.ds.DeleteExpiredMediaUploads(time.Now().Add(-uploadExpiration)) {
+		os.Remove(requestHandler.uploadFileName(&expired))
+	}
+}
+
+// WatchExpiredUploads removes the expired uploads every interval until stop is closed
+func WatchExpiredUploads(stop <-chan struct{}) {
+	ticker := time.NewTicker(uploadCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			RemoveExpiredUploads()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// AppendChunk definition, the chunk is appended to the file of the upload at the offset,
+// the bytes received before an interruption are kept and the media is created with the
+// last chunk
+func (handler *RequestHandler) AppendChunk(userID uint, id uint, offset int64, chunk io.Reader) (*model.MediaUpload, error) {
+	upload, err := handler.appendChunk(userID, id, offset, chunk)
+	if err != nil || upload.Received < upload.Size {
+		return upload, err
+	}
+
+	fileName := handler.uploadFileName(upload)
+	defer os.Remove(fileName)
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	defer file.Close()
+
+	media, err := handler.addMediaContent(upload.FileName, file, upload.Received, userID)
+	if err != nil {
+		// the upload cant be resumed after the file is removed
+		handler.ds.DeleteMediaUpload(upload)
+		return nil, err
+	}
+
+	upload.MediaID = media.ID
+	upload.Media = media
+	handler.ds.UpdateMediaUpload(upload)
+
+	log.WithFields(log.Fields{
+		"uploadID": upload.ID,
+		"mediaID":  media.ID,
+	}).Info("media upload completed")
+
+	return upload, nil
+}
+
+// the chunks of an upload are appended one at a time, the owner is checked before waiting
+// for the chunk in progress
+func (handler *RequestHandler) appendChunk(userID uint, id uint, offset int64, chunk io.Reader) (*model.MediaUpload, error) {
+	if handler.ds.FindMediaUpload(userID, id) == nil {
+		return nil, errUploadNotFound
+	}
+
+	unlock := handler.uploads.lock(id)
+	defer unlock()
+
+	upload := handler.ds.FindMediaUpload(userID, id)
+	if upload == nil {
+		return nil, errUploadNotFound
+	}
+	if upload.MediaID != 0 || upload.Received == upload.Size {
+		return nil, errUploadCompleted
+	}
+	if offset != upload.Received {
+		return nil, fmt.Errorf("%w: %v", errUploadOffset, upload.Received)
+	}
+
+	file, err := os.OpenFile(handler.uploadFileName(upload), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+	defer file.Close()
+
+	// drops the bytes written after the received bytes were saved
+	err = file.Truncate(upload.Received)
+	if err == nil {
+		_, err = file.Seek(upload.Received, io.SeekStart)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errStore, err)
+	}
+
+	remaining := upload.Size - upload.Received
+	written, err := io.Copy(file, io.LimitReader(chunk, remaining+1))
+	if written > remaining {
+		file.Truncate(upload.Received)
+		return nil, errTooLarge
+	}
+
+	upload.Received += written
+	handler.ds.UpdateMediaUpload(upload)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"uploadID": upload.ID,
+			"received": upload.Received,
+		}).Info("media upload interrupted")
+		return nil, fmt.Errorf("%w: %v", errUploadIncomplete, err)
+	}
+
+	return upload, nil
+}
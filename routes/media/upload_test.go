@@ -0,0 +1,41 @@
+package media
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadLocks(t *testing.T) {
+	var locks uploadLocks
+
+	unlockFirst := locks.lock(1)
+
+	// other uploads don't wait for the chunk in progress
+	done := make(chan bool)
+	go func() {
+		locks.lock(2)()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("upload 2 waiting for upload 1")
+	}
+
+	// the next chunk of the same upload waits
+	go func() {
+		locks.lock(1)()
+		done <- true
+	}()
+	select {
+	case <-done:
+		t.Fatal("upload 1 locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockFirst()
+	<-done
+	assert.Empty(t, locks.locks)
+}
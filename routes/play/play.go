@@ -64,6 +64,16 @@ func play(c *gin.Context) {
 		return
 	}
 
+	if input.GameDefinition != nil && input.GameDefinition.Hidden {
+		log.WithFields(log.Fields{
+			"message":          "GameDefinition hidden by the moderation",
+			"gameDefinitionID": input.GameDefinitionID,
+		}).Info("Invalid body content on play()")
+
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
 	log.WithFields(log.Fields{
 		"AvailableMatch": input,
 	}).Info("play()")
@@ -72,13 +82,23 @@ func play(c *gin.Context) {
 
 	if requestHandler.UserHasLevelToPlay(&user.Level, input.GameDefinition) {
 
-		luchador := requestHandler.ds.FindLuchador(user.User)
+		luchador := requestHandler.FindPlayLuchador(user.User, playRequest.LuchadorID)
 		log.WithFields(log.Fields{
 			"luchador": model.LogGameComponent(luchador),
 			"user.id":  user.User.ID,
 			"TeamID":   playRequest.TeamID,
 		}).Info("play()")
 
+		if luchador == nil {
+			log.WithFields(log.Fields{
+				"luchadorID": playRequest.LuchadorID,
+				"user.id":    user.User.ID,
+			}).Info("Luchador not found on play()")
+
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
 		match := requestHandler.Play(input, luchador.ID, playRequest.TeamID)
 
 		log.WithFields(log.Fields{
@@ -99,7 +119,7 @@ func play(c *gin.Context) {
 }
 
 // leaveTutorialMatch godoc
-// @Summary Sends message to end active tutorial matches
+// @Summary Sends message to end active tutorial matches of the luchadors of the user
 // @Accept json
 // @Produce json
 // @Success 200 {string} string
@@ -109,9 +129,8 @@ func leaveTutorialMatch(c *gin.Context) {
 
 	user := httphelper.UserFromContext(c)
 
-	var luchador *model.GameComponent
-	luchador = requestHandler.ds.FindLuchador(user)
-	if luchador == nil {
+	luchadors := requestHandler.ds.FindLuchadorsByUser(user.ID)
+	if len(luchadors) == 0 {
 		log.WithFields(log.Fields{
 			"user": user,
 		}).Error("Error getting luchador for the current user")
@@ -119,6 +138,8 @@ func leaveTutorialMatch(c *gin.Context) {
 		return
 	}
 
-	requestHandler.LeaveTutorialMatches(luchador)
+	for n := range luchadors {
+		requestHandler.LeaveTutorialMatches(&luchadors[n])
+	}
 	c.JSON(http.StatusOK, "")
 }
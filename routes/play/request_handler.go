@@ -209,6 +209,15 @@ func (handler *RequestHandler) FindAvailableMatchByID(id uint) *model.AvailableM
 	return &result
 }
 
+// FindPlayLuchador definition, one of the luchadors of the user or the default luchador
+// when luchadorID is empty, nil when the luchador is not found
+func (handler *RequestHandler) FindPlayLuchador(user *model.User, luchadorID uint) *model.GameComponent {
+	if luchadorID == 0 {
+		return handler.ds.FindLuchador(user)
+	}
+	return handler.ds.FindUserLuchador(user.ID, luchadorID)
+}
+
 // LeaveTutorialMatches definition
 func (handler *RequestHandler) LeaveTutorialMatches(gameComponent *model.GameComponent) {
 
@@ -145,3 +145,26 @@ func TestUserHasLevelToPlay(t *testing.T) {
 	assert.False(t, handler.UserHasLevelToPlay(&levelFourTeen, &defTenThirteen))
 
 }
+
+func TestFindPlayLuchador(t *testing.T) {
+	Setup(t)
+	defer ds.DB.Close()
+	handler := play.NewRequestHandler(ds, publisher)
+
+	user := ds.CreateUser("player")
+	first := ds.CreateLuchador(&model.GameComponent{UserID: user.ID, Name: "PvP Bot"})
+	second := ds.CreateLuchador(&model.GameComponent{UserID: user.ID, Name: "Tutorial Bot"})
+	other := createLuchador(user.ID + 1)
+
+	// the first luchador is used until the default is set
+	assert.Equal(t, first.ID, handler.FindPlayLuchador(user, 0).ID)
+	assert.Equal(t, second.ID, handler.FindPlayLuchador(user, second.ID).ID)
+
+	ds.SetDefaultLuchador(user, second.ID)
+	assert.Equal(t, second.ID, handler.FindPlayLuchador(user, 0).ID)
+	assert.Equal(t, first.ID, handler.FindPlayLuchador(user, first.ID).ID)
+
+	// only the luchadors of the user
+	assert.Nil(t, handler.FindPlayLuchador(user, other.ID))
+	assert.Nil(t, handler.FindPlayLuchador(user, 9999))
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/pubsub"
+)
+
+// maximum length of the reason sent by the user
+const maxReasonLength = 2000
+
+var (
+	errNotFound    = errors.New("reported content DOES NOT exist")
+	errContentType = errors.New("contentType should be luchador, gamedefinition or classroom")
+	errReason      = errors.New("reason should have at most 2000 characters")
+	errOwnContent  = errors.New("users cant report their own content")
+)
+
+// Init receive database and message queue objects
+func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
+	requestHandler = NewRequestHandler(_ds, _publisher)
+
+	return &Router{ds: _ds,
+		publisher: _publisher,
+	}
+}
+
+// RequestHandler definition
+type RequestHandler struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// NewRequestHandler creates a new request handler
+func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher) *RequestHandler {
+	handler := RequestHandler{
+		ds:        _ds,
+		publisher: _publisher,
+	}
+
+	return &handler
+}
+
+var requestHandler *RequestHandler
+
+// Router definition
+type Router struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// Setup definition
+func (router *Router) Setup(group *gin.RouterGroup) {
+	group.POST("/moderation/report", addModerationReport)
+}
+
+// maps the report errors to the response status
+func statusFromError(err error) int {
+	if errors.Is(err, errNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// addModerationReport godoc
+// @Summary report a luchador, map or classroom to the moderators
+// @Accept json
+// @Produce json
+// @Param request body model.ModerationReportRequest true "ModerationReportRequest"
+// @Success 200 {object} model.ModerationReport
+// @Security ApiKeyAuth
+// @Router /private/moderation/report [post]
+func addModerationReport(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	var request model.ModerationReportRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on addModerationReport")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result, err := requestHandler.Add(user.ID, request)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Info("Invalid request on addModerationReport")
+		c.AbortWithStatusJSON(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Add definition, the report is added to the moderation queue with the owner of the content
+func (handler *RequestHandler) Add(userID uint, request model.ModerationReportRequest) (*model.ModerationReport, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if len(reason) > maxReasonLength {
+		return nil, errReason
+	}
+
+	ownerID, err := handler.findOwner(request.ContentType, request.ContentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ownerID == userID {
+		return nil, errOwnContent
+	}
+
+	return handler.ds.AddModerationReport(&model.ModerationReport{
+		ReporterUserID: userID,
+		ContentType:    request.ContentType,
+		ContentID:      request.ContentID,
+		ReportedUserID: ownerID,
+		Reason:         reason,
+		Status:         model.MODERATION_REPORT_PENDING,
+	}), nil
+}
+
+// finds the user that owns the reported content, system maps and NPCs have no owner
+func (handler *RequestHandler) findOwner(contentType string, contentID uint) (uint, error) {
+	switch contentType {
+	case model.MODERATION_CONTENT_LUCHADOR:
+		luchador := handler.ds.FindLuchadorByIDNoPreload(contentID)
+		if luchador == nil || luchador.IsNPC {
+			return 0, errNotFound
+		}
+		return luchador.UserID, nil
+	case model.MODERATION_CONTENT_GAMEDEFINITION:
+		gameDefinition := handler.ds.FindGameDefinition(contentID)
+		if gameDefinition == nil || gameDefinition.OwnerUserID == 0 {
+			return 0, errNotFound
+		}
+		return gameDefinition.OwnerUserID, nil
+	case model.MODERATION_CONTENT_CLASSROOM:
+		classroom := handler.ds.FindClassroomByID(contentID)
+		if classroom == nil || classroom.ID == 0 {
+			return 0, errNotFound
+		}
+		return classroom.OwnerID, nil
+	}
+	return 0, errContentType
+}
@@ -0,0 +1,226 @@
+package review
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// getModerationReports godoc
+// @Summary find the reports sent by the users by status, pending reports when status is not informed
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, resolved or dismissed"
+// @Success 200 {array} model.ModerationReport
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/report [get]
+func getModerationReports(c *gin.Context) {
+	status := c.DefaultQuery("status", model.MODERATION_REPORT_PENDING)
+	if status != model.MODERATION_REPORT_PENDING &&
+		status != model.MODERATION_REPORT_RESOLVED &&
+		status != model.MODERATION_REPORT_DISMISSED {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "status should be pending, resolved or dismissed")
+		return
+	}
+
+	result := requestHandler.ds.FindModerationReportsByStatus(status)
+	c.JSON(http.StatusOK, result)
+}
+
+// resolveModerationReport godoc
+// @Summary resolve the report applying rename-luchador, hide-map or suspend-user, an empty action only resolves the report
+// @Accept json
+// @Produce json
+// @Param id path int true "ModerationReport id"
+// @Param request body model.ModerationActionRequest true "ModerationActionRequest"
+// @Success 200 {object} model.ModerationReport
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/report/{id}/resolve [post]
+func resolveModerationReport(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "resolveModerationReport")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var request model.ModerationActionRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		log.Info("Invalid body content on resolveModerationReport")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	result, err := requestHandler.Resolve(c, user.ID, id, request)
+	if err != nil {
+		abortWithError(c, err, "resolveModerationReport")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// dismissModerationReport godoc
+// @Summary dismiss the report without changing the reported content
+// @Accept json
+// @Produce json
+// @Param id path int true "ModerationReport id"
+// @Param request body model.ModerationReviewRequest false "ModerationReviewRequest"
+// @Success 200 {object} model.ModerationReport
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/report/{id}/dismiss [post]
+func dismissModerationReport(c *gin.Context) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", "dismissModerationReport")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// the body is optional
+	var request model.ModerationReviewRequest
+	if c.Request.ContentLength > 0 {
+		err = c.BindJSON(&request)
+		if err != nil {
+			log.Info("Invalid body content on dismissModerationReport")
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := requestHandler.findPendingReport(id)
+	if err != nil {
+		abortWithError(c, err, "dismissModerationReport")
+		return
+	}
+
+	result := requestHandler.closeReport(user.ID, report, model.MODERATION_REPORT_DISMISSED, "", request.Note)
+	c.JSON(http.StatusOK, result)
+}
+
+// unsuspendUser godoc
+// @Summary allow a suspended user to start new sessions
+// @Accept json
+// @Produce json
+// @Param id path int true "User id"
+// @Success 200 {object} model.User
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/user/{id}/unsuspend [post]
+func unsuspendUser(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "unsuspendUser")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	user := requestHandler.ds.FindUserByID(id)
+	if user == nil {
+		abortWithError(c, errUserNotFound, "unsuspendUser")
+		return
+	}
+
+	c.JSON(http.StatusOK, requestHandler.ds.UnsuspendUser(user))
+}
+
+// unhideMap godoc
+// @Summary list the matches of a map hidden by the moderators again
+// @Accept json
+// @Produce json
+// @Param id path int true "GameDefinition id"
+// @Success 200 {string} string
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/map/{id}/unhide [post]
+func unhideMap(c *gin.Context) {
+	id, err := httphelper.GetIntegerParam(c, "id", "unhideMap")
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if !requestHandler.ds.UpdateGameDefinitionHidden(id, false) {
+		abortWithError(c, errMapNotFound, "unhideMap")
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
+
+// Resolve definition, applies the action to the reported content and resolves the report
+func (handler *RequestHandler) Resolve(c *gin.Context, userID uint, id uint, request model.ModerationActionRequest) (*model.ModerationReport, error) {
+	report, err := handler.findPendingReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	note := strings.TrimSpace(request.Note)
+
+	switch {
+	case request.Action == "":
+	case request.Action == model.MODERATION_ACTION_RENAME_LUCHADOR &&
+		report.ContentType == model.MODERATION_CONTENT_LUCHADOR:
+		response := handler.renameLuchador(c, report.ContentID, request.Name)
+		if response == nil {
+			return nil, errContentNotFound
+		}
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("%w: %v", errRename, strings.Join(response.Errors, ", "))
+		}
+	case request.Action == model.MODERATION_ACTION_HIDE_MAP &&
+		report.ContentType == model.MODERATION_CONTENT_GAMEDEFINITION:
+		if !handler.ds.UpdateGameDefinitionHidden(report.ContentID, true) {
+			return nil, errContentNotFound
+		}
+	case request.Action == model.MODERATION_ACTION_SUSPEND_USER:
+		user := handler.ds.FindUserByID(report.ReportedUserID)
+		if user == nil {
+			return nil, errUserNotFound
+		}
+		reason := note
+		if reason == "" {
+			reason = report.Reason
+		}
+		handler.ds.SuspendUser(user, reason)
+	default:
+		return nil, errAction
+	}
+
+	return handler.closeReport(userID, report, model.MODERATION_REPORT_RESOLVED, request.Action, note), nil
+}
+
+func (handler *RequestHandler) findPendingReport(id uint) (*model.ModerationReport, error) {
+	report := handler.ds.FindModerationReport(id)
+	if report == nil {
+		return nil, errReportNotFound
+	}
+	if report.Status != model.MODERATION_REPORT_PENDING {
+		return nil, errReportReviewed
+	}
+	return report, nil
+}
+
+func (handler *RequestHandler) closeReport(userID uint, report *model.ModerationReport, status string, action string, note string) *model.ModerationReport {
+	now := time.Now()
+	report.Status = status
+	report.Action = action
+	report.ReviewedByUserID = userID
+	report.ReviewedAt = &now
+	report.Note = strings.TrimSpace(note)
+	handler.ds.UpdateModerationReport(report)
+
+	log.WithFields(log.Fields{
+		"report": report.ID,
+		"status": status,
+		"action": action,
+	}).Info("Moderation report reviewed")
+
+	return report
+}
@@ -0,0 +1,252 @@
+package review
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/datasource"
+	"gitlab.com/robolucha/robolucha-api/httphelper"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/moderation"
+	"gitlab.com/robolucha/robolucha-api/pubsub"
+)
+
+var (
+	errNotFound        = errors.New("moderation flag DOES NOT exist")
+	errReviewed        = errors.New("moderation flag was already reviewed")
+	errReportNotFound  = errors.New("moderation report DOES NOT exist")
+	errReportReviewed  = errors.New("moderation report was already reviewed")
+	errContentNotFound = errors.New("reported content DOES NOT exist")
+	errUserNotFound    = errors.New("user DOES NOT exist")
+	errMapNotFound     = errors.New("map DOES NOT exist")
+	errAction          = errors.New("action should be rename-luchador for luchadors, hide-map for maps or suspend-user")
+	errRename          = errors.New("luchador name is invalid")
+)
+
+// LuchadorRenamer definition, renames the luchador with the same validations and update
+// message of the luchador update, nil when the luchador is not found
+type LuchadorRenamer func(c *gin.Context, luchadorID uint, name string) *model.UpdateLuchadorResponse
+
+// Init receive database and message queue objects
+func Init(_ds *datasource.DataSource, _publisher pubsub.Publisher) *Router {
+	requestHandler = NewRequestHandler(_ds, _publisher)
+
+	return &Router{ds: _ds,
+		publisher: _publisher,
+	}
+}
+
+// RequestHandler definition
+type RequestHandler struct {
+	ds             *datasource.DataSource
+	publisher      pubsub.Publisher
+	renameLuchador LuchadorRenamer
+}
+
+// NewRequestHandler creates a new request handler
+func NewRequestHandler(_ds *datasource.DataSource, _publisher pubsub.Publisher) *RequestHandler {
+	handler := RequestHandler{
+		ds:        _ds,
+		publisher: _publisher,
+	}
+
+	return &handler
+}
+
+var requestHandler *RequestHandler
+
+// WithLuchadorRenamer sets how the luchadors are renamed when a report is resolved
+func (router *Router) WithLuchadorRenamer(renamer LuchadorRenamer) *Router {
+	requestHandler.renameLuchador = renamer
+	return router
+}
+
+// Router definition
+type Router struct {
+	ds        *datasource.DataSource
+	publisher pubsub.Publisher
+}
+
+// Setup definition
+func (router *Router) Setup(group *gin.RouterGroup) {
+	reviewer := auth.RequirePermission(auth.PermissionModerationReview)
+
+	group.GET("/moderation/flag", reviewer, getModerationFlags)
+	group.POST("/moderation/flag/:id/confirm", reviewer, confirmModerationFlag)
+	group.POST("/moderation/flag/:id/dismiss", reviewer, dismissModerationFlag)
+	group.GET("/moderation/allowed-word", reviewer, getAllowedWords)
+	group.POST("/moderation/reload", reviewer, reloadWordLists)
+	group.GET("/moderation/report", reviewer, getModerationReports)
+	group.POST("/moderation/report/:id/resolve", reviewer, resolveModerationReport)
+	group.POST("/moderation/report/:id/dismiss", reviewer, dismissModerationReport)
+	group.POST("/moderation/user/:id/unsuspend", reviewer, unsuspendUser)
+	group.POST("/moderation/map/:id/unhide", reviewer, unhideMap)
+}
+
+// maps the review errors to the response status
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, errNotFound),
+		errors.Is(err, errReportNotFound),
+		errors.Is(err, errContentNotFound),
+		errors.Is(err, errUserNotFound),
+		errors.Is(err, errMapNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errReviewed), errors.Is(err, errReportReviewed):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func abortWithError(c *gin.Context, err error, context string) {
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Info("Invalid request on " + context)
+	c.AbortWithStatusJSON(statusFromError(err), err.Error())
+}
+
+// getModerationFlags godoc
+// @Summary find the moderation flags by status, pending flags when status is not informed
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, confirmed or dismissed"
+// @Success 200 {array} model.ModerationFlag
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/flag [get]
+func getModerationFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", model.MODERATION_FLAG_PENDING)
+	if status != model.MODERATION_FLAG_PENDING &&
+		status != model.MODERATION_FLAG_CONFIRMED &&
+		status != model.MODERATION_FLAG_DISMISSED {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "status should be pending, confirmed or dismissed")
+		return
+	}
+
+	result := requestHandler.ds.FindModerationFlagsByStatus(status)
+	c.JSON(http.StatusOK, result)
+}
+
+// confirmModerationFlag godoc
+// @Summary confirm the flagged text is inappropriate
+// @Accept json
+// @Produce json
+// @Param id path int true "ModerationFlag id"
+// @Param request body model.ModerationReviewRequest false "ModerationReviewRequest"
+// @Success 200 {object} model.ModerationFlag
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/flag/{id}/confirm [post]
+func confirmModerationFlag(c *gin.Context) {
+	reviewFlag(c, model.MODERATION_FLAG_CONFIRMED, "confirmModerationFlag")
+}
+
+// dismissModerationFlag godoc
+// @Summary dismiss the flag as a false positive, allowMatches adds the matched words to the allow-list
+// @Accept json
+// @Produce json
+// @Param id path int true "ModerationFlag id"
+// @Param request body model.ModerationReviewRequest false "ModerationReviewRequest"
+// @Success 200 {object} model.ModerationFlag
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/flag/{id}/dismiss [post]
+func dismissModerationFlag(c *gin.Context) {
+	reviewFlag(c, model.MODERATION_FLAG_DISMISSED, "dismissModerationFlag")
+}
+
+func reviewFlag(c *gin.Context, status string, context string) {
+	user := httphelper.UserFromContext(c)
+
+	id, err := httphelper.GetIntegerParam(c, "id", context)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// the body is optional
+	var request model.ModerationReviewRequest
+	if c.Request.ContentLength > 0 {
+		err = c.BindJSON(&request)
+		if err != nil {
+			log.Info("Invalid body content on " + context)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := requestHandler.Review(user.ID, id, status, request)
+	if err != nil {
+		abortWithError(c, err, context)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getAllowedWords godoc
+// @Summary find the words allowed by the reviewers
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.ModerationAllowedWord
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/allowed-word [get]
+func getAllowedWords(c *gin.Context) {
+	result := requestHandler.ds.FindAllModerationAllowedWords()
+	c.JSON(http.StatusOK, result)
+}
+
+// reloadWordLists godoc
+// @Summary reload the word list files and the words allowed by the reviewers
+// @Accept json
+// @Produce json
+// @Success 200 {string} string
+// @Security ApiKeyAuth
+// @Router /dashboard/moderation/reload [post]
+func reloadWordLists(c *gin.Context) {
+	moderation.Default().Reload()
+	moderation.LoadReviewedWords(requestHandler.ds)
+	c.JSON(http.StatusOK, "")
+}
+
+// Review definition, only pending flags can be reviewed
+func (handler *RequestHandler) Review(userID uint, id uint, status string, request model.ModerationReviewRequest) (*model.ModerationFlag, error) {
+	flag := handler.ds.FindModerationFlag(id)
+	if flag == nil {
+		return nil, errNotFound
+	}
+	if flag.Status != model.MODERATION_FLAG_PENDING {
+		return nil, errReviewed
+	}
+
+	now := time.Now()
+	flag.Status = status
+	flag.ReviewedByUserID = userID
+	flag.ReviewedAt = &now
+	flag.Note = strings.TrimSpace(request.Note)
+	handler.ds.UpdateModerationFlag(flag)
+
+	if status == model.MODERATION_FLAG_DISMISSED && request.AllowMatches {
+		for _, word := range strings.Split(flag.Matches, ",") {
+			if word == "" {
+				continue
+			}
+			handler.ds.AddModerationAllowedWord(&model.ModerationAllowedWord{
+				Word:            word,
+				Locale:          flag.Locale,
+				CreatedByUserID: userID,
+			})
+		}
+		moderation.LoadReviewedWords(handler.ds)
+	}
+
+	log.WithFields(log.Fields{
+		"flag":         flag.ID,
+		"status":       status,
+		"allowMatches": request.AllowMatches,
+	}).Info("Moderation flag reviewed")
+
+	return flag, nil
+}
@@ -0,0 +1,274 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+// MaxScriptLength the characters of the script of a code, the size of the script column,
+// longer scripts would be truncated or rejected by the database
+const MaxScriptLength = 125000
+
+// MaxBlocklyLength the characters of the blockly xml of a code, the size of the blockly column
+const MaxBlocklyLength = 125000
+
+// the globals of the lua standard library giving access to the server or to other scripts
+var forbidden = map[string]bool{
+	"collectgarbage": true,
+	"debug":          true,
+	"dofile":         true,
+	"getfenv":        true,
+	"getmetatable":   true,
+	"io":             true,
+	"load":           true,
+	"loadfile":       true,
+	"loadstring":     true,
+	"module":         true,
+	"os":             true,
+	"package":        true,
+	"rawequal":       true,
+	"rawget":         true,
+	"rawset":         true,
+	"require":        true,
+	"setfenv":        true,
+	"setmetatable":   true,
+	// the global table gives access to the forbidden globals with any expression as the key
+	globalTable: true,
+}
+
+const globalTable = "_G"
+
+// Check definition, the errors of the codes, size limits, syntax errors with the line and
+// column and the use of the forbidden globals, label identifies the codes in the messages
+func Check(label string, codes []model.Code) []string {
+	result := make([]string, 0)
+	for _, code := range codes {
+		result = append(result, CheckCode(label, code)...)
+	}
+	return result
+}
+
+// Changed definition, the codes without a stored code with the same event and script, the
+// stored scripts are saved again without the checks as they may be older than the checks
+func Changed(codes []model.Code, stored []model.Code) []model.Code {
+	found := make(map[codeKey]bool)
+	for _, code := range stored {
+		found[keyOf(code)] = true
+	}
+
+	result := make([]model.Code, 0)
+	for _, code := range codes {
+		if !found[keyOf(code)] {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+type codeKey struct {
+	event   string
+	script  string
+	blockly string
+}
+
+func keyOf(code model.Code) codeKey {
+	return codeKey{event: code.Event, script: code.Script, blockly: code.Blockly}
+}
+
+// CheckCode definition, the errors of the script of the code
+func CheckCode(label string, code model.Code) []string {
+	name := strings.TrimSpace(label + " " + code.Event)
+
+	if length := utf8.RuneCountInString(code.Script); length > MaxScriptLength {
+		return []string{fmt.Sprintf("%v script length should be less or equal to %v characters, found %v", name, MaxScriptLength, length)}
+	}
+
+	result := make([]string, 0)
+	if length := utf8.RuneCountInString(code.Blockly); length > MaxBlocklyLength {
+		result = append(result, fmt.Sprintf("%v blockly length should be less or equal to %v characters", name, MaxBlocklyLength))
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(code.Script), code.Event)
+	if err != nil {
+		return append(result, syntaxError(name, err))
+	}
+
+	walker := walker{name: name, errors: result}
+	walker.block(chunk)
+	return walker.errors
+}
+
+func syntaxError(name string, err error) string {
+	parseError, ok := err.(*parse.Error)
+	if !ok {
+		return fmt.Sprintf("%v has a syntax error: %v", name, err)
+	}
+
+	if parseError.Pos.Line == parse.EOF {
+		return fmt.Sprintf("%v has a syntax error at the end of the script: %v", name, parseError.Message)
+	}
+	return fmt.Sprintf("%v has a syntax error at line %v column %v near '%v': %v",
+		name, parseError.Pos.Line, parseError.Pos.Column, parseError.Token, parseError.Message)
+}
+
+// walker finds the forbidden globals, the local variables with the same names are accepted
+type walker struct {
+	name   string
+	scopes []map[string]bool
+	errors []string
+}
+
+func (w *walker) forbidden(line int, global string) {
+	w.errors = append(w.errors, fmt.Sprintf("%v uses %v at line %v, it is not available for the luchadors", w.name, global, line))
+}
+
+func (w *walker) declare(names ...string) {
+	scope := w.scopes[len(w.scopes)-1]
+	for _, name := range names {
+		scope[name] = true
+	}
+}
+
+func (w *walker) isLocal(name string) bool {
+	for _, scope := range w.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// block walks the statements in a new scope, the names are declared in the scope
+func (w *walker) block(stmts []ast.Stmt, names ...string) {
+	w.enter(names...)
+	w.stmts(stmts)
+	w.leave()
+}
+
+func (w *walker) enter(names ...string) {
+	w.scopes = append(w.scopes, make(map[string]bool))
+	w.declare(names...)
+}
+
+func (w *walker) leave() {
+	w.scopes = w.scopes[:len(w.scopes)-1]
+}
+
+func (w *walker) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		w.stmt(stmt)
+	}
+}
+
+func (w *walker) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		for _, target := range s.Lhs {
+			// assigning a global doesn't use it
+			if _, ok := target.(*ast.IdentExpr); !ok {
+				w.expr(target)
+			}
+		}
+		w.exprs(s.Rhs)
+	case *ast.LocalAssignStmt:
+		w.exprs(s.Exprs)
+		w.declare(s.Names...)
+	case *ast.FuncCallStmt:
+		w.expr(s.Expr)
+	case *ast.DoBlockStmt:
+		w.block(s.Stmts)
+	case *ast.WhileStmt:
+		w.expr(s.Condition)
+		w.block(s.Stmts)
+	case *ast.RepeatStmt:
+		// the condition sees the locals of the body
+		w.enter()
+		w.stmts(s.Stmts)
+		w.expr(s.Condition)
+		w.leave()
+	case *ast.IfStmt:
+		w.expr(s.Condition)
+		w.block(s.Then)
+		w.block(s.Else)
+	case *ast.NumberForStmt:
+		w.exprs([]ast.Expr{s.Init, s.Limit, s.Step})
+		w.block(s.Stmts, s.Name)
+	case *ast.GenericForStmt:
+		w.exprs(s.Exprs)
+		w.block(s.Stmts, s.Names...)
+	case *ast.FuncDefStmt:
+		if _, ok := s.Name.Func.(*ast.IdentExpr); !ok {
+			w.expr(s.Name.Func)
+		}
+		if s.Name.Receiver != nil {
+			w.expr(s.Name.Receiver)
+		}
+		w.function(s.Func, s.Name.Method != "")
+	case *ast.ReturnStmt:
+		w.exprs(s.Exprs)
+	}
+}
+
+func (w *walker) exprs(exprs []ast.Expr) {
+	for _, expr := range exprs {
+		w.expr(expr)
+	}
+}
+
+func (w *walker) function(function *ast.FunctionExpr, method bool) {
+	names := append([]string{}, function.ParList.Names...)
+	if method {
+		names = append(names, "self")
+	}
+	w.block(function.Stmts, names...)
+}
+
+func (w *walker) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.IdentExpr:
+		if forbidden[e.Value] && !w.isLocal(e.Value) {
+			w.forbidden(e.Line(), e.Value)
+		}
+	case *ast.AttrGetExpr:
+		// _G.os is reported with the global, any other use of _G as the global table
+		if object, ok := e.Object.(*ast.IdentExpr); ok && object.Value == globalTable && !w.isLocal(globalTable) {
+			if key, ok := e.Key.(*ast.StringExpr); ok && forbidden[key.Value] {
+				w.forbidden(e.Line(), globalTable+"."+key.Value)
+				break
+			}
+		}
+		w.expr(e.Object)
+		w.expr(e.Key)
+	case *ast.TableExpr:
+		for _, field := range e.Fields {
+			w.expr(field.Key)
+			w.expr(field.Value)
+		}
+	case *ast.FuncCallExpr:
+		w.expr(e.Func)
+		w.expr(e.Receiver)
+		w.exprs(e.Args)
+	case *ast.LogicalOpExpr:
+		w.exprs([]ast.Expr{e.Lhs, e.Rhs})
+	case *ast.RelationalOpExpr:
+		w.exprs([]ast.Expr{e.Lhs, e.Rhs})
+	case *ast.StringConcatOpExpr:
+		w.exprs([]ast.Expr{e.Lhs, e.Rhs})
+	case *ast.ArithmeticOpExpr:
+		w.exprs([]ast.Expr{e.Lhs, e.Rhs})
+	case *ast.UnaryMinusOpExpr:
+		w.expr(e.Expr)
+	case *ast.UnaryNotOpExpr:
+		w.expr(e.Expr)
+	case *ast.UnaryLenOpExpr:
+		w.expr(e.Expr)
+	case *ast.FunctionExpr:
+		w.function(e, false)
+	}
+}
@@ -0,0 +1,96 @@
+package script
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+func TestCheckValidScript(t *testing.T) {
+	codes := []model.Code{
+		{Event: "onStart", Script: "turnGun(90)"},
+		{Event: "onRepeat", Script: `
+local os = 10
+for i = 1, 3 do
+  move(os * i)
+end
+if lastFire then fire(1) else turn(45) end
+`},
+		{Event: "onHitWall", Script: ""},
+	}
+
+	assert.Empty(t, Check("Code", codes))
+}
+
+func TestCheckSyntaxError(t *testing.T) {
+	messages := CheckCode("Code", model.Code{Event: "onRepeat", Script: "move(10)\nif x then\n  turn(90\nend"})
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "Code onRepeat has a syntax error at line 4 column 3 near 'end': syntax error", messages[0])
+
+	messages = CheckCode("Code", model.Code{Event: "onStart", Script: "while true do\n  move(1)"})
+	assert.Equal(t, 1, len(messages))
+	assert.True(t, strings.HasPrefix(messages[0], "Code onStart has a syntax error at the end of the script"), messages[0])
+}
+
+func TestCheckForbiddenGlobals(t *testing.T) {
+	script := `
+os.exit(1)
+local f = load("move(1)")
+function run(debug)
+  debug.traceback()
+end
+local value = _G.io
+print(require)
+`
+	messages := CheckCode("Code", model.Code{Event: "onRepeat", Script: script})
+	assert.Equal(t, []string{
+		"Code onRepeat uses os at line 2, it is not available for the luchadors",
+		"Code onRepeat uses load at line 3, it is not available for the luchadors",
+		"Code onRepeat uses _G.io at line 7, it is not available for the luchadors",
+		"Code onRepeat uses require at line 8, it is not available for the luchadors",
+	}, messages)
+}
+
+func TestCheckGlobalTable(t *testing.T) {
+	script := `
+local name = "o" .. "s"
+_G[name].exit(1)
+local env = _G
+setmetatable({}, {__index = function() end})
+local _G = {}
+print(_G.io)
+`
+	messages := CheckCode("Code", model.Code{Event: "onRepeat", Script: script})
+	assert.Equal(t, []string{
+		"Code onRepeat uses _G at line 3, it is not available for the luchadors",
+		"Code onRepeat uses _G at line 4, it is not available for the luchadors",
+		"Code onRepeat uses setmetatable at line 5, it is not available for the luchadors",
+	}, messages)
+}
+
+func TestCheckLength(t *testing.T) {
+	long := strings.Repeat("-", MaxScriptLength+1)
+	messages := CheckCode("Code", model.Code{Event: "onRepeat", Script: long})
+	assert.Equal(t, []string{"Code onRepeat script length should be less or equal to 125000 characters, found 125001"}, messages)
+
+	messages = CheckCode("", model.Code{Event: "onStart", Script: "move(1)", Blockly: strings.Repeat("x", MaxBlocklyLength+1)})
+	assert.Equal(t, []string{"onStart blockly length should be less or equal to 125000 characters"}, messages)
+}
+
+func TestChanged(t *testing.T) {
+	stored := []model.Code{
+		{Event: "onRepeat", Script: "move(10"},
+		{Event: "onStart", Script: "turn(90)", GameDefinitionID: 2},
+	}
+	codes := []model.Code{
+		{Event: "onRepeat", Script: "move(10"},
+		{Event: "onStart", Script: "turn(90)"},
+		{Event: "onStart", Script: "turn(45)"},
+		{Event: "onHitWall", Script: "move(10"},
+	}
+
+	changed := Changed(codes, stored)
+	assert.Equal(t, []model.Code{codes[2], codes[3]}, changed)
+}
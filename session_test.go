@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/robolucha/robolucha-api/auth"
+	"gitlab.com/robolucha/robolucha-api/model"
+	"gitlab.com/robolucha/robolucha-api/test"
+)
+
+func SetupSession(t *testing.T) {
+	SetupClassroom(t)
+
+	users := []auth.JWTUser{
+		{Username: "maria"},
+		{Username: "admin", Roles: []string{auth.SystemEditorRole}},
+	}
+	provider, err := auth.NewDevProvider(users)
+	assert.Nil(t, err)
+	auth.EnableDevProvider(provider)
+
+	router = createRouter(test.API_KEY, "true", auth.SessionIsValid, auth.SessionIsValid)
+}
+
+func findSessions(t *testing.T, url string, token string) []model.Session {
+	w := test.PerformRequest(router, "GET", url, "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result []model.Session
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestSessionLogout(t *testing.T) {
+	SetupSession(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	first := devLoginToken(t, "maria")
+	second := devLoginToken(t, "maria")
+
+	w := test.PerformRequest(router, "GET", "/private/get-user", "", first)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	sessions := findSessions(t, "/private/session", second)
+	assert.Equal(t, 2, len(sessions))
+	user := ds.FindUserByUsername("maria")
+	assert.Equal(t, user.ID, sessions[0].UserID)
+	assert.NotEqual(t, "", sessions[0].UUID)
+
+	w = test.PerformRequest(router, "POST", "/private/logout", "", first)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", first)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	sessions = findSessions(t, "/private/session", second)
+	assert.Equal(t, 1, len(sessions))
+
+	// sessions of other users cant be revoked
+	admin := devLoginToken(t, "admin")
+	url := fmt.Sprintf("/private/session/%v", sessions[0].UUID)
+	w = test.PerformRequest(router, "DELETE", url, "", admin)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = test.PerformRequest(router, "DELETE", url, "", second)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", second)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestSessionAdminRevoke(t *testing.T) {
+	SetupSession(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	first := devLoginToken(t, "maria")
+	second := devLoginToken(t, "maria")
+	admin := devLoginToken(t, "admin")
+
+	test.PerformRequest(router, "GET", "/private/get-user", "", first)
+	test.PerformRequest(router, "GET", "/private/get-user", "", second)
+	user := ds.FindUserByUsername("maria")
+
+	url := fmt.Sprintf("/dashboard/user/%v/session", user.ID)
+	w := test.PerformRequest(router, "GET", url, "", first)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	sessions := findSessions(t, url, admin)
+	assert.Equal(t, 2, len(sessions))
+
+	w = test.PerformRequest(router, "DELETE", url, "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Body.String())
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", first)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", second)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = test.PerformRequest(router, "DELETE", "/dashboard/user/999/session", "", admin)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSessionDeletedUser(t *testing.T) {
+	SetupSession(t)
+	defer ds.DB.Close()
+	defer auth.EnableDevProvider(nil)
+
+	maria := devLoginToken(t, "maria")
+	w := test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the cached session is kept but its user no longer exists
+	ds.DB.Delete(ds.FindUserByUsername("maria"))
+
+	w = test.PerformRequest(router, "GET", "/private/get-user", "", maria)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
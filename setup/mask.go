@@ -0,0 +1,148 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitlab.com/robolucha/robolucha-api/model"
+)
+
+const maskShapesFile = "shapes.json"
+const maskColorsFile = "colors.json"
+const maskNameFolder = "name"
+
+var maskHexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+type maskShapesContent struct {
+	ColorKeys []string                    `json:"colorKeys"`
+	Shapes    map[string][]model.MaskItem `json:"shapes"`
+}
+
+type maskColorsContent struct {
+	Colors []model.MaskItem `json:"colors"`
+}
+
+// SetupMaskCatalogueFromFolder definition, the current catalogue is kept when the folder
+// is invalid
+func SetupMaskCatalogueFromFolder(folderName string) {
+	catalogue, err := LoadMaskCatalogue(folderName)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"folderName": folderName,
+			"error":      err,
+		}).Error("Error loading mask catalogue")
+		return
+	}
+
+	model.SetMaskCatalogue(catalogue)
+	log.WithFields(log.Fields{
+		"folderName": folderName,
+		"shapes":     len(catalogue.Shapes),
+		"colors":     len(catalogue.Colors),
+		"locales":    catalogue.NameLocales(),
+	}).Info("mask catalogue LOADED")
+}
+
+// LoadMaskCatalogue reads shapes.json, colors.json and the name word lists of the
+// locales from the name folder, named by the locale as "pt.json"
+func LoadMaskCatalogue(folderName string) (model.MaskCatalogue, error) {
+	catalogue := model.MaskCatalogue{Names: make(map[string]model.MaskNameWords)}
+
+	var shapes maskShapesContent
+	err := readMaskFile(filepath.Join(folderName, maskShapesFile), &shapes)
+	if err != nil {
+		return catalogue, err
+	}
+	catalogue.ColorKeys = shapes.ColorKeys
+	catalogue.Shapes = shapes.Shapes
+
+	var colors maskColorsContent
+	err = readMaskFile(filepath.Join(folderName, maskColorsFile), &colors)
+	if err != nil {
+		return catalogue, err
+	}
+	catalogue.Colors = colors.Colors
+
+	for _, file := range readFilesFromFolder(filepath.Join(folderName, maskNameFolder)) {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		var words model.MaskNameWords
+		err = readMaskFile(filepath.Join(folderName, maskNameFolder, file.Name()), &words)
+		if err != nil {
+			return catalogue, err
+		}
+
+		locale := strings.ToLower(strings.TrimSuffix(file.Name(), ".json"))
+		catalogue.Names[locale] = words
+	}
+
+	return catalogue, validateMaskCatalogue(catalogue)
+}
+
+func readMaskFile(fileName string, content interface{}) error {
+	bytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(bytes, content)
+	if err != nil {
+		return fmt.Errorf("invalid mask file %v, %v", fileName, err)
+	}
+	return nil
+}
+
+// every config needs items unlocked for the new users, used by the random masks
+func validateMaskCatalogue(catalogue model.MaskCatalogue) error {
+	if len(catalogue.ColorKeys) == 0 || len(catalogue.Shapes) == 0 {
+		return fmt.Errorf("mask catalogue without color keys or shapes")
+	}
+
+	for key, items := range catalogue.Shapes {
+		if !hasUnlockedMaskItem(items) {
+			return fmt.Errorf("mask shape %v without items unlocked at level 0", key)
+		}
+		for _, item := range items {
+			if item.Name == "" || item.Name != filepath.Base(item.Name) {
+				return fmt.Errorf("mask shape %v with invalid name %q", key, item.Name)
+			}
+		}
+	}
+
+	if !hasUnlockedMaskItem(catalogue.Colors) {
+		return fmt.Errorf("mask colors without items unlocked at level 0")
+	}
+	for _, item := range catalogue.Colors {
+		if item.Name == "" || !maskHexColor.MatchString(item.Hex) {
+			return fmt.Errorf("mask color %q should have a name and a #RRGGBB hex", item.Name)
+		}
+	}
+
+	for locale, words := range catalogue.Names {
+		if len(words.Nouns) == 0 || len(words.Adjectives) == 0 || words.Template == "" {
+			return fmt.Errorf("mask name words %v should have a template, nouns and adjectives", locale)
+		}
+	}
+	if _, found := catalogue.Names[model.DefaultMaskNameLocale]; !found {
+		return fmt.Errorf("mask name words without the %v locale", model.DefaultMaskNameLocale)
+	}
+
+	return nil
+}
+
+func hasUnlockedMaskItem(items []model.MaskItem) bool {
+	for _, item := range items {
+		if item.MinLevel == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"gitlab.com/robolucha/robolucha-api/utility"
+	"gitlab.com/robolucha/robolucha-api/moderation"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,7 +19,8 @@ func LoadMetadataFromFolder(folderName string, ds *datasource.DataSource) {
 	SetupGradeFromFolder(filepath.Join(folderName, "grade"), ds)
 	SetupLearningObjectiveFromFolder(filepath.Join(folderName, "learning-objective"), ds)
 	SetupLevelGroupFromFolder(filepath.Join(folderName, "level-group"), ds)
-	utility.SetupBadWordListFromFolder(folderName)
+	SetupMaskCatalogueFromFolder(filepath.Join(folderName, "mask"))
+	moderation.Setup(folderName)
 }
 
 // CreateAvailableMatches definition
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileServer definition, stores with files served by the API
+type FileServer interface {
+	ServeFile(c *gin.Context)
+}
+
+// LocalStore definition, files are saved in the folder and the URL
+// is the base URL followed by the key
+type LocalStore struct {
+	folder  string
+	baseURL string
+}
+
+// NewLocalStore creates a store saving the files in the folder
+func NewLocalStore(folder string, baseURL string) *LocalStore {
+	return &LocalStore{
+		folder:  folder,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put definition, the file is removed when the content cant be copied
+func (store *LocalStore) Put(key string, content io.Reader, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	fileName := filepath.Join(store.folder, filepath.FromSlash(key))
+	err = os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create media folder, %v", err)
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create media file %q, %v", key, err)
+	}
+
+	_, err = io.Copy(file, content)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fileName)
+		return "", fmt.Errorf("failed to write media file %q, %v", key, err)
+	}
+
+	return store.baseURL + "/" + key, nil
+}
+
+// Delete definition
+func (store *LocalStore) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(store.folder, filepath.FromSlash(key)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete media file %q, %v", key, err)
+	}
+	return nil
+}
+
+// content types of the media files by the extension of the key, the other
+// files are served as downloads
+var contentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// ServeFile definition, the key is read from the "key" path parameter, the content type
+// is set from the image extensions and the browsers should not sniff another one
+func (store *LocalStore) ServeFile(c *gin.Context) {
+	key, err := cleanKey(c.Param("key"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	fileName := filepath.Join(store.folder, filepath.FromSlash(key))
+	info, err := os.Stat(fileName)
+	if err != nil || info.IsDir() {
+		log.WithFields(log.Fields{
+			"key": key,
+		}).Debug("media file not found")
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	contentType, found := contentTypes[strings.ToLower(filepath.Ext(key))]
+	if !found {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(fileName)
+}
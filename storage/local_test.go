@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorePut(t *testing.T) {
+	folder, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+
+	store := NewLocalStore(folder, "/public/media-file/")
+
+	url, err := store.Put("1/image.png", strings.NewReader("content"), "image/png")
+	assert.Nil(t, err)
+	assert.Equal(t, "/public/media-file/1/image.png", url)
+
+	data, err := ioutil.ReadFile(filepath.Join(folder, "1", "image.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "content", string(data))
+
+	// keys cant leave the folder
+	url, err = store.Put("../../outside.png", strings.NewReader("content"), "image/png")
+	assert.Nil(t, err)
+	assert.Equal(t, "/public/media-file/outside.png", url)
+	assert.FileExists(t, filepath.Join(folder, "outside.png"))
+
+	_, err = store.Put("/", strings.NewReader("content"), "image/png")
+	assert.NotNil(t, err)
+}
+
+func TestLocalStoreDelete(t *testing.T) {
+	folder, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+
+	store := NewLocalStore(folder, "/public/media-file")
+	_, err = store.Put("1/image.png", strings.NewReader("content"), "image/png")
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Delete("1/image.png"))
+	assert.NoFileExists(t, filepath.Join(folder, "1", "image.png"))
+
+	// missing files are ignored
+	assert.Nil(t, store.Delete("1/image.png"))
+	assert.NotNil(t, store.Delete("/"))
+}
+
+func TestLocalStoreServeFile(t *testing.T) {
+	folder, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(folder)
+
+	store := NewLocalStore(folder, "/public/media-file")
+	_, err = store.Put("1/image.txt", strings.NewReader("content"), "text/plain")
+	assert.Nil(t, err)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET(LocalRoute+"/*key", store.ServeFile)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", LocalRoute+"/1/image.txt", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "content", w.Body.String())
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	// only the image extensions have their content type, other files are downloads
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+
+	_, err = store.Put("1/page.html", strings.NewReader("<script></script>"), "text/html")
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", LocalRoute+"/1/page.html", nil))
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+
+	_, err = store.Put("1/image.PNG", strings.NewReader("content"), "image/png")
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", LocalRoute+"/1/image.PNG", nil))
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	for _, path := range []string{"/1/missing.txt", "/1", "/../../etc/passwd"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", LocalRoute+path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	defer os.Unsetenv(driverEnv)
+	defer os.Unsetenv(s3BucketEnv)
+	defer os.Unsetenv(s3RegionEnv)
+
+	store, err := NewFromEnv()
+	assert.Nil(t, err)
+	assert.IsType(t, &LocalStore{}, store)
+
+	os.Setenv(driverEnv, "ftp")
+	_, err = NewFromEnv()
+	assert.NotNil(t, err)
+
+	os.Setenv(driverEnv, DriverS3)
+	_, err = NewFromEnv()
+	assert.NotNil(t, err)
+
+	os.Setenv(s3BucketEnv, "bucket")
+	os.Setenv(s3RegionEnv, "nyc3")
+	store, err = NewFromEnv()
+	assert.Nil(t, err)
+	assert.IsType(t, &S3Store{}, store)
+}
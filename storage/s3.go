@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Config definition, Endpoint is empty for AWS and set for other S3 compatible
+// services as "nyc3.digitaloceanspaces.com", ACL is not sent when empty
+type S3Config struct {
+	Endpoint       string
+	Bucket         string
+	Region         string
+	ACL            string
+	ForcePathStyle bool
+}
+
+// S3Store definition, the credentials are read by the AWS SDK from the environment
+type S3Store struct {
+	uploader *s3manager.Uploader
+	bucket   string
+	acl      string
+}
+
+// NewS3Store creates a store saving the files in the bucket
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("S3 bucket should be defined")
+	}
+	if config.Region == "" {
+		return nil, errors.New("S3 region should be defined")
+	}
+
+	awsConfig := aws.Config{
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+	}
+
+	sess, err := session.NewSession(&awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 session, %v", err)
+	}
+
+	return &S3Store{
+		uploader: s3manager.NewUploader(sess),
+		bucket:   config.Bucket,
+		acl:      config.ACL,
+	}, nil
+}
+
+// Put definition, the content is streamed to the bucket in parts
+func (store *S3Store) Put(key string, content io.Reader, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	input := s3manager.UploadInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+		Body:   content,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if store.acl != "" {
+		input.ACL = aws.String(store.acl)
+	}
+
+	result, err := store.uploader.Upload(&input)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media file %q, %v", key, err)
+	}
+
+	return result.Location, nil
+}
+
+// Delete definition, S3 doesn't return an error for missing keys
+func (store *S3Store) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.uploader.S3.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete media file %q, %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	driverEnv           = "MEDIA_STORE"
+	localFolderEnv      = "MEDIA_LOCAL_FOLDER"
+	localURLEnv         = "MEDIA_LOCAL_URL"
+	s3EndpointEnv       = "MEDIA_S3_ENDPOINT"
+	s3BucketEnv         = "MEDIA_S3_BUCKET"
+	s3RegionEnv         = "MEDIA_S3_REGION"
+	s3ACLEnv            = "MEDIA_S3_ACL"
+	s3ForcePathStyleEnv = "MEDIA_S3_FORCE_PATH_STYLE"
+
+	// DriverLocal saves the files in a local folder served by the API
+	DriverLocal = "local"
+	// DriverS3 saves the files in a S3 compatible bucket
+	DriverS3 = "s3"
+
+	defaultLocalFolder = "media"
+	// LocalRoute is where the files of the local driver are served in the public routes
+	LocalRoute = "/media-file"
+)
+
+// MediaStore definition, where the media files uploaded by the users are saved
+type MediaStore interface {
+	// Put saves the content with the key and returns the URL of the file
+	Put(key string, content io.Reader, contentType string) (string, error)
+	// Delete removes the file of the key, missing files are not an error
+	Delete(key string) error
+}
+
+// NewFromEnv creates the store of the driver defined by MEDIA_STORE, the local driver is used
+// when it is not defined
+func NewFromEnv() (MediaStore, error) {
+	driver := os.Getenv(driverEnv)
+
+	switch driver {
+	case "", DriverLocal:
+		folder := os.Getenv(localFolderEnv)
+		if folder == "" {
+			folder = defaultLocalFolder
+		}
+		baseURL := os.Getenv(localURLEnv)
+		if baseURL == "" {
+			baseURL = "/public" + LocalRoute
+		}
+
+		log.WithFields(log.Fields{
+			"folder":  folder,
+			"baseURL": baseURL,
+		}).Info("Media saved in the local folder")
+
+		return NewLocalStore(folder, baseURL), nil
+	case DriverS3:
+		config := S3Config{
+			Endpoint:       os.Getenv(s3EndpointEnv),
+			Bucket:         os.Getenv(s3BucketEnv),
+			Region:         os.Getenv(s3RegionEnv),
+			ACL:            os.Getenv(s3ACLEnv),
+			ForcePathStyle: os.Getenv(s3ForcePathStyleEnv) == "true",
+		}
+
+		log.WithFields(log.Fields{
+			"endpoint": config.Endpoint,
+			"bucket":   config.Bucket,
+			"region":   config.Region,
+			"acl":      config.ACL,
+		}).Info("Media saved in the S3 bucket")
+
+		return NewS3Store(config)
+	}
+
+	return nil, fmt.Errorf("%v should be %v or %v", driverEnv, DriverLocal, DriverS3)
+}
+
+// DeleteKeys removes the files of the keys, the errors are logged as the records
+// of the files are already removed, empty keys of the media saved without keys are skipped
+func DeleteKeys(store MediaStore, keys []string) {
+	if store == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		err := store.Delete(key)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"key":   key,
+			}).Error("Error deleting media file")
+		}
+	}
+}
+
+// cleans the key as a relative slash separated path, "../" can't leave the root folder
+func cleanKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return cleaned, nil
+}
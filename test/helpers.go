@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -11,6 +13,9 @@ import (
 const API_KEY = "123456"
 const DB_NAME = "robolucha-api-test.db"
 
+// MEDIA_FOLDER is where the local media store saves the files in the tests
+var MEDIA_FOLDER = filepath.Join(os.TempDir(), "robolucha-api-test-media")
+
 // PerformRequest sends http request no authentication header
 func PerformRequest(r http.Handler, method, path string, body string, authorization string) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
@@ -2,12 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 
+	"gitlab.com/robolucha/robolucha-api/auth"
 	"gitlab.com/robolucha/robolucha-api/model"
 	"gitlab.com/robolucha/robolucha-api/setup"
 	"gitlab.com/robolucha/robolucha-api/test"
@@ -111,3 +113,140 @@ func TestUpdateUserLevelAfterMatchEnds(t *testing.T) {
 
 	assert.Equal(t, userDetails.Level.Level, uint(16))
 }
+
+func testCreateGameDefinitionWithRules(rules []model.ProgressionRule) {
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "FOOBAR"
+	gd.UnblockLevel = 16
+	gd.ProgressionRules = rules
+	ds.CreateGameDefinition(&gd)
+}
+
+func TestUserLevelKeptWhenRulesFail(t *testing.T) {
+	userName := "someOtherPlayer"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	testCreateGameDefinitionWithRules([]model.ProgressionRule{
+		{Type: model.PROGRESSION_RULE_MIN_SCORE, Value: 10},
+	})
+
+	match := testStartMatch(t, router, luchador.ID, userName)
+	testAddParticipantToMatch(t, match.ID, luchador.ID)
+	ds.AddMatchScores(&model.ScoreList{Scores: []model.MatchScore{
+		{MatchID: match.ID, LuchadorID: luchador.ID, Score: 5},
+	}})
+	testEndMatch(t, router, match)
+
+	userDetails := testGetUser(t, router, userName)
+	assert.Equal(t, userDetails.Level.Level, uint(0))
+}
+
+func TestUpdateUserLevelWithRules(t *testing.T) {
+	userName := "someOtherPlayer"
+	luchador := SetupWithUserName(t, userName)
+	defer ds.DB.Close()
+
+	testCreateGameDefinitionWithRules([]model.ProgressionRule{
+		{Type: model.PROGRESSION_RULE_MIN_KILLS, Value: 2},
+		{Type: model.PROGRESSION_RULE_WINNING_TEAM},
+		{Type: model.PROGRESSION_RULE_NARRATIVE_EVENT, Event: "flag-captured"},
+	})
+
+	match := testStartMatch(t, router, luchador.ID, userName)
+	testAddParticipantToMatch(t, match.ID, luchador.ID)
+	ds.AddMatchScores(&model.ScoreList{Scores: []model.MatchScore{
+		{MatchID: match.ID, LuchadorID: luchador.ID, Kills: 3, Score: 30},
+	}})
+
+	event := fmt.Sprintf(`{"matchID":%v,"luchadorID":%v,"event":"flag-captured"}`, match.ID, luchador.ID)
+	w := test.PerformRequest(router, "POST", "/internal/match-event", event, test.API_KEY)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	testEndMatch(t, router, match)
+
+	userDetails := testGetUser(t, router, userName)
+	assert.Equal(t, userDetails.Level.Level, uint(16))
+
+	changes := ds.FindUserLevelChanges(userDetails.User.ID)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Reason, model.LEVEL_CHANGE_MATCH)
+	assert.Equal(t, changes[0].MatchID, match.ID)
+}
+
+func TestTeacherSetsStudentLevel(t *testing.T) {
+	classroom := SetupClassroomRoster(t)
+	defer ds.DB.Close()
+
+	url := fmt.Sprintf("/dashboard/classroom/students/%v", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	var students []model.StudentResponse
+	json.Unmarshal(w.Body.Bytes(), &students)
+
+	url = fmt.Sprintf("/dashboard/student/%v/level", students[0].StudentID)
+	w = test.PerformRequest(router, "PUT", url, `{"level":7}`, "alice")
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	w = test.PerformRequestNoAuth(router, "PUT", url, `{"level":7}`)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	userDetails := testGetUser(t, router, "alice")
+	assert.Equal(t, userDetails.Level.Level, uint(7))
+
+	url = fmt.Sprintf("/dashboard/student/%v/level-history", students[0].StudentID)
+	w = test.PerformRequestNoAuth(router, "GET", url, "")
+	var changes []model.UserLevelChange
+	json.Unmarshal(w.Body.Bytes(), &changes)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Reason, model.LEVEL_CHANGE_TEACHER)
+	assert.Equal(t, changes[0].Level, uint(7))
+}
+
+func TestTeacherSetsInvalidStudentLevel(t *testing.T) {
+	classroom := SetupClassroomRoster(t)
+	defer ds.DB.Close()
+
+	url := fmt.Sprintf("/dashboard/classroom/students/%v", classroom.ID)
+	w := test.PerformRequestNoAuth(router, "GET", url, "")
+	var students []model.StudentResponse
+	json.Unmarshal(w.Body.Bytes(), &students)
+
+	url = fmt.Sprintf("/dashboard/student/%v/level", students[0].StudentID)
+	for _, body := range []string{"null", `{"level":-1}`, fmt.Sprintf(`{"level":%v}`, model.MAX_USER_LEVEL+1)} {
+		w = test.PerformRequestNoAuth(router, "PUT", url, body)
+		assert.Equal(t, w.Code, http.StatusBadRequest, body)
+	}
+
+	w = test.PerformRequestNoAuth(router, "PUT", url, fmt.Sprintf(`{"level":%v}`, model.MAX_USER_LEVEL))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	userDetails := testGetUser(t, router, "alice")
+	assert.Equal(t, userDetails.Level.Level, uint(model.MAX_USER_LEVEL))
+	assert.Equal(t, len(ds.FindUserLevelChanges(userDetails.User.ID)), 1)
+}
+
+func TestGameDefinitionUnknownProgressionRule(t *testing.T) {
+	SetupMain(t)
+	defer ds.DB.Close()
+
+	gd := model.BuildDefaultGameDefinition()
+	gd.Name = "UNKNOWN-RULE"
+	gd.ProgressionRules = []model.ProgressionRule{
+		{Type: model.PROGRESSION_RULE_MIN_SCORE, Value: 10},
+		{Type: "max-deaths", Value: 2},
+		{Type: model.PROGRESSION_RULE_NARRATIVE_EVENT},
+	}
+	body, _ := json.Marshal(gd)
+
+	router := createRouter(test.API_KEY, "true", auth.SessionAllwaysValid, auth.SessionAllwaysValid)
+	w := test.PerformRequest(router, "POST", "/internal/game-definition", string(body), test.API_KEY)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	var messages []string
+	json.Unmarshal(w.Body.Bytes(), &messages)
+	assert.Equal(t, len(messages), 2)
+	assert.Assert(t, ds.FindGameDefinitionByName(gd.Name) == nil)
+
+	w = test.PerformRequest(router, "POST", "/internal/game-definition", "null", test.API_KEY)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}